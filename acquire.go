@@ -0,0 +1,140 @@
+package ntest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResourceLimitsEnv names the environment variable that sets the capacity
+// of the shared resources used with Acquire, for example
+// "db-connections=20,browsers=4".
+const ResourceLimitsEnv = "NTEST_RESOURCE_LIMITS"
+
+var (
+	semaphoresLock sync.Mutex
+	semaphores     = make(map[string]*weightedSemaphore)
+)
+
+func setResourceLimits(spec string) error {
+	limits, err := parseResourceLimits(spec)
+	for name, limit := range limits {
+		SetResourceLimit(name, limit)
+	}
+	return err
+}
+
+func parseResourceLimits(s string) (map[string]int64, error) {
+	limits := make(map[string]int64)
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return limits, fmt.Errorf("expected name=limit, got %q", item)
+		}
+		limit, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil || limit <= 0 {
+			return limits, fmt.Errorf("invalid limit for %s: %q", name, value)
+		}
+		limits[strings.TrimSpace(name)] = limit
+	}
+	return limits, nil
+}
+
+// SetResourceLimit sets the capacity of a shared resource for Acquire. It
+// overrides the limit from NTEST_RESOURCE_LIMITS. It should be called
+// before any tests use the resource, for example from TestMain.
+func SetResourceLimit(name string, limit int64) {
+	semaphoresLock.Lock()
+	defer semaphoresLock.Unlock()
+	semaphores[name] = &weightedSemaphore{size: limit}
+}
+
+// Acquire takes n tokens of a shared resource and holds them until the
+// test finishes. If the tokens are not available, Acquire waits. This lets
+// parallel tests (and matrix cells) throttle themselves around something
+// scarce, like database connections:
+//
+//	ntest.Acquire(t, "db-connections", 5)
+//
+// Resources without a limit (from NTEST_RESOURCE_LIMITS or
+// SetResourceLimit) are unlimited and Acquire returns immediately. Asking
+// for more than the limit fails the test.
+func Acquire(t T, resource string, n int64) {
+	t.Helper()
+	semaphoresLock.Lock()
+	sem, ok := semaphores[resource]
+	semaphoresLock.Unlock()
+	if !ok {
+		return
+	}
+	if n > sem.size {
+		t.Fatalf("cannot acquire %d of %s: the limit is %d", n, resource, sem.size)
+	}
+	start := time.Now()
+	if !sem.tryAcquire(n) {
+		t.Logf("waiting for %d of %s", n, resource)
+		sem.acquire(n)
+		t.Logf("acquired %d of %s after %s", n, resource, time.Since(start).Round(time.Millisecond))
+	}
+	t.Cleanup(func() {
+		sem.release(n)
+	})
+}
+
+// weightedSemaphore grants tokens in FIFO order so that large requests
+// are not starved by small ones.
+type weightedSemaphore struct {
+	size    int64
+	mu      sync.Mutex
+	cur     int64
+	waiters []semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	n     int64
+	ready chan struct{}
+}
+
+func (s *weightedSemaphore) tryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size-s.cur >= n && len(s.waiters) == 0 {
+		s.cur += n
+		return true
+	}
+	return false
+}
+
+func (s *weightedSemaphore) acquire(n int64) {
+	s.mu.Lock()
+	if s.size-s.cur >= n && len(s.waiters) == 0 {
+		s.cur += n
+		s.mu.Unlock()
+		return
+	}
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, semaphoreWaiter{n: n, ready: ready})
+	s.mu.Unlock()
+	<-ready
+}
+
+func (s *weightedSemaphore) release(n int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cur -= n
+	for len(s.waiters) != 0 {
+		next := s.waiters[0]
+		if s.size-s.cur < next.n {
+			break
+		}
+		s.cur += next.n
+		s.waiters = s.waiters[1:]
+		close(next.ready)
+	}
+}
@@ -0,0 +1,44 @@
+package ntest_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestAcquire(t *testing.T) {
+	t.Parallel()
+	ntest.SetResourceLimit("acquire-test", 3)
+	var inUse, maxInUse int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ct := &cleanupT{T: t}
+			defer ct.runCleanups()
+			ntest.Acquire(ct, "acquire-test", 2)
+			n := atomic.AddInt32(&inUse, 2)
+			for {
+				m := atomic.LoadInt32(&maxInUse)
+				if n <= m || atomic.CompareAndSwapInt32(&maxInUse, m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&inUse, -2)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), maxInUse)
+
+	assert.True(t, catchFatal(func() {
+		ntest.Acquire(&fatalCapturingT{T: t}, "acquire-test", 4)
+	}), "more than the limit")
+	ntest.Acquire(t, "acquire-test-unlimited", 1000)
+}
@@ -0,0 +1,93 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ApprovalsDir is the directory, relative to the package being tested,
+// where approved and received files are kept.
+const ApprovalsDir = "testdata/approvals"
+
+// VerifyString compares s with the approved output for this test, stored
+// in ApprovalsDir as <TestName>.approved.txt.
+//
+// If they differ (or nothing has been approved yet) then s is written
+// to <TestName>.received.txt, the test is marked as failed, and the
+// failure message includes a diff and the command to approve the
+// received output. When the output matches, any stale received file is
+// removed.
+//
+// Unlike Snapshot, there is no automatic update mode: approving output
+// is meant to be a deliberate step.
+func VerifyString(t T, s string) bool {
+	t.Helper()
+	return verifyApproval(t, []byte(s), "txt")
+}
+
+// VerifyJSON is like VerifyString but for JSON. Values that are not
+// already JSON ([]byte, json.RawMessage) are marshaled. In either case,
+// the JSON is normalized (sorted keys, indented) so that formatting
+// differences do not cause failures.
+func VerifyJSON(t T, v interface{}) bool {
+	t.Helper()
+	var raw []byte
+	switch v := v.(type) {
+	case []byte:
+		raw = v
+	case json.RawMessage:
+		raw = v
+	default:
+		var err error
+		raw, err = json.Marshal(v)
+		if err != nil {
+			t.Fatalf("marshal for approval: %s", err)
+		}
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(raw, &normalized); err != nil {
+		t.Fatalf("invalid JSON for approval: %s", err)
+	}
+	out, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		t.Fatalf("marshal for approval: %s", err)
+	}
+	return verifyApproval(t, append(out, '\n'), "json")
+}
+
+func verifyApproval(t T, received []byte, ext string) bool {
+	t.Helper()
+	parts := strings.Split(t.Name(), "/")
+	for i, part := range parts {
+		parts[i] = snapshotFileName(part)
+	}
+	base := filepath.Join(ApprovalsDir, filepath.Join(parts...))
+	approvedPath := base + ".approved." + ext
+	receivedPath := base + ".received." + ext
+
+	approved, err := os.ReadFile(approvedPath)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("read approved file: %s", err)
+	}
+	if err == nil && bytes.Equal(approved, received) {
+		_ = os.Remove(receivedPath)
+		return true
+	}
+	if err := os.MkdirAll(filepath.Dir(receivedPath), 0o755); err != nil {
+		t.Fatalf("create approvals directory: %s", err)
+	}
+	if err := os.WriteFile(receivedPath, received, 0o644); err != nil {
+		t.Fatalf("write received file: %s", err)
+	}
+	if approved == nil {
+		t.Errorf("no approved output for %s yet; review %s and approve it with:\n\tmv %s %s",
+			t.Name(), receivedPath, receivedPath, approvedPath)
+		return false
+	}
+	t.Errorf("received output does not match approved output; approve with:\n\tmv %s %s\n%s",
+		receivedPath, approvedPath, unifiedDiff(string(approved), string(received), approvedPath, receivedPath))
+	return false
+}
@@ -0,0 +1,26 @@
+package ntest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestVerifyJSON(t *testing.T) {
+	t.Parallel()
+	assert.True(t, ntest.VerifyJSON(t, []byte(`{"b":["x"],   "a":1}`)))
+
+	capture := &errorCapturingT{T: t}
+	assert.False(t, ntest.VerifyJSON(capture, map[string]interface{}{"a": 2, "b": []string{"x"}}))
+	received := "testdata/approvals/TestVerifyJSON.received.json"
+	defer os.Remove(received)
+	if assert.Equal(t, 1, len(capture.errors)) {
+		assert.Contains(t, capture.errors[0], "mv "+received+" testdata/approvals/TestVerifyJSON.approved.json")
+		assert.Contains(t, capture.errors[0], "-  \"a\": 1,\n+  \"a\": 2,\n")
+	}
+	_, err := os.Stat(received)
+	assert.NoError(t, err, "received file written")
+}
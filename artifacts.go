@@ -0,0 +1,162 @@
+package ntest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ArtifactRootEnv names the environment variable that sets the directory
+// under which ArtifactDir creates per-test directories. If it is not set,
+// "ntest-artifacts" in the system temporary directory is used.
+const ArtifactRootEnv = "NTEST_ARTIFACT_ROOT"
+
+// artifactRoots has the directories of the current run of each
+// top-level test, by root and top-level test name
+var artifactRoots sync.Map // artifactRootKey -> *artifactRoot
+
+type artifactRootKey struct {
+	root string
+	top  string
+}
+
+// artifactRoot is the directory of a top-level test. It is cleared
+// once for each run of the test so that the directories of subtests
+// that have already been made in the run are kept.
+type artifactRoot struct {
+	mu      sync.Mutex
+	cleared bool
+	err     error
+	dirs    map[string]*artifactEntry // test name -> directory
+}
+
+// artifactEntry is the directory of one run of a test
+type artifactEntry struct {
+	owner T
+	dir   string
+	err   error
+}
+
+// ArtifactDir returns a directory for files that help diagnose a test
+// failure (logs, dumps, screenshots). The directory is derived from the
+// test name so that it is in the same place on every run: any contents
+// from a previous run are removed the first time a test, or one of its
+// subtests, requests a directory. Unlike t.TempDir, it is not removed when the test finishes.
+//
+// The directory is included in TestResult.ArtifactDir so that reporters
+// can point at it.
+func ArtifactDir(t T) string {
+	t.Helper()
+	dir, err := artifactDir(t)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	return dir
+}
+
+func artifactDir(t T) (string, error) {
+	name := t.Name()
+	root := artifactRootDir()
+	segments := strings.Split(name, "/")
+	top := filepath.Join(root, artifactSegment(segments[0]))
+	dir := top
+	for _, segment := range segments[1:] {
+		dir = filepath.Join(dir, artifactSegment(segment))
+	}
+	value, _ := artifactRoots.LoadOrStore(artifactRootKey{root: root, top: segments[0]}, &artifactRoot{})
+	r := value.(*artifactRoot)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// test names are unique within a run, so a name that was used by
+	// another test means that this is a new run (with -count, say) of
+	// the top-level test
+	owner := unwrapAll(t)
+	entry, ok := r.dirs[name]
+	if ok && sameTest(entry.owner, owner) {
+		if r.err == nil {
+			// created again in case something has removed it
+			// since, and to retry a failure
+			entry.err = createArtifactDir(entry.dir)
+		}
+		return entry.dir, entry.err
+	}
+	if ok || !r.cleared {
+		r.cleared = true
+		r.dirs = make(map[string]*artifactEntry)
+		r.err = removeArtifactDir(top)
+	}
+	entry = &artifactEntry{owner: owner, dir: dir, err: r.err}
+	if entry.err == nil {
+		entry.err = createArtifactDir(dir)
+	}
+	r.dirs[name] = entry
+	return entry.dir, entry.err
+}
+
+// sameTest reports whether a and b, as returned by unwrapAll, can be
+// the same run of a test. Only two T of the same type can be told apart:
+// tests often pass both their *testing.T and a wrapper of it that is not
+// known to unwrapAll.
+func sameTest(a, b T) bool {
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return true
+	}
+	return a == b
+}
+
+func artifactRootDir() string {
+	if root := os.Getenv(ArtifactRootEnv); root != "" {
+		return root
+	}
+	return filepath.Join(os.TempDir(), "ntest-artifacts")
+}
+
+func removeArtifactDir(dir string) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("remove old artifact directory: %w", err)
+	}
+	return nil
+}
+
+func createArtifactDir(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create artifact directory: %w", err)
+	}
+	return nil
+}
+
+// artifactSegment makes one element of a test name safe to use as a file
+// name on all platforms.
+func artifactSegment(s string) string {
+	safe := []byte(s)
+	for i, c := range safe {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			safe[i] = '_'
+		}
+	}
+	s = strings.Trim(string(safe), ".")
+	if s == "" {
+		return "_"
+	}
+	return s
+}
+
+func lookupArtifactDir(name string) string {
+	root := artifactRootDir()
+	value, ok := artifactRoots.Load(artifactRootKey{root: root, top: strings.SplitN(name, "/", 2)[0]})
+	if !ok {
+		return ""
+	}
+	r := value.(*artifactRoot)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.dirs[name]; ok && entry.err == nil {
+		return entry.dir
+	}
+	return ""
+}
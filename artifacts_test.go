@@ -0,0 +1,69 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestArtifactDir(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	stale := filepath.Join(root, "TestArtifactDir", "cell_x_1", "stale.txt")
+	require.NoError(t, os.MkdirAll(filepath.Dir(stale), 0o755))
+	require.NoError(t, os.WriteFile(stale, []byte("old"), 0o644))
+
+	reporter := &recordingReporter{prefix: t.Name() + "/"}
+	t.Cleanup(ntest.AddReporter(reporter))
+	t.Run("cell:x=1", func(t *testing.T) {
+		ntest.RunTest(t, func(t ntest.T) {
+			dir := ntest.ArtifactDir(t)
+			assert.Equal(t, filepath.Dir(stale), dir)
+			assert.Equal(t, dir, ntest.ArtifactDir(t), "same directory within a test")
+			_, err := os.Stat(stale)
+			assert.True(t, os.IsNotExist(err), "stale artifacts removed")
+		})
+	})
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	require.Equal(t, 1, len(reporter.results))
+	assert.Equal(t, filepath.Dir(stale), reporter.results[0].ArtifactDir)
+}
+
+func TestArtifactDirKeepsSubtests(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+	var evidence string
+	t.Run("child", func(t *testing.T) {
+		evidence = filepath.Join(ntest.ArtifactDir(t), "evidence.txt")
+		require.NoError(t, os.WriteFile(evidence, []byte("x"), 0o644))
+	})
+	parent := ntest.ArtifactDir(t)
+	assert.Equal(t, parent, filepath.Dir(filepath.Dir(evidence)))
+	_, err := os.Stat(evidence)
+	assert.NoError(t, err, "the parent's directory is not cleared after its subtests made theirs")
+}
+
+func TestArtifactDirClearedEachRun(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+	// two runs of the same test, as with -count=2
+	first := &cleanupT{T: t}
+	evidence := filepath.Join(ntest.ArtifactDir(first), "evidence.txt")
+	require.NoError(t, os.WriteFile(evidence, []byte("x"), 0o644))
+	assert.Equal(t, filepath.Dir(evidence), ntest.ArtifactDir(first))
+	_, err := os.Stat(evidence)
+	require.NoError(t, err, "kept within a run")
+
+	second := &cleanupT{T: t}
+	assert.Equal(t, filepath.Dir(evidence), ntest.ArtifactDir(second))
+	_, err = os.Stat(evidence)
+	assert.True(t, os.IsNotExist(err), "removed by the next run")
+
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	assert.Equal(t, filepath.Join(root, "TestArtifactDirClearedEachRun"), ntest.ArtifactDir(second), "root is read again")
+}
@@ -0,0 +1,41 @@
+package ntest
+
+import "strings"
+
+// attrT is implemented by *testing.T (Go 1.25 and later).
+type attrT interface {
+	Attr(key, value string)
+}
+
+// wrappedT is implemented by the T wrappers in this package.
+type wrappedT interface {
+	unwrap() T
+}
+
+func (t logWrappedT) unwrap() T   { return t.T }
+func (t *reportingT) unwrap() T   { return t.T }
+func (t *errorBudgetT) unwrap() T { return t.T }
+
+// Attr attaches a key/value attribute to the test if the underlying T
+// supports it (testing.T does with Go 1.25 and later). Attributes are
+// included in go test output (including -json) so that structured test
+// output can carry fixture context. Otherwise Attr does nothing.
+//
+// Keys may not contain spaces and values may not contain newlines, so
+// those are replaced.
+//
+// ntest adds attributes for matrix cells ("ntest.cell"), fixtures that
+// call ReportFixture ("ntest.fixture"), and TestIDs ("ntest.test-id").
+func Attr(t T, key, value string) {
+	for {
+		if a, ok := t.(attrT); ok {
+			a.Attr(strings.Join(strings.Fields(key), "_"), strings.NewReplacer("\r", " ", "\n", " ").Replace(value))
+			return
+		}
+		w, ok := t.(wrappedT)
+		if !ok {
+			return
+		}
+		t = w.unwrap()
+	}
+}
@@ -0,0 +1,23 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestAttr(t *testing.T) {
+	t.Parallel()
+	capture := &attrCapturingT{T: t, attrs: make(map[string]string)}
+	wrapped := ntest.ExtraDetailLogger(ntest.ErrorBudget(capture, 3), "x")
+	ntest.Attr(wrapped, "database name", "db\nname")
+	ntest.RunTest(capture, func(t ntest.T) {
+		ntest.ReportFixture(t, "widget")
+	})
+	assert.Equal(t, map[string]string{
+		"database_name": "db name",
+		"ntest.fixture": "widget",
+	}, capture.attrs)
+}
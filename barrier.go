@@ -0,0 +1,117 @@
+package ntest
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// Signal is a one-time event that parallel tests, like the cells of a
+// parallel matrix, or goroutines can wait for.
+type Signal struct {
+	name string
+	once sync.Once
+	done chan struct{}
+}
+
+// NewSignal creates a Signal. The name is used in failure messages.
+func NewSignal(name string) *Signal {
+	return &Signal{name: name, done: make(chan struct{})}
+}
+
+// SignalFixture returns an injector that provides the same *Signal to
+// every test whose chain includes it, so that the cells of a matrix
+// can coordinate:
+//
+//	ntest.RunParallelMatrix(t,
+//		ntest.SignalFixture("loaded"),
+//		map[string]nject.Provider{
+//			"loader": nject.Provide("loader", func(loaded *ntest.Signal) { load(); loaded.Fire() }),
+//			"reader": nject.Provide("reader", func(t ntest.T, loaded *ntest.Signal) { loaded.Wait(t) }),
+//		},
+//		...)
+func SignalFixture(name string) nject.Provider {
+	s := NewSignal(name)
+	return nject.Provide("signal-"+name, func() *Signal { return s })
+}
+
+// Fire releases everyone who is waiting for the signal. Firing again
+// does nothing.
+func (s *Signal) Fire() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Done returns a channel that is closed when the signal is fired.
+func (s *Signal) Done() <-chan struct{} {
+	return s.done
+}
+
+// Wait blocks until the signal is fired. Like Eventually, it gives up
+// shortly before the deadline of the test, or after
+// DefaultEventuallyTimeout, and fails the test with t.Fatalf.
+func (s *Signal) Wait(t T) {
+	t.Helper()
+	start := time.Now()
+	timer := time.NewTimer(time.Until(eventuallyDeadline(t, start, EventuallyOptions{})))
+	defer timer.Stop()
+	select {
+	case <-s.done:
+	case <-timer.C:
+		t.Fatalf("%s timed out after %s waiting for signal %q, which was never fired",
+			t.Name(), time.Since(start).Round(time.Millisecond), s.name)
+	}
+}
+
+// Barrier makes a fixed number of parallel tests (or goroutines) wait
+// for each other: Wait returns once all of them have called it.
+type Barrier struct {
+	name    string
+	parties int
+	mu      sync.Mutex
+	arrived []string
+	done    chan struct{}
+}
+
+// NewBarrier creates a Barrier for parties participants. The name is
+// used in failure messages.
+func NewBarrier(name string, parties int) *Barrier {
+	return &Barrier{name: name, parties: parties, done: make(chan struct{})}
+}
+
+// BarrierFixture returns an injector that provides the same *Barrier,
+// for parties participants, to every test whose chain includes it.
+func BarrierFixture(name string, parties int) nject.Provider {
+	b := NewBarrier(name, parties)
+	return nject.Provide("barrier-"+name, func() *Barrier { return b })
+}
+
+// Wait records that t has arrived and blocks until all the parties
+// have. Like Eventually, it gives up shortly before the deadline of the
+// test, or after DefaultEventuallyTimeout, and fails the test with
+// t.Fatalf, listing who arrived. Once every party has arrived, Wait
+// returns immediately.
+func (b *Barrier) Wait(t T) {
+	t.Helper()
+	start := time.Now()
+	b.mu.Lock()
+	b.arrived = append(b.arrived, t.Name())
+	if len(b.arrived) == b.parties {
+		close(b.done)
+	}
+	b.mu.Unlock()
+	timer := time.NewTimer(time.Until(eventuallyDeadline(t, start, EventuallyOptions{})))
+	defer timer.Stop()
+	select {
+	case <-b.done:
+	case <-timer.C:
+		b.mu.Lock()
+		arrived := append([]string(nil), b.arrived...)
+		b.mu.Unlock()
+		sort.Strings(arrived)
+		t.Fatalf("%s timed out after %s at barrier %q: %d of %d parties arrived:\n\t%s",
+			t.Name(), time.Since(start).Round(time.Millisecond), b.name, len(arrived), b.parties, strings.Join(arrived, "\n\t"))
+	}
+}
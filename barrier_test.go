@@ -0,0 +1,70 @@
+package ntest_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestBarrierFixture(t *testing.T) {
+	var before, after int32
+	ntest.RunParallelMatrix(t,
+		ntest.BarrierFixture("all started", 3),
+		map[string]nject.Provider{
+			"a": nject.Provide("a", func() int { return 1 }),
+			"b": nject.Provide("b", func() int { return 2 }),
+			"c": nject.Provide("c", func() int { return 3 }),
+		},
+		func(t ntest.T, _ int, b *ntest.Barrier) {
+			atomic.AddInt32(&before, 1)
+			b.Wait(t)
+			assert.Equal(t, int32(3), atomic.LoadInt32(&before), "all arrived before any left")
+			atomic.AddInt32(&after, 1)
+		},
+	)
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+		ntest.Eventually(t, func() error {
+			if n := atomic.LoadInt32(&after); n != 3 {
+				return fmt.Errorf("%d of 3 cells passed the barrier", n)
+			}
+			return nil
+		}, ntest.EventuallyOptions{})
+	})
+}
+
+// soonFatalT has a test deadline that is about to pass.
+func soonFatalT(t *testing.T) (*deadlineT, *testingFatalT) {
+	fatal := &testingFatalT{T: t}
+	return &deadlineT{T: fatal, deadline: time.Now().Add(ntest.ScaledTimeout(ntest.DefaultDeadlineMargin) + 50*time.Millisecond)}, fatal
+}
+
+func TestBarrierTimeout(t *testing.T) {
+	t.Parallel()
+	b := ntest.NewBarrier("ready", 3)
+	dt, fatal := soonFatalT(t)
+	assert.True(t, catchFatal(func() { b.Wait(dt) }))
+	require.Len(t, fatal.fatals, 1)
+	assert.Regexp(t, `^TestBarrierTimeout timed out after \S+ at barrier "ready": 1 of 3 parties arrived:\n\tTestBarrierTimeout$`, fatal.fatals[0])
+}
+
+func TestSignal(t *testing.T) {
+	t.Parallel()
+	s := ntest.NewSignal("loaded")
+	go s.Fire()
+	s.Wait(t)
+	s.Fire()
+	<-s.Done()
+
+	dt, fatal := soonFatalT(t)
+	assert.True(t, catchFatal(func() { ntest.NewSignal("never").Wait(dt) }))
+	require.Len(t, fatal.fatals, 1)
+	assert.Regexp(t, `^TestSignal timed out after \S+ waiting for signal "never", which was never fired$`, fatal.fatals[0])
+}
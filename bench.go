@@ -0,0 +1,213 @@
+package ntest
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/muir/nject"
+)
+
+// BenchMetrics is injected by RunBenchmark and RunBenchmarkMatrix so that
+// fixtures and benchmarks can count things (queries issued, bytes
+// transferred) that are reported as custom benchmark metrics.
+type BenchMetrics struct {
+	mu     sync.Mutex
+	totals map[string]float64
+}
+
+// Add adds v to the metric named unit (for example "queries" or
+// "bytes-sent"). When the benchmark finishes, the total divided by b.N is
+// reported as unit+"/op". Units may not contain spaces.
+func (m *BenchMetrics) Add(unit string, v float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.totals == nil {
+		m.totals = make(map[string]float64)
+	}
+	m.totals[unit] += v
+}
+
+// report reports the metrics per operation and returns them.
+func (m *BenchMetrics) report(b *testing.B) map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	perOp := make(map[string]float64, len(m.totals))
+	for unit, total := range m.totals {
+		perOp[unit+"/op"] = total / float64(b.N)
+		b.ReportMetric(perOp[unit+"/op"], unit+"/op")
+	}
+	return perOp
+}
+
+// RunBenchmark is RunTest for benchmarks. It calls b.ReportAllocs and
+// injects *testing.B and a *BenchMetrics. The final function should loop
+// b.N times. RunBenchmark runs the chain each time the testing package
+// calls the benchmark function, so fixtures are created per run; call
+// b.ResetTimer after expensive setup.
+func RunBenchmark(b *testing.B, chain ...interface{}) {
+	runBenchmark(b, chain)
+}
+
+func runBenchmark(b *testing.B, chain []interface{}) map[string]float64 {
+	b.ReportAllocs()
+	metrics := &BenchMetrics{}
+	RunTest(b,
+		nject.Provide("testing.B", func() *testing.B { return b }),
+		nject.Provide("bench-metrics", func() *BenchMetrics { return metrics }),
+		nject.Sequence("benchmark-chain", chain...),
+	)
+	return metrics.report(b)
+}
+
+// Environment variables that set BenchRepetitions and BenchMaxVariation,
+// for example NTEST_BENCH_REPETITIONS=5 NTEST_BENCH_MAX_VARIATION=0.1.
+const (
+	BenchRepetitionsEnv  = "NTEST_BENCH_REPETITIONS"
+	BenchMaxVariationEnv = "NTEST_BENCH_MAX_VARIATION"
+)
+
+// BenchRepetitions is the number of times RunBenchmarkMatrix runs each
+// cell. With more than one, the metrics of each cell are summarized as
+// mean, standard deviation, and 95th percentile.
+var BenchRepetitions = 1
+
+// BenchMaxVariation, if not zero, is the largest coefficient of variation
+// (standard deviation divided by mean, so 0.1 is 10%) of a custom metric
+// over the repetitions of a cell before RunBenchmarkMatrix flags the cell
+// as too noisy to compare.
+var BenchMaxVariation float64
+
+func setBenchRepetitions(value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("repetitions must be at least 1")
+	}
+	BenchRepetitions = n
+	return nil
+}
+
+func setBenchMaxVariation(value string) error {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	BenchMaxVariation = v
+	return nil
+}
+
+// RunBenchmarkMatrix is like RunMatrix for benchmarks: each cell of the
+// matrix is a sub-benchmark (b.Run) run with RunBenchmark, BenchRepetitions
+// times. After all the cells have run, the custom metrics of each cell are
+// logged side by side (run with -v to see them) so that cells can be
+// compared.
+func RunBenchmarkMatrix(b *testing.B, chain ...interface{}) {
+	matrix, before, after := breakChain(chain)
+	if matrix == nil {
+		b.Fatal("No matrix found in matrix benchmark, perhaps the specifier is in a Sequence? (not allowed)")
+	}
+	var mu sync.Mutex
+	results := make(map[string][]map[string]float64)
+	var runCells func(b *testing.B, matrix map[string]nject.Provider, before []interface{}, after []interface{})
+	runCells = func(b *testing.B, matrix map[string]nject.Provider, before []interface{}, after []interface{}) {
+		names := make([]string, 0, len(matrix))
+		for name := range matrix {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			subChain := matrix[name]
+			nextMatrix, newBefore, newAfter := breakChain(after)
+			if nextMatrix != nil {
+				b.Run(name, func(b *testing.B) {
+					runCells(b, nextMatrix, combineSlices(before, newBefore, []interface{}{subChain}), newAfter)
+				})
+				continue
+			}
+			for i := 0; i < BenchRepetitions; i++ {
+				var cell string
+				var perOp map[string]float64
+				b.Run(name, func(b *testing.B) {
+					// the last run has the largest b.N
+					perOp = runBenchmark(b, combineSlices(before, []interface{}{subChain}, after))
+					cell = b.Name()
+				})
+				if perOp == nil {
+					continue
+				}
+				// repetitions are named cell#01, cell#02, ...
+				if j := strings.LastIndexByte(cell, '#'); j != -1 && i > 0 {
+					cell = cell[:j]
+				}
+				mu.Lock()
+				results[cell] = append(results[cell], perOp)
+				mu.Unlock()
+			}
+		}
+	}
+	runCells(b, matrix, before, after)
+	logBenchMetrics(b, results)
+}
+
+func logBenchMetrics(b *testing.B, results map[string][]map[string]float64) {
+	unitSet := make(map[string]bool)
+	cells := make([]string, 0, len(results))
+	for cell, runs := range results {
+		cells = append(cells, cell)
+		for _, perOp := range runs {
+			for unit := range perOp {
+				unitSet[unit] = true
+			}
+		}
+	}
+	if len(unitSet) == 0 {
+		return
+	}
+	units := make([]string, 0, len(unitSet))
+	for unit := range unitSet {
+		units = append(units, unit)
+	}
+	sort.Strings(units)
+	sort.Strings(cells)
+	var lines []string
+	var noisy []string
+	for _, unit := range units {
+		for _, cell := range cells {
+			var values []float64
+			for _, perOp := range results[cell] {
+				if v, ok := perOp[unit]; ok {
+					values = append(values, v)
+				}
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if len(values) == 1 {
+				lines = append(lines, strings.Join([]string{unit, cell, strconv.FormatFloat(values[0], 'g', 4, 64)}, "\t"))
+				continue
+			}
+			stats := summarize(values)
+			line := strings.Join([]string{unit, cell,
+				"mean " + strconv.FormatFloat(stats.Mean, 'g', 4, 64),
+				"stddev " + strconv.FormatFloat(stats.StdDev, 'g', 4, 64),
+				"p95 " + strconv.FormatFloat(stats.P95, 'g', 4, 64),
+				"n " + strconv.Itoa(stats.N),
+			}, "\t")
+			if BenchMaxVariation != 0 && stats.Variation() > BenchMaxVariation {
+				line += "\tnoisy"
+				noisy = append(noisy, fmt.Sprintf("%s %s (variation %.0f%%)", cell, unit, 100*stats.Variation()))
+			}
+			lines = append(lines, line)
+		}
+	}
+	b.Logf("custom metrics by cell:\n%s", strings.Join(lines, "\n"))
+	if len(noisy) != 0 {
+		b.Logf("warning: metrics vary by more than %.0f%% between repetitions: %s", 100*BenchMaxVariation, strings.Join(noisy, ", "))
+	}
+}
@@ -0,0 +1,77 @@
+package ntest_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type benchQueries int
+
+var benchSink []byte
+
+func BenchmarkRunBenchmarkMatrix(b *testing.B) {
+	ntest.RunBenchmarkMatrix(b,
+		map[string]nject.Provider{
+			"one": nject.Provide("one", func() benchQueries { return 1 }),
+			"two": nject.Provide("two", func() benchQueries { return 2 }),
+		},
+		func(b *testing.B, metrics *ntest.BenchMetrics, q benchQueries) {
+			for i := 0; i < b.N; i++ {
+				metrics.Add("queries", float64(q))
+			}
+		},
+	)
+}
+
+func TestRunBenchmark(t *testing.T) {
+	t.Parallel()
+	var called bool
+	result := testing.Benchmark(func(b *testing.B) {
+		ntest.RunBenchmark(b, func(b *testing.B, metrics *ntest.BenchMetrics) {
+			called = true
+			for i := 0; i < b.N; i++ {
+				metrics.Add("queries", 3)
+				benchSink = make([]byte, 64)
+			}
+		})
+	})
+	assert.True(t, called)
+	assert.Equal(t, 3.0, result.Extra["queries/op"])
+	assert.Greater(t, result.AllocsPerOp(), int64(0))
+}
+
+func TestBenchRepetitions(t *testing.T) {
+	old := ntest.BenchRepetitions
+	ntest.BenchRepetitions = 3
+	defer func() { ntest.BenchRepetitions = old }()
+	benchtime := flag.Lookup("test.benchtime")
+	require.NotNil(t, benchtime)
+	oldBenchtime := benchtime.Value.String()
+	require.NoError(t, benchtime.Value.Set("10x"))
+	defer func() { _ = benchtime.Value.Set(oldBenchtime) }()
+
+	runs := make(map[benchQueries]int)
+	testing.Benchmark(func(b *testing.B) {
+		ntest.RunBenchmarkMatrix(b,
+			map[string]nject.Provider{
+				"one": nject.Provide("one", func() benchQueries { return 1 }),
+				"two": nject.Provide("two", func() benchQueries { return 2 }),
+			},
+			func(b *testing.B, metrics *ntest.BenchMetrics, q benchQueries) {
+				if b.N == 1 {
+					runs[q]++
+				}
+				for i := 0; i < b.N; i++ {
+					metrics.Add("queries", float64(q))
+				}
+			},
+		)
+	})
+	assert.Equal(t, map[benchQueries]int{1: 3, 2: 3}, runs)
+}
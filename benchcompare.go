@@ -0,0 +1,248 @@
+package ntest
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/muir/nject"
+)
+
+// BenchCompare configures CompareBenchmarks.
+type BenchCompare struct {
+	// Runs is the number of times each implementation is benchmarked.
+	// The default is 10.
+	Runs int
+	// MaxRegression is how much worse, as a fraction (0.1 is 10%), the
+	// new implementation may be on each of Metrics before the test fails.
+	MaxRegression float64
+	// Metrics are the metrics that are checked for regressions. Lower
+	// is better for all of them. The default is "ns/op". Other metrics,
+	// like "B/op", "allocs/op", and those reported with BenchMetrics, are
+	// compared and logged but only checked if they are listed.
+	Metrics []string
+	// Alpha is the significance level: a difference is only a
+	// regression if the probability that it is due to chance is less
+	// than Alpha. The default is 0.05.
+	Alpha float64
+}
+
+// BenchDelta compares one metric of two implementations.
+type BenchDelta struct {
+	Metric   string
+	Old, New BenchStats
+	// Ratio is New.Mean / Old.Mean.
+	Ratio float64
+	// P is the p-value of a Mann-Whitney U test: the probability that a
+	// difference this large is due to chance.
+	P float64
+}
+
+// Significant reports if P is less than alpha.
+func (d BenchDelta) Significant(alpha float64) bool {
+	return d.P < alpha
+}
+
+func (d BenchDelta) String() string {
+	return fmt.Sprintf("%s: old %s new %s (%+.1f%%, p=%.3f n=%d+%d)",
+		d.Metric, d.Old, d.New, (d.Ratio-1)*100, d.P, d.Old.N, d.New.N)
+}
+
+// BenchStats summarizes the values of a metric over several runs.
+type BenchStats struct {
+	N      int
+	Mean   float64
+	StdDev float64
+	P95    float64
+}
+
+// Variation is the coefficient of variation: StdDev / Mean.
+func (s BenchStats) Variation() float64 {
+	if s.Mean == 0 {
+		return 0
+	}
+	return s.StdDev / math.Abs(s.Mean)
+}
+
+func (s BenchStats) String() string {
+	if s.Mean == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%.4g ±%.0f%%", s.Mean, 100*s.Variation())
+}
+
+// CompareBenchmarks benchmarks chain twice, once with oldImpl injected
+// and once with newImpl, like a matrix with "old" and "new" cells. Each
+// is run opts.Runs times with testing.Benchmark (alternating, so that
+// changes in machine load affect both), with RunBenchmark injections.
+// The comparison of each metric is logged and the test fails if the new
+// implementation is significantly worse than the old one by more than
+// opts.MaxRegression on any of opts.Metrics. The comparisons are
+// returned.
+//
+// Each run takes as long as a benchmark does (see go test -benchtime),
+// so CompareBenchmarks skips the test in -short mode.
+//
+//	ntest.CompareBenchmarks(t, ntest.BenchCompare{MaxRegression: 0.1},
+//		nject.Provide("old", func() Encoder { return oldEncoder{} }),
+//		nject.Provide("new", func() Encoder { return newEncoder{} }),
+//		func(b *testing.B, e Encoder) {
+//			for i := 0; i < b.N; i++ {
+//				e.Encode(value)
+//			}
+//		})
+func CompareBenchmarks(t T, opts BenchCompare, oldImpl, newImpl nject.Provider, chain ...interface{}) []BenchDelta {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("benchmark comparison skipped in -short mode")
+	}
+	if opts.Runs == 0 {
+		opts.Runs = 10
+	}
+	if opts.Alpha == 0 {
+		opts.Alpha = 0.05
+	}
+	if len(opts.Metrics) == 0 {
+		opts.Metrics = []string{"ns/op"}
+	}
+	oldValues := make(map[string][]float64)
+	newValues := make(map[string][]float64)
+	for i := 0; i < opts.Runs; i++ {
+		addBenchResult(oldValues, testing.Benchmark(func(b *testing.B) {
+			RunBenchmark(b, combineSlices([]interface{}{oldImpl}, chain)...)
+		}))
+		addBenchResult(newValues, testing.Benchmark(func(b *testing.B) {
+			RunBenchmark(b, combineSlices([]interface{}{newImpl}, chain)...)
+		}))
+	}
+
+	metrics := make([]string, 0, len(oldValues))
+	for metric := range oldValues {
+		if _, ok := newValues[metric]; ok {
+			metrics = append(metrics, metric)
+		}
+	}
+	sort.Strings(metrics)
+	deltas := make([]BenchDelta, 0, len(metrics))
+	var regressions []string
+	var lines []string
+	for _, metric := range metrics {
+		d := compareValues(metric, oldValues[metric], newValues[metric])
+		deltas = append(deltas, d)
+		lines = append(lines, d.String())
+		if containsString(opts.Metrics, metric) && d.Ratio > 1+opts.MaxRegression && d.Significant(opts.Alpha) {
+			regressions = append(regressions, d.String())
+		}
+	}
+	t.Logf("benchmark comparison of %s:\n\t%s", t.Name(), strings.Join(lines, "\n\t"))
+	if len(regressions) != 0 {
+		t.Errorf("new implementation is more than %.0f%% worse than old:\n\t%s",
+			opts.MaxRegression*100, strings.Join(regressions, "\n\t"))
+	}
+	return deltas
+}
+
+func addBenchResult(values map[string][]float64, r testing.BenchmarkResult) {
+	if r.N == 0 {
+		return
+	}
+	values["ns/op"] = append(values["ns/op"], float64(r.T.Nanoseconds())/float64(r.N))
+	values["B/op"] = append(values["B/op"], float64(r.MemBytes)/float64(r.N))
+	values["allocs/op"] = append(values["allocs/op"], float64(r.MemAllocs)/float64(r.N))
+	for metric, v := range r.Extra {
+		values[metric] = append(values[metric], v)
+	}
+}
+
+func compareValues(metric string, oldValues, newValues []float64) BenchDelta {
+	d := BenchDelta{
+		Metric: metric,
+		Old:    summarize(oldValues),
+		New:    summarize(newValues),
+		P:      mannWhitneyP(oldValues, newValues),
+	}
+	switch {
+	case d.Old.Mean != 0:
+		d.Ratio = d.New.Mean / d.Old.Mean
+	case d.New.Mean == 0:
+		d.Ratio = 1
+	default:
+		d.Ratio = math.Inf(1)
+	}
+	return d
+}
+
+func summarize(values []float64) BenchStats {
+	s := BenchStats{N: len(values)}
+	if s.N == 0 {
+		return s
+	}
+	for _, v := range values {
+		s.Mean += v
+	}
+	s.Mean /= float64(s.N)
+	if s.N > 1 {
+		var sum float64
+		for _, v := range values {
+			sum += (v - s.Mean) * (v - s.Mean)
+		}
+		s.StdDev = math.Sqrt(sum / float64(s.N-1))
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	// nearest rank
+	s.P95 = sorted[int(math.Ceil(0.95*float64(s.N)))-1]
+	return s
+}
+
+// mannWhitneyP returns the two-sided p-value of a Mann-Whitney U test of
+// a and b, using the normal approximation with corrections for ties and
+// continuity.
+func mannWhitneyP(a, b []float64) float64 {
+	n1, n2 := float64(len(a)), float64(len(b))
+	if n1 == 0 || n2 == 0 {
+		return 1
+	}
+	type sample struct {
+		v     float64
+		fromA bool
+	}
+	all := make([]sample, 0, len(a)+len(b))
+	for _, v := range a {
+		all = append(all, sample{v: v, fromA: true})
+	}
+	for _, v := range b {
+		all = append(all, sample{v: v})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].v < all[j].v })
+	var rankSumA, ties float64
+	for i := 0; i < len(all); {
+		j := i
+		for j < len(all) && all[j].v == all[i].v {
+			j++
+		}
+		// ranks i+1 through j share their average
+		rank := float64(i+1+j) / 2
+		for k := i; k < j; k++ {
+			if all[k].fromA {
+				rankSumA += rank
+			}
+		}
+		tied := float64(j - i)
+		ties += tied*tied*tied - tied
+		i = j
+	}
+	n := n1 + n2
+	u := rankSumA - n1*(n1+1)/2
+	sigma := math.Sqrt(n1 * n2 / 12 * (n + 1 - ties/(n*(n-1))))
+	if sigma == 0 {
+		return 1
+	}
+	z := (math.Abs(u-n1*n2/2) - 0.5) / sigma
+	if z < 0 {
+		return 1
+	}
+	return math.Erfc(z / math.Sqrt2)
+}
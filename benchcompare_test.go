@@ -0,0 +1,62 @@
+package ntest_test
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type sleeper interface {
+	sleep()
+}
+
+type sleepFor time.Duration
+
+func (s sleepFor) sleep() { time.Sleep(time.Duration(s)) }
+
+func TestCompareBenchmarks(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs benchmarks")
+	}
+	benchtime := flag.Lookup("test.benchtime")
+	require.NotNil(t, benchtime)
+	old := benchtime.Value.String()
+	require.NoError(t, benchtime.Value.Set("20x"))
+	defer func() { _ = benchtime.Value.Set(old) }()
+
+	fast := nject.Provide("fast", func() sleeper { return sleepFor(0) })
+	slow := nject.Provide("slow", func() sleeper { return sleepFor(5 * time.Millisecond) })
+	bench := func(b *testing.B, s sleeper, m *ntest.BenchMetrics) {
+		for i := 0; i < b.N; i++ {
+			s.sleep()
+			m.Add("sleeps", 1)
+		}
+	}
+
+	capture := &errorCapturingT{T: t}
+	deltas := ntest.CompareBenchmarks(capture, ntest.BenchCompare{Runs: 5, MaxRegression: 0.5}, fast, slow, bench)
+	require.Len(t, capture.errors, 1)
+	assert.Contains(t, capture.errors[0], "new implementation is more than 50% worse than old:\n\tns/op: old ")
+
+	metrics := make(map[string]ntest.BenchDelta)
+	for _, d := range deltas {
+		metrics[d.Metric] = d
+	}
+	assert.Greater(t, metrics["ns/op"].Ratio, 10.0)
+	assert.True(t, metrics["ns/op"].Significant(0.05))
+	assert.Equal(t, 5, metrics["ns/op"].Old.N)
+	if assert.Contains(t, metrics, "sleeps/op") {
+		assert.Equal(t, 1.0, metrics["sleeps/op"].Ratio)
+		assert.False(t, metrics["sleeps/op"].Significant(0.05))
+	}
+
+	capture = &errorCapturingT{T: t}
+	ntest.CompareBenchmarks(capture, ntest.BenchCompare{Runs: 5, MaxRegression: 0.5}, slow, fast, bench)
+	assert.Empty(t, capture.errors, "improvements are not regressions")
+}
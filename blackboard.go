@@ -0,0 +1,120 @@
+package ntest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// Blackboard is a concurrency-safe store where the cells of a matrix,
+// or other parallel tests, publish results for a later subtest to
+// check, instead of package variables guarded by mutexes:
+//
+//	board := ntest.NewBlackboard()
+//	ntest.RunParallelMatrix(t, ntest.BlackboardFixture(board), cells,
+//		func(t ntest.T, b *ntest.Blackboard, c *Client) {
+//			ntest.Publish(b, t.Name(), c.Count())
+//		})
+//	t.Run("validate", func(t *testing.T) {
+//		t.Parallel()
+//		a := ntest.Await[int](t, board, "TestSync/a")
+//		...
+//	})
+//
+// Values are read back with the type they were published with.
+type Blackboard struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+	// changed is closed, and replaced, when a value is published
+	changed chan struct{}
+}
+
+// NewBlackboard creates an empty Blackboard.
+func NewBlackboard() *Blackboard {
+	return &Blackboard{
+		values:  make(map[string]interface{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// BlackboardFixture returns an injector that provides board.
+func BlackboardFixture(board *Blackboard) nject.Provider {
+	return nject.Provide("blackboard", func() *Blackboard { return board })
+}
+
+// Publish sets key to value, replacing what was published before.
+func Publish[V any](b *Blackboard, key string, value V) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.values[key] = value
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+// Published returns the value of key if it has been published as a V.
+func Published[V any](b *Blackboard, key string) (V, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	v, ok := b.values[key].(V)
+	return v, ok
+}
+
+// AllPublished returns the values that have been published as a V, by key.
+func AllPublished[V any](b *Blackboard) map[string]V {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	all := make(map[string]V)
+	for key, value := range b.values {
+		if v, ok := value.(V); ok {
+			all[key] = v
+		}
+	}
+	return all
+}
+
+// Keys returns the keys that have been published, sorted.
+func (b *Blackboard) Keys() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	keys := make([]string, 0, len(b.values))
+	for key := range b.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Await waits for key to be published and returns its value. Like
+// Eventually, it gives up shortly before the deadline of the test, or
+// after DefaultEventuallyTimeout, and fails the test with t.Fatalf,
+// listing what was published. It also fails the test if the value is
+// not a V.
+func Await[V any](t T, b *Blackboard, key string) V {
+	t.Helper()
+	start := time.Now()
+	timer := time.NewTimer(time.Until(eventuallyDeadline(t, start, EventuallyOptions{})))
+	defer timer.Stop()
+	for {
+		b.mu.Lock()
+		value, ok := b.values[key]
+		changed := b.changed
+		b.mu.Unlock()
+		if ok {
+			v, ok := value.(V)
+			if !ok {
+				t.Fatalf("blackboard %q is a %T, not a %s", key, value, reflect.TypeOf((*V)(nil)).Elem())
+			}
+			return v
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			t.Fatalf("%s timed out after %s waiting for %q to be published to the blackboard, which has:\n\t%s",
+				t.Name(), time.Since(start).Round(time.Millisecond), key, strings.Join(b.Keys(), "\n\t"))
+		}
+	}
+}
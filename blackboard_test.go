@@ -0,0 +1,54 @@
+package ntest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestBlackboard(t *testing.T) {
+	t.Parallel()
+	board := ntest.NewBlackboard()
+	ntest.Publish(board, "count", 3)
+	ntest.Publish(board, "name", "x")
+	ntest.Publish(board, "other", 4)
+
+	count, ok := ntest.Published[int](board, "count")
+	assert.True(t, ok)
+	assert.Equal(t, 3, count)
+	_, ok = ntest.Published[string](board, "count")
+	assert.False(t, ok, "wrong type")
+	_, ok = ntest.Published[int](board, "missing")
+	assert.False(t, ok)
+	assert.Equal(t, map[string]int{"count": 3, "other": 4}, ntest.AllPublished[int](board))
+	assert.Equal(t, []string{"count", "name", "other"}, board.Keys())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ntest.Publish(board, "late", 1.5)
+	}()
+	assert.Equal(t, 1.5, ntest.Await[float64](t, board, "late"))
+	wg.Wait()
+}
+
+func TestAwaitFailures(t *testing.T) {
+	t.Parallel()
+	board := ntest.NewBlackboard()
+	ntest.Publish(board, "count", 3)
+
+	fatal := &testingFatalT{T: t}
+	assert.True(t, catchFatal(func() { ntest.Await[string](fatal, board, "count") }))
+	require.Len(t, fatal.fatals, 1)
+	assert.Equal(t, `blackboard "count" is a int, not a string`, fatal.fatals[0])
+
+	dt, fatal := soonFatalT(t)
+	assert.True(t, catchFatal(func() { ntest.Await[int](dt, board, "missing") }))
+	require.Len(t, fatal.fatals, 1)
+	assert.Regexp(t, `^TestAwaitFailures timed out after \S+ waiting for "missing" to be published to the blackboard, which has:\n\tcount$`, fatal.fatals[0])
+}
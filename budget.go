@@ -0,0 +1,49 @@
+package ntest
+
+import "sync"
+
+type errorBudgetT struct {
+	T
+	limit int
+	mu    sync.Mutex
+	count int
+}
+
+// ErrorBudget creates a T that allows at most limit calls to Error or Errorf.
+// The call that exhausts the budget is reported normally and then the test
+// is stopped with FailNow. This keeps loops that validate many things from
+// producing thousands of follow-on failures while still reporting the
+// first several.
+//
+// Like FailNow, the budget-exhausting call must be made from the
+// goroutine running the test.
+func ErrorBudget(t T, limit int) T {
+	return &errorBudgetT{
+		T:     t,
+		limit: limit,
+	}
+}
+
+func (t *errorBudgetT) Error(args ...interface{}) {
+	t.T.Helper()
+	t.T.Error(args...)
+	t.spend()
+}
+
+func (t *errorBudgetT) Errorf(format string, args ...interface{}) {
+	t.T.Helper()
+	t.T.Errorf(format, args...)
+	t.spend()
+}
+
+func (t *errorBudgetT) spend() {
+	t.T.Helper()
+	t.mu.Lock()
+	t.count++
+	exhausted := t.count == t.limit
+	t.mu.Unlock()
+	if exhausted {
+		t.T.Logf("error budget of %d errors exhausted, stopping test", t.limit)
+		t.T.FailNow()
+	}
+}
@@ -0,0 +1,26 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestErrorBudget(t *testing.T) {
+	t.Parallel()
+	capture := &failNowCapturingT{errorCapturingT{T: t}}
+	budget := ntest.ErrorBudget(capture, 3)
+	var checked int
+	assert.True(t, catchFatal(func() {
+		for i := 0; i < 100; i++ {
+			checked++
+			if i%2 == 0 {
+				budget.Errorf("bad value %d", i)
+			}
+		}
+	}))
+	assert.Equal(t, []string{"bad value 0", "bad value 2", "bad value 4"}, capture.errors)
+	assert.Equal(t, 5, checked)
+}
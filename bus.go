@@ -0,0 +1,224 @@
+package ntest
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// Bus is an in-memory publish/subscribe message bus. It is a lightweight
+// stand-in for NATS: subjects are dot-separated tokens and subscriptions
+// may use "*" to match a single token and ">" to match all remaining tokens.
+//
+// Each subscription receives its messages in publish order on its own
+// goroutine.
+type Bus struct {
+	mu     sync.Mutex
+	closed bool
+	subs   map[*BusSubscription]struct{}
+}
+
+// BusMessage is a message delivered by a Bus.
+type BusMessage struct {
+	Subject string
+	Data    []byte
+}
+
+// BusSubscription is an active subscription on a Bus.
+type BusSubscription struct {
+	bus     *Bus
+	pattern []string
+	handler func(BusMessage)
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []BusMessage
+	done    bool
+	stopped chan struct{}
+}
+
+// BusCapture records every message that matches a subject pattern.
+type BusCapture struct {
+	sub      *BusSubscription
+	mu       sync.Mutex
+	cond     *sync.Cond
+	messages []BusMessage
+}
+
+// ErrBusClosed is returned when publishing to a closed Bus.
+var ErrBusClosed = errors.New("bus closed")
+
+// BusFixture provides a *Bus that is closed when the test finishes.
+var BusFixture = nject.Provide("message-bus", func(t T) *Bus {
+	bus := NewBus()
+	t.Cleanup(bus.Close)
+	return bus
+})
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*BusSubscription]struct{})}
+}
+
+// Publish delivers a message to all matching subscriptions. Data is copied.
+func (b *Bus) Publish(subject string, data []byte) error {
+	msg := BusMessage{Subject: subject, Data: append([]byte(nil), data...)}
+	tokens := strings.Split(subject, ".")
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBusClosed
+	}
+	for sub := range b.subs {
+		if subjectMatches(sub.pattern, tokens) {
+			sub.enqueue(msg)
+		}
+	}
+	return nil
+}
+
+// Subscribe calls handler for each message published to a subject matching
+// pattern.
+func (b *Bus) Subscribe(pattern string, handler func(BusMessage)) *BusSubscription {
+	sub := &BusSubscription{
+		bus:     b,
+		pattern: strings.Split(pattern, "."),
+		handler: handler,
+		stopped: make(chan struct{}),
+	}
+	sub.cond = sync.NewCond(&sub.mu)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(sub.stopped)
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	go sub.deliver()
+	return sub
+}
+
+// Close stops all subscriptions. Messages that have been published
+// but not yet delivered are dropped.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+	for sub := range subs {
+		sub.stop()
+	}
+}
+
+// Unsubscribe stops delivery to the subscription and waits for any
+// in-progress handler to return.
+func (s *BusSubscription) Unsubscribe() {
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+	s.stop()
+}
+
+func (s *BusSubscription) stop() {
+	s.mu.Lock()
+	s.done = true
+	s.queue = nil
+	s.cond.Broadcast()
+	s.mu.Unlock()
+	<-s.stopped
+}
+
+func (s *BusSubscription) enqueue(msg BusMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, msg)
+	s.cond.Broadcast()
+}
+
+func (s *BusSubscription) deliver() {
+	defer close(s.stopped)
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.done {
+			s.cond.Wait()
+		}
+		if s.done {
+			s.mu.Unlock()
+			return
+		}
+		msg := s.queue[0]
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+		s.handler(msg)
+	}
+}
+
+// CaptureMessages records all messages published to subjects matching
+// pattern until the test finishes.
+func CaptureMessages(t T, bus *Bus, pattern string) *BusCapture {
+	c := &BusCapture{}
+	c.cond = sync.NewCond(&c.mu)
+	c.sub = bus.Subscribe(pattern, func(msg BusMessage) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.messages = append(c.messages, msg)
+		c.cond.Broadcast()
+	})
+	t.Cleanup(c.sub.Unsubscribe)
+	return c
+}
+
+// Messages returns a copy of the messages captured so far.
+func (c *BusCapture) Messages() []BusMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]BusMessage(nil), c.messages...)
+}
+
+// WaitFor waits until at least n messages have been captured and returns
+// them. The test fails if that does not happen within timeout (scaled with
+// ScaledTimeout).
+func (c *BusCapture) WaitFor(t T, n int, timeout time.Duration) []BusMessage {
+	t.Helper()
+	timeout = ScaledTimeout(timeout)
+	timer := time.AfterFunc(timeout, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.cond.Broadcast()
+	})
+	defer timer.Stop()
+	deadline := time.Now().Add(timeout)
+	c.mu.Lock()
+	for len(c.messages) < n && time.Now().Before(deadline) {
+		c.cond.Wait()
+	}
+	messages := append([]BusMessage(nil), c.messages...)
+	c.mu.Unlock()
+	if len(messages) < n {
+		subjects := make([]string, len(messages))
+		for i, msg := range messages {
+			subjects[i] = msg.Subject
+		}
+		t.Fatalf("timed out after %s waiting for %d messages matching %s, got %d: %s",
+			timeout, n, strings.Join(c.sub.pattern, "."), len(messages), strings.Join(subjects, ", "))
+	}
+	return messages
+}
+
+func subjectMatches(pattern, subject []string) bool {
+	for i, token := range pattern {
+		if token == ">" {
+			return len(subject) > i
+		}
+		if i >= len(subject) {
+			return false
+		}
+		if token != "*" && token != subject[i] {
+			return false
+		}
+	}
+	return len(pattern) == len(subject)
+}
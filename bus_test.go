@@ -0,0 +1,34 @@
+package ntest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestBus(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t,
+		ntest.BusFixture,
+		func(t *testing.T, bus *ntest.Bus) {
+			orders := ntest.CaptureMessages(t, bus, "orders.*")
+			all := ntest.CaptureMessages(t, bus, "orders.>")
+			require.NoError(t, bus.Publish("orders.created", []byte("1")))
+			require.NoError(t, bus.Publish("orders.eu.created", []byte("2")))
+			require.NoError(t, bus.Publish("users.created", []byte("3")))
+
+			got := orders.WaitFor(t, 1, time.Second)
+			assert.Equal(t, "orders.created", got[0].Subject)
+			assert.Equal(t, "1", string(got[0].Data))
+
+			got = all.WaitFor(t, 2, time.Second)
+			assert.Equal(t, "orders.eu.created", got[1].Subject)
+			time.Sleep(10 * time.Millisecond)
+			assert.Equal(t, 1, len(orders.Messages()), "no extra messages")
+		},
+	)
+}
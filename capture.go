@@ -0,0 +1,97 @@
+package ntest
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// CaptureLogOutput returns an injector that applies CaptureLog.
+func CaptureLogOutput(stdio bool) nject.Provider {
+	return nject.Required(nject.Provide("capture-log-output", func(t T) {
+		CaptureLog(t, stdio)
+	}))
+}
+
+// CaptureLog redirects the output of the standard library log package
+// to t.Log until the test finishes, so that code under test that logs
+// globally shows up with the test that caused it. If stdio is true,
+// os.Stdout and os.Stderr are redirected too (through pipes).
+// Each line is prefixed with its source: "log:", "stdout:", or
+// "stderr:".
+//
+// Since it changes global state, it cannot be used in parallel tests.
+func CaptureLog(t T, stdio bool) {
+	t.Helper()
+	logLines := &lineLogger{t: t, prefix: "log:"}
+	previous := log.Writer()
+	log.SetOutput(logLines)
+	t.Cleanup(func() {
+		log.SetOutput(previous)
+		logLines.flush()
+	})
+	if stdio {
+		captureFile(t, &os.Stdout, "stdout:")
+		captureFile(t, &os.Stderr, "stderr:")
+	}
+}
+
+// captureFile replaces *file with a pipe that is copied to t.Log.
+func captureFile(t T, file **os.File, prefix string) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("capture %s %s", prefix, err)
+	}
+	lines := &lineLogger{t: t, prefix: prefix}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = io.Copy(lines, r)
+	}()
+	previous := *file
+	*file = w
+	t.Cleanup(func() {
+		*file = previous
+		_ = w.Close()
+		<-done
+		_ = r.Close()
+		lines.flush()
+	})
+}
+
+// lineLogger is an io.Writer that logs each complete line.
+type lineLogger struct {
+	t      T
+	prefix string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.buf = append(l.buf, p...)
+	for {
+		i := bytes.IndexByte(l.buf, '\n')
+		if i == -1 {
+			break
+		}
+		l.t.Log(l.prefix, string(l.buf[:i]))
+		l.buf = l.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (l *lineLogger) flush() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buf) != 0 {
+		l.t.Log(l.prefix, string(l.buf))
+		l.buf = nil
+	}
+}
@@ -0,0 +1,44 @@
+package ntest_test
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestCaptureLog(t *testing.T) {
+	var mu sync.Mutex
+	var caught []string
+	previous := log.Writer()
+	stdout := os.Stdout
+	t.Run("capture", func(t *testing.T) {
+		captureT := ntest.ReplaceLogger(t, func(s string) {
+			t.Log("captured:", s)
+			mu.Lock()
+			defer mu.Unlock()
+			caught = append(caught, strings.TrimSpace(s))
+		})
+		ntest.RunTest(captureT, ntest.CaptureLogOutput(true), func() {
+			log.Print("from log")
+			fmt.Println("from stdout")
+			fmt.Fprint(os.Stderr, "partial line on stderr")
+		})
+	})
+	assert.Equal(t, previous, log.Writer())
+	assert.Equal(t, stdout, os.Stdout)
+	assert.Len(t, caught, 3)
+	assert.Contains(t, caught, "stdout: from stdout")
+	assert.Contains(t, caught, "stderr: partial line on stderr")
+	for _, line := range caught {
+		if strings.HasPrefix(line, "log:") {
+			assert.True(t, strings.HasSuffix(line, " from log"), line)
+		}
+	}
+}
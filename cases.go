@@ -0,0 +1,65 @@
+package ntest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muir/nject"
+	"gopkg.in/yaml.v3"
+)
+
+// Cases loads test cases from files matching pattern (a filepath.Glob
+// pattern) and returns them as a matrix for RunMatrix or
+// RunParallelMatrix. Each file is unmarshaled into a C which is then
+// provided to its matrix cell. Files ending in .yaml or .yml are
+// parsed as YAML; everything else is parsed as JSON. Cells are named
+// after the files with their extensions removed.
+//
+//	ntest.RunParallelMatrix(t,
+//		ntest.Cases[loginCase](t, "testdata/cases/*.yaml"),
+//		func(t *testing.T, c loginCase) { ... },
+//	)
+//
+// The test fails immediately if no files match or a file cannot be parsed
+// so that mistakes in case files are not silently skipped.
+func Cases[C any](t T, pattern string) map[string]nject.Provider {
+	t.Helper()
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		t.Fatalf("bad case file pattern %s: %s", pattern, err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no case files match %s", pattern)
+	}
+	matrix := make(map[string]nject.Provider, len(files))
+	for _, file := range files {
+		c, err := loadCase[C](file)
+		if err != nil {
+			t.Fatalf("load case %s: %s", file, err)
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		matrix[name] = nject.Provide("case-"+name, func() C { return c })
+	}
+	return matrix
+}
+
+func loadCase[C any](file string) (C, error) {
+	var c C
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return c, err
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		err = dec.Decode(&c)
+	default:
+		dec := json.NewDecoder(strings.NewReader(string(data)))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&c)
+	}
+	return c, err
+}
@@ -0,0 +1,31 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+type additionCase struct {
+	A   int `yaml:"a" json:"a"`
+	B   int `yaml:"b" json:"b"`
+	Sum int `yaml:"sum" json:"sum"`
+}
+
+func TestCases(t *testing.T) {
+	t.Parallel()
+	ran := make(map[string]bool)
+	ntest.RunMatrix(t,
+		ntest.Cases[additionCase](t, "testdata/cases/add-*"),
+		func(t *testing.T, c additionCase) {
+			ran[t.Name()] = true
+			assert.Equal(t, c.Sum, c.A+c.B)
+		},
+	)
+	assert.Equal(t, map[string]bool{
+		"TestCases/add-small":    true,
+		"TestCases/add-negative": true,
+	}, ran)
+}
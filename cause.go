@@ -0,0 +1,15 @@
+//go:build go1.20
+
+package ntest
+
+import "context"
+
+// only the cause of the first call to cancel is recorded
+func withCancelCause(ctx context.Context) (context.Context, func(error)) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return ctx, cancel
+}
+
+func contextCause(ctx context.Context) error {
+	return context.Cause(ctx)
+}
@@ -0,0 +1,59 @@
+//go:build !go1.20
+
+package ntest
+
+import (
+	"context"
+	"sync"
+)
+
+type causeKey struct{}
+
+// causeContext records the cause of its cancellation, like
+// context.WithCancelCause does in Go 1.20
+type causeContext struct {
+	context.Context
+	once  sync.Once
+	mu    sync.Mutex
+	cause error
+}
+
+func (c *causeContext) Value(key interface{}) interface{} {
+	if key == (causeKey{}) {
+		return c
+	}
+	return c.Context.Value(key)
+}
+
+// only the cause of the first call to cancel is recorded
+func withCancelCause(parent context.Context) (context.Context, func(error)) {
+	ctx, cancel := context.WithCancel(parent)
+	c := &causeContext{Context: ctx}
+	return c, func(cause error) {
+		c.once.Do(func() {
+			c.mu.Lock()
+			if err := ctx.Err(); err != nil {
+				// already cancelled by the parent
+				c.cause = contextCause(parent)
+			} else {
+				c.cause = cause
+			}
+			c.mu.Unlock()
+			cancel()
+		})
+	}
+}
+
+func contextCause(ctx context.Context) error {
+	if c, ok := ctx.Value(causeKey{}).(*causeContext); ok && ctx.Err() != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.cause != nil {
+			return c.cause
+		}
+		if c.Err() != nil {
+			return contextCause(c.Context)
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,84 @@
+package ntest
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/muir/nject"
+)
+
+// ChainWarnings controls whether RunTest logs warnings about common
+// mistakes in injection chains before running them.
+var ChainWarnings = true
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// checkChain logs warnings for common mistakes in a chain given to
+// RunTest:
+//   - a matrix, which only RunMatrix and RunParallelMatrix understand
+//   - a final function that returns values, which nothing can use
+//     (perhaps the test function is missing)
+//   - two functions in the chain that provide the same type, so that
+//     the first is silently unused by anything after the second (unless
+//     the second takes the type too, like AutoCancel)
+//
+// Only the top level of the chain is examined.
+func checkChain(t T, chain []interface{}) {
+	if !ChainWarnings || len(chain) == 0 {
+		return
+	}
+	t.Helper()
+	providedBy := make(map[reflect.Type]int)
+	for i, injector := range chain {
+		if _, ok := injector.(map[string]nject.Provider); ok {
+			t.Logf("ntest warning: element %d of the chain for %s is a matrix (map[string]nject.Provider); use RunMatrix or RunParallelMatrix for matrix tests", i, t.Name())
+			continue
+		}
+		v := reflect.ValueOf(injector)
+		if v.Kind() != reflect.Func {
+			continue
+		}
+		fnType := v.Type()
+		if i == len(chain)-1 {
+			if outputs := nonErrorOutputs(fnType); len(outputs) != 0 {
+				t.Logf("ntest warning: the final function in the chain for %s returns %s which nothing will use; is the test function missing from the end of the chain?", t.Name(), strings.Join(outputs, ", "))
+			}
+			continue
+		}
+		if fnType.NumIn() > 0 && fnType.In(0).Kind() == reflect.Func {
+			// wrappers pass values through
+			continue
+		}
+		for j := 0; j < fnType.NumOut(); j++ {
+			out := fnType.Out(j)
+			if out == errorType {
+				continue
+			}
+			if previous, ok := providedBy[out]; ok && !takesType(fnType, out) {
+				t.Logf("ntest warning: elements %d and %d of the chain for %s both provide %s; only the later one will be used by what follows it (use nject.ReplaceNamed to override on purpose)", previous, i, t.Name(), out)
+			}
+			providedBy[out] = i
+		}
+	}
+}
+
+// takesType is true for decorators, like AutoCancel, that replace a
+// value with one derived from it.
+func takesType(fnType reflect.Type, t reflect.Type) bool {
+	for i := 0; i < fnType.NumIn(); i++ {
+		if fnType.In(i) == t {
+			return true
+		}
+	}
+	return false
+}
+
+func nonErrorOutputs(fnType reflect.Type) []string {
+	var outputs []string
+	for i := 0; i < fnType.NumOut(); i++ {
+		if out := fnType.Out(i); out != errorType {
+			outputs = append(outputs, out.String())
+		}
+	}
+	return outputs
+}
@@ -0,0 +1,58 @@
+package ntest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestChainWarnings(t *testing.T) {
+	t.Parallel()
+	type name string
+
+	capture := &logCapturingT{T: t}
+	ntest.RunTest(capture,
+		func() name { return "a" },
+		func() name { return "b" },
+		func(n name) {
+			assert.Equal(t, name("b"), n)
+		},
+	)
+	warnings := capture.warnings()
+	require.Equal(t, 1, len(warnings))
+	assert.Contains(t, warnings[0], "elements 0 and 1")
+
+	// nject rejects this chain too, but the warning explains why
+	capture = &logCapturingT{T: &failNowCapturingT{errorCapturingT{T: t}}}
+	assert.True(t, catchFatal(func() {
+		ntest.RunTest(capture,
+			func() name { return "a" },
+			func(n name) int { return 0 },
+		)
+	}))
+	warnings = capture.warnings()
+	require.Equal(t, 1, len(warnings))
+	assert.Contains(t, warnings[0], "returns int which nothing will use")
+
+	capture = &logCapturingT{T: t}
+	ntest.RunTest(capture,
+		nject.Provide("named", func() name { return "a" }),
+		func(inner func() name) { _ = inner() },
+		func(n name) {},
+	)
+	assert.Empty(t, capture.warnings())
+
+	// decorators replace what they take
+	capture = &logCapturingT{T: t}
+	ntest.RunTest(capture,
+		context.Background,
+		ntest.AutoCancel,
+		func(ctx context.Context) {},
+	)
+	assert.Empty(t, capture.warnings())
+}
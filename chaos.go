@@ -0,0 +1,143 @@
+package ntest
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// Errors returned by calls that a ChaosPolicy interferes with.
+var (
+	ErrChaos     = errors.New("ntest chaos: injected failure")
+	ErrChaosDrop = errors.New("ntest chaos: connection dropped")
+)
+
+// ChaosPolicy describes faults to inject into calls made through the
+// clients that consult it: HTTPLog (both Transport and Middleware) and
+// SQLLog. A nil *ChaosPolicy injects nothing.
+//
+// To run a test with and without faults, use ChaosMatrix.
+type ChaosPolicy struct {
+	// Latency is added to every call.
+	Latency time.Duration
+	// Jitter is the maximum additional random latency.
+	Jitter time.Duration
+	// ErrorRate is the fraction (0 to 1) of calls that fail with
+	// ErrChaos without being made.
+	ErrorRate float64
+	// DropRate is the fraction (0 to 1) of calls that behave as if the
+	// connection was dropped. HTTP requests are made but their response
+	// is replaced by ErrChaosDrop. SQL calls fail with driver.ErrBadConn
+	// before being made, so database/sql retries them.
+	DropRate float64
+	// Seed makes the choice of calls repeatable.
+	Seed int64
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+// ChaosFixture makes an injected *ChaosPolicy apply to the *HTTPLog and
+// *SQLLog of the test, if there are any. Put it after the injectors that
+// provide them.
+var ChaosFixture = nject.Sequence("chaos",
+	nject.Desired(nject.Provide("http-chaos", func(chaos *ChaosPolicy, log *HTTPLog) {
+		log.Chaos = chaos
+	})),
+	nject.Desired(nject.Provide("sql-chaos", func(chaos *ChaosPolicy, log *SQLLog) {
+		log.Chaos = chaos
+	})),
+)
+
+// ChaosMatrix creates a matrix, for RunMatrix, with a cell for each
+// policy that provides it as a *ChaosPolicy. A nil policy means no
+// faults.
+//
+//	ntest.RunMatrix(t,
+//		ntest.ChaosMatrix(map[string]*ntest.ChaosPolicy{
+//			"calm":  nil,
+//			"flaky": {ErrorRate: 0.2, Latency: 10 * time.Millisecond},
+//		}),
+//		ntest.HTTPLogFixture,
+//		ntest.ChaosFixture,
+//		func(log *ntest.HTTPLog) { ... },
+//	)
+func ChaosMatrix(policies map[string]*ChaosPolicy) map[string]nject.Provider {
+	matrix := make(map[string]nject.Provider, len(policies))
+	for name, policy := range policies {
+		policy := policy
+		matrix[name] = nject.Provide("chaos-"+name, func() *ChaosPolicy {
+			if policy == nil {
+				return nil
+			}
+			// each cell gets its own random sequence
+			return &ChaosPolicy{
+				Latency:   policy.Latency,
+				Jitter:    policy.Jitter,
+				ErrorRate: policy.ErrorRate,
+				DropRate:  policy.DropRate,
+				Seed:      policy.Seed,
+			}
+		})
+	}
+	return matrix
+}
+
+type chaosAction int
+
+const (
+	chaosNone chaosAction = iota
+	chaosError
+	chaosDrop
+)
+
+func (a chaosAction) String() string {
+	switch a {
+	case chaosError:
+		return "error"
+	case chaosDrop:
+		return "drop"
+	default:
+		return ""
+	}
+}
+
+// decide waits for the injected latency and then chooses what to do to
+// the call. The error is from ctx if it is done first.
+func (p *ChaosPolicy) decide(ctx context.Context) (chaosAction, error) {
+	if p == nil {
+		return chaosNone, nil
+	}
+	p.mu.Lock()
+	if p.rng == nil {
+		p.rng = rand.New(rand.NewSource(p.Seed))
+	}
+	delay := p.Latency
+	if p.Jitter > 0 {
+		delay += time.Duration(p.rng.Int63n(int64(p.Jitter)))
+	}
+	roll := p.rng.Float64()
+	p.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return chaosNone, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	switch {
+	case roll < p.ErrorRate:
+		return chaosError, nil
+	case roll < p.ErrorRate+p.DropRate:
+		return chaosDrop, nil
+	default:
+		return chaosNone, nil
+	}
+}
@@ -0,0 +1,100 @@
+package ntest_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestChaosHTTP(t *testing.T) {
+	log := &ntest.HTTPLog{T: t}
+	server := httptest.NewServer(log.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	defer server.Close()
+
+	client := &ntest.HTTPLog{T: t, Chaos: &ntest.ChaosPolicy{ErrorRate: 1}}
+	_, err := client.Client().Get(server.URL)
+	assert.True(t, errors.Is(err, ntest.ErrChaos), "client error %v", err)
+
+	client.Chaos = &ntest.ChaosPolicy{DropRate: 1}
+	_, err = client.Client().Get(server.URL)
+	assert.True(t, errors.Is(err, ntest.ErrChaosDrop), "client drop %v", err)
+
+	client.Chaos = nil
+	log.Chaos = &ntest.ChaosPolicy{ErrorRate: 1}
+	resp, err := client.Client().Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	log.Chaos = &ntest.ChaosPolicy{DropRate: 1}
+	_, err = client.Client().Get(server.URL)
+	assert.Error(t, err, "server drop")
+
+	log.Chaos = &ntest.ChaosPolicy{Latency: 20 * time.Millisecond}
+	start := time.Now()
+	resp, err = client.Client().Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestChaosSQL(t *testing.T) {
+	log := ntest.NewSQLLog(t)
+	db, err := log.Open("ntest-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	log.Chaos = &ntest.ChaosPolicy{ErrorRate: 1}
+	_, err = db.Exec("DELETE FROM a")
+	assert.True(t, errors.Is(err, ntest.ErrChaos), "error %v", err)
+
+	log.Chaos = &ntest.ChaosPolicy{DropRate: 1}
+	_, err = db.Exec("DELETE FROM a")
+	assert.True(t, errors.Is(err, driver.ErrBadConn), "drop %v", err)
+
+	log.Chaos = &ntest.ChaosPolicy{ErrorRate: 0.5, Seed: 7}
+	var failures int
+	for i := 0; i < 40; i++ {
+		if _, err := db.Exec("DELETE FROM a"); err != nil {
+			failures++
+		}
+	}
+	assert.Greater(t, failures, 5)
+	assert.Less(t, failures, 35)
+}
+
+func TestChaosMatrix(t *testing.T) {
+	results := ntest.RunMatrix(t,
+		ntest.ChaosMatrix(map[string]*ntest.ChaosPolicy{
+			"calm":  nil,
+			"flaky": {ErrorRate: 1},
+		}),
+		ntest.SQLLogFixture,
+		func(log *ntest.SQLLog) (*sql.DB, error) {
+			return log.Open("ntest-fake", "")
+		},
+		ntest.ChaosFixture,
+		func(t *testing.T, db *sql.DB, info ntest.CellInfo) {
+			_, err := db.Exec("DELETE FROM a")
+			if info.Path == "flaky" {
+				assert.True(t, errors.Is(err, ntest.ErrChaos), "error %v", err)
+			} else {
+				assert.NoError(t, err)
+			}
+			_ = db.Close()
+		},
+	)
+	assert.Equal(t, 2, results.Ran())
+}
@@ -0,0 +1,130 @@
+package ntest
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CleanupHangEnv names the environment variable that sets
+// CleanupHangTimeout, for example NTEST_CLEANUP_HANG=2m.
+const CleanupHangEnv = "NTEST_CLEANUP_HANG"
+
+// CleanupHangTimeout is how long (scaled with ScaledTimeout) the cleanup
+// functions registered during RunTest may take before the cleanup
+// watchdog reports the one that is still running, with its stack, and
+// dumps all goroutines (see Dump). That way a teardown that hangs, like
+// stopping a container, does not silently use up the go test -timeout.
+// Cleanup functions cannot be abandoned, so the test still waits for
+// it. Zero disables the watchdog.
+var CleanupHangTimeout = time.Minute
+
+func setCleanupHangTimeout(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	CleanupHangTimeout = d
+	return nil
+}
+
+// watchCleanups is called at the start of RunTest. The function it
+// returns must be deferred: it marks the end of the test function, after
+// which the cleanup functions that were registered in between run.
+func watchCleanups(t T) func() {
+	timeout := ScaledTimeout(CleanupHangTimeout)
+	if timeout <= 0 {
+		return func() {}
+	}
+	var mu sync.Mutex
+	var timer *time.Timer
+	done := false
+	// registered first so it runs last; Stop does not wait for a
+	// callback that has already started, so done keeps it from logging
+	// after the test has finished
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if timer != nil {
+			timer.Stop()
+		}
+		done = true
+	})
+	return func() {
+		t.Cleanup(func() {
+			goroutine := currentGoroutine()
+			start := time.Now()
+			mu.Lock()
+			defer mu.Unlock()
+			timer = time.AfterFunc(timeout, func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if !done {
+					cleanupHung(t, goroutine, time.Since(start))
+				}
+			})
+		})
+	}
+}
+
+func cleanupHung(t T, goroutine string, elapsed time.Duration) {
+	dump := goroutineDump()
+	stack := goroutineStack(dump, goroutine)
+	what := "a cleanup function"
+	if f, ok := runningCleanup(stack); ok {
+		what = f
+	}
+	t.Logf("cleanup watchdog: %s of %s is still running after %s:\n%s", what, t.Name(), elapsed.Round(time.Millisecond), stack)
+	Dump(t, "cleanup-goroutines.txt", dump)
+}
+
+// currentGoroutine returns the header, like "goroutine 7 ", of the
+// current goroutine's stack trace.
+func currentGoroutine() string {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	if i := bytes.IndexByte(buf, '['); i != -1 {
+		return string(buf[:i])
+	}
+	return string(buf)
+}
+
+// goroutineStack finds the stack of one goroutine in a dump of all of
+// them.
+func goroutineStack(dump []byte, goroutine string) string {
+	for _, stack := range strings.Split(string(dump), "\n\n") {
+		if strings.HasPrefix(stack, goroutine+"[") {
+			return stack
+		}
+	}
+	return ""
+}
+
+// runningCleanup finds, in the stack of a goroutine that is running
+// the cleanup functions of a *testing.T, the cleanup function that is
+// running and where it is. Each frame in the stack is a line with the
+// function and a line with its location.
+func runningCleanup(stack string) (string, bool) {
+	lines := strings.Split(stack, "\n")
+	for i := 3; i < len(lines); i += 2 {
+		if strings.HasPrefix(lines[i], "testing.(*common).Cleanup.func") {
+			location := strings.TrimSpace(lines[i-1])
+			if j := strings.LastIndex(location, " +0x"); j != -1 {
+				location = location[:j]
+			}
+			return fmt.Sprintf("%s (%s)", funcName(lines[i-2]), location), true
+		}
+	}
+	return "", false
+}
+
+// funcName removes the arguments from a function line of a stack trace.
+func funcName(line string) string {
+	if i := strings.LastIndexByte(line, '('); i > 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,51 @@
+package ntest_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func stopContainer() { time.Sleep(300 * time.Millisecond) }
+
+func TestCleanupHang(t *testing.T) {
+	old := ntest.CleanupHangTimeout
+	ntest.CleanupHangTimeout = 50 * time.Millisecond
+	defer func() { ntest.CleanupHangTimeout = old }()
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+
+	var mu sync.Mutex
+	var logged []string
+	t.Run("hangs", func(t *testing.T) {
+		wrapped := ntest.ReplaceLogger(t, func(s string) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, s)
+		})
+		ntest.RunTest(wrapped, func(t ntest.T) {
+			t.Cleanup(stopContainer)
+		})
+	})
+	t.Run("fast", func(t *testing.T) {
+		wrapped := ntest.ReplaceLogger(t, func(s string) {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, s)
+		})
+		ntest.RunTest(wrapped, func(t ntest.T) {
+			t.Cleanup(func() {})
+		})
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	all := strings.Join(logged, "\n")
+	assert.Regexp(t, `cleanup watchdog: github.com/memsql/ntest_test.stopContainer \(.*cleanupwatch_test.go:\d+\) of TestCleanupHang/hangs is still running after \d+ms:\ngoroutine \d+ \[sleep\]:\ntime.Sleep`, all)
+	assert.Contains(t, all, "cleanup-goroutines.txt:\n")
+	assert.NotContains(t, all, "TestCleanupHang/fast")
+}
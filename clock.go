@@ -0,0 +1,276 @@
+package ntest
+
+import (
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// FakeClockFixture provides a *FakeClock that starts at the current
+// time and a context.Context whose timeouts follow it: contexts derived
+// from it with FakeClock.WithTimeout and FakeClock.WithDeadline expire
+// when the test advances the clock past their deadline, so timeout
+// paths can be tested without sleeping. The context is canceled when the
+// test finishes. It replaces any context.Context provided earlier in the
+// chain.
+//
+// Timeouts that are set with context.WithTimeout or context.WithDeadline
+// still use real time. When that happens to the provided context, or a
+// context derived from it, the place that did it is logged once.
+var FakeClockFixture = nject.Sequence("fake-clock",
+	nject.Provide("fake-clock", func(t T) *FakeClock {
+		return NewFakeClock(t, time.Now())
+	}),
+	nject.Provide("fake-clock-context", func(t T, clock *FakeClock) context.Context {
+		ctx, cancel := clock.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		return ctx
+	}),
+)
+
+// FakeClock is a clock that only moves when it is told to. Code under
+// test should get the time, and wait for it, through a small interface
+// that FakeClock satisfies (for example, one with just Now and After)
+// so that the fake can be substituted for the real clock.
+type FakeClock struct {
+	t      T
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	// logged is the places that were logged for using real time
+	logged map[string]bool
+}
+
+type fakeTimer struct {
+	when    time.Time
+	f       func()
+	stopped bool
+}
+
+// NewFakeClock creates a FakeClock that starts at start. Uses of real
+// time are logged to t.
+func NewFakeClock(t T, start time.Time) *FakeClock {
+	return &FakeClock{t: t, now: start}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// Advance moves the clock forward by d, running the functions and
+// waking up the waiters whose time has come, in order, each with the
+// clock set to its time.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			if end.After(c.now) {
+				c.now = end
+			}
+			c.mu.Unlock()
+			return
+		}
+		timer := c.timers[0]
+		c.timers = c.timers[1:]
+		if timer.when.After(c.now) {
+			c.now = timer.when
+		}
+		c.mu.Unlock()
+		timer.f()
+	}
+}
+
+// AfterFunc calls f, in the goroutine that calls Advance, once the
+// clock has advanced by d. The returned function stops that from
+// happening and reports if it did.
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) (stop func() bool) {
+	c.mu.Lock()
+	timer := &fakeTimer{when: c.now.Add(d), f: f}
+	i := sort.Search(len(c.timers), func(i int) bool { return c.timers[i].when.After(timer.when) })
+	c.timers = append(c.timers, nil)
+	copy(c.timers[i+1:], c.timers[i:])
+	c.timers[i] = timer
+	c.mu.Unlock()
+	if d <= 0 {
+		c.Advance(0)
+	}
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for i, t := range c.timers {
+			if t == timer {
+				c.timers = append(c.timers[:i], c.timers[i+1:]...)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// After returns a channel that receives the time once the clock has
+// advanced by d.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.AfterFunc(d, func() { ch <- c.Now() })
+	return ch
+}
+
+// Sleep blocks until another goroutine advances the clock by d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// RealNow is the escape hatch for code that needs real time. It returns
+// time.Now and logs where it was called from, since a test that depends
+// on real time is no longer deterministic.
+func (c *FakeClock) RealNow() time.Time {
+	c.logRealTime("RealNow", 2)
+	return time.Now()
+}
+
+// WithCancel is like context.WithCancel, except that the returned
+// context logs timeouts that are set on it with real time.
+func (c *FakeClock) WithCancel(parent context.Context) (context.Context, context.CancelFunc) {
+	return c.newContext(parent, time.Time{})
+}
+
+// WithDeadline is like context.WithDeadline with the deadline measured
+// by the clock.
+func (c *FakeClock) WithDeadline(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if d, ok := parent.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	return c.newContext(parent, deadline)
+}
+
+// WithTimeout is like context.WithTimeout with the timeout measured by
+// the clock.
+func (c *FakeClock) WithTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return c.WithDeadline(parent, c.Now().Add(timeout))
+}
+
+func (c *FakeClock) newContext(parent context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	ctx := &fakeClockContext{
+		Context:  parent,
+		clock:    c,
+		deadline: deadline,
+		done:     make(chan struct{}),
+	}
+	if !deadline.IsZero() {
+		stop := c.AfterFunc(deadline.Sub(c.Now()), func() { ctx.cancel(context.DeadlineExceeded) })
+		ctx.mu.Lock()
+		ctx.stop = stop
+		ctx.mu.Unlock()
+	}
+	if parentDone := parent.Done(); parentDone != nil {
+		go func() {
+			select {
+			case <-parentDone:
+				ctx.cancel(parent.Err())
+			case <-ctx.done:
+			}
+		}()
+	}
+	return ctx, func() { ctx.cancel(context.Canceled) }
+}
+
+// fakeClockContext is a context whose deadline is measured by a
+// FakeClock.
+type fakeClockContext struct {
+	context.Context
+	clock    *FakeClock
+	deadline time.Time
+	stop     func() bool
+	done     chan struct{}
+	mu       sync.Mutex
+	err      error
+}
+
+func (ctx *fakeClockContext) cancel(err error) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.err != nil {
+		return
+	}
+	ctx.err = err
+	close(ctx.done)
+	if ctx.stop != nil {
+		ctx.stop()
+	}
+}
+
+// Deadline is called by context.WithDeadline (and so by
+// context.WithTimeout), which sets a timeout with real time.
+func (ctx *fakeClockContext) Deadline() (time.Time, bool) {
+	// the frames in between are those of contexts derived from this one
+	pcs := make([]uintptr, 4)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		if strings.HasPrefix(frame.Function, "context.WithDeadline") {
+			ctx.clock.logRealTime("a context deadline", 2)
+			break
+		}
+		if !more || !strings.HasPrefix(frame.Function, "context.") {
+			break
+		}
+	}
+	if ctx.deadline.IsZero() {
+		return ctx.Context.Deadline()
+	}
+	return ctx.deadline, true
+}
+
+func (ctx *fakeClockContext) Done() <-chan struct{} {
+	return ctx.done
+}
+
+func (ctx *fakeClockContext) Err() error {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	return ctx.err
+}
+
+// logRealTime logs, once per place, that what was called from the
+// first caller outside of the context package, skip frames up, uses
+// real time.
+func (c *FakeClock) logRealTime(what string, skip int) {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(skip+1, pcs)])
+	var where string
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "context.") {
+			where = frame.Function + " (" + frame.File + ":" + strconv.Itoa(frame.Line) + ")"
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	c.mu.Lock()
+	if c.logged == nil {
+		c.logged = make(map[string]bool)
+	}
+	seen := c.logged[where]
+	c.logged[where] = true
+	c.mu.Unlock()
+	if !seen {
+		c.t.Logf("fake clock: %s uses real time, from %s", what, where)
+	}
+}
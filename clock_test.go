@@ -0,0 +1,97 @@
+package ntest_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestFakeClock(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := ntest.NewFakeClock(t, start)
+	var fired []string
+	clock.AfterFunc(2*time.Second, func() { fired = append(fired, "2s at "+clock.Since(start).String()) })
+	clock.AfterFunc(time.Second, func() { fired = append(fired, "1s at "+clock.Since(start).String()) })
+	stop := clock.AfterFunc(time.Second, func() { fired = append(fired, "stopped") })
+	assert.True(t, stop())
+	assert.False(t, stop())
+	after := clock.After(3 * time.Second)
+
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, []string{"1s at 1s"}, fired)
+	assert.Equal(t, start.Add(1500*time.Millisecond), clock.Now())
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, []string{"1s at 1s", "2s at 2s"}, fired)
+	assert.Equal(t, start.Add(3*time.Second), <-after)
+	assert.Equal(t, start.Add(11500*time.Millisecond), clock.Now())
+}
+
+func TestFakeClockFixture(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var logged []string
+	ct := &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		logged = append(logged, s)
+	})}
+	var testCtx context.Context
+	ntest.RunTest(ct, ntest.FakeClockFixture, func(ctx context.Context, clock *ntest.FakeClock) {
+		testCtx = ctx
+		timeoutCtx, cancel := clock.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		deadline, ok := timeoutCtx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, clock.Now().Add(time.Minute), deadline)
+
+		clock.Advance(59 * time.Second)
+		assert.NoError(t, timeoutCtx.Err())
+		clock.Advance(time.Second)
+		<-timeoutCtx.Done()
+		assert.Equal(t, context.DeadlineExceeded, timeoutCtx.Err())
+
+		child, cancel := clock.WithTimeout(ctx, time.Hour)
+		defer cancel()
+		parent, cancelParent := clock.WithCancel(ctx)
+		grandchild, cancel := clock.WithTimeout(parent, time.Hour)
+		defer cancel()
+		cancelParent()
+		<-grandchild.Done()
+		assert.Equal(t, context.Canceled, grandchild.Err())
+		assert.NoError(t, child.Err())
+
+		_, cancel = context.WithTimeout(ctx, time.Hour)
+		defer cancel()
+		_ = clock.RealNow()
+		type key struct{}
+		_, cancel = context.WithTimeout(context.WithValue(ctx, key{}, 1), time.Hour)
+		defer cancel()
+	})
+	assert.NoError(t, testCtx.Err())
+	ct.runCleanups()
+	<-testCtx.Done()
+	assert.Equal(t, context.Canceled, testCtx.Err())
+
+	mu.Lock()
+	defer mu.Unlock()
+	var realTime []string
+	for _, s := range logged {
+		if strings.HasPrefix(s, "fake clock:") {
+			realTime = append(realTime, s)
+		}
+	}
+	require.Len(t, realTime, 3, "%v", logged)
+	assert.Contains(t, realTime[0], "fake clock: a context deadline uses real time, from github.com/memsql/ntest_test.TestFakeClockFixture.func")
+	assert.Contains(t, realTime[0], "clock_test.go:")
+	assert.Contains(t, realTime[1], "fake clock: RealNow uses real time, from github.com/memsql/ntest_test.TestFakeClockFixture.func")
+	assert.Contains(t, realTime[2], "fake clock: a context deadline uses real time, from github.com/memsql/ntest_test.TestFakeClockFixture.func")
+	assert.NotEqual(t, realTime[0], realTime[2], "logged by place")
+}
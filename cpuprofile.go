@@ -0,0 +1,79 @@
+package ntest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/pprof"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// CPUProfileEnv names the environment variable that selects, with a
+// regular expression that is matched against the full test name, tests
+// that RunTest profiles with ProfileCPU. For example
+// NTEST_CPU_PROFILE='^TestSlowImport$' profiles one test without
+// changing it.
+const CPUProfileEnv = "NTEST_CPU_PROFILE"
+
+var cpuProfile struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+}
+
+func enableCPUProfile(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	cpuProfile.mu.Lock()
+	defer cpuProfile.mu.Unlock()
+	cpuProfile.pattern = re
+	return nil
+}
+
+func cpuProfileSelected(name string) bool {
+	cpuProfile.mu.Lock()
+	defer cpuProfile.mu.Unlock()
+	return cpuProfile.pattern != nil && cpuProfile.pattern.MatchString(name)
+}
+
+// CPUProfileFixture is an injector that applies ProfileCPU.
+var CPUProfileFixture = nject.Required(nject.Provide("cpu-profile", ProfileCPU))
+
+// ProfileCPU starts a CPU profile that is written to cpu.pprof in the
+// ArtifactDir of t when the test finishes, including its cleanup
+// functions registered after ProfileCPU. Only one CPU profile can be
+// collected at a time, so if another test (or go test -cpuprofile) is
+// already profiling, that is logged and the test is not profiled. The
+// profile covers the whole process, so it is best used for tests that
+// do not run in parallel with others.
+func ProfileCPU(t T) {
+	t.Helper()
+	// Fatalf would abort the test for the sake of a diagnostic
+	dir, err := artifactDir(t)
+	path := filepath.Join(dir, "cpu.pprof")
+	var f *os.File
+	if err == nil {
+		f, err = os.Create(path)
+	}
+	if err != nil {
+		t.Logf("could not write CPU profile: %s", err)
+		return
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		t.Logf("could not start CPU profile: %s", err)
+		return
+	}
+	t.Cleanup(func() {
+		pprof.StopCPUProfile()
+		if err := f.Close(); err != nil {
+			t.Logf("could not write CPU profile: %s", err)
+			return
+		}
+		t.Logf("CPU profile written to %s", path)
+	})
+}
@@ -0,0 +1,42 @@
+package ntest_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestCPUProfile(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	ntest.RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-ntest.cpu-profile", "^TestCPUProfile/selected$"}))
+	defer func() { _ = fs.Parse([]string{"-ntest.cpu-profile", "$^"}) }()
+
+	for _, name := range []string{"selected", "other"} {
+		t.Run(name, func(t *testing.T) {
+			ntest.RunTest(t, func() {})
+		})
+	}
+	info, err := os.Stat(filepath.Join(root, "TestCPUProfile", "selected", "cpu.pprof"))
+	if assert.NoError(t, err) {
+		assert.NotZero(t, info.Size())
+	}
+	_, err = os.Stat(filepath.Join(root, "TestCPUProfile", "other", "cpu.pprof"))
+	assert.True(t, os.IsNotExist(err))
+
+	t.Run("fixture", func(t *testing.T) {
+		ct := &cleanupT{T: t}
+		ntest.RunTest(ct, ntest.CPUProfileFixture, func() {})
+		ct.runCleanups()
+		_, err := os.Stat(filepath.Join(root, "TestCPUProfile", "fixture", "cpu.pprof"))
+		assert.NoError(t, err)
+	})
+}
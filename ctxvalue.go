@@ -0,0 +1,28 @@
+package ntest
+
+import "context"
+
+// ctxValueKey is distinct for each V
+type ctxValueKey[V any] struct{}
+
+// WithCtxValue returns a copy of ctx that carries value. Fixtures can use
+// it to pass information to each other, and to the code under test,
+// through the injected context.Context without adding types to the
+// injection chain. There is one value for each type V, so use a type of
+// your own rather than, for example, string:
+//
+//	type tenantID string
+//
+//	func(ctx context.Context) context.Context {
+//		return ntest.WithCtxValue(ctx, tenantID("t1"))
+//	}
+func WithCtxValue[V any](ctx context.Context, value V) context.Context {
+	return context.WithValue(ctx, ctxValueKey[V]{}, value)
+}
+
+// CtxValue returns the value of type V that was added to ctx with
+// WithCtxValue.
+func CtxValue[V any](ctx context.Context) (V, bool) {
+	value, ok := ctx.Value(ctxValueKey[V]{}).(V)
+	return value, ok
+}
@@ -0,0 +1,35 @@
+package ntest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestCtxValue(t *testing.T) {
+	type tenantID string
+	type region string
+	ntest.RunTest(t,
+		context.Background,
+		func(ctx context.Context) context.Context {
+			return ntest.WithCtxValue(ctx, tenantID("t1"))
+		},
+		func(ctx context.Context) {
+			tenant, ok := ntest.CtxValue[tenantID](ctx)
+			assert.True(t, ok)
+			assert.Equal(t, tenantID("t1"), tenant)
+
+			_, ok = ntest.CtxValue[region](ctx)
+			assert.False(t, ok, "each type has its own key")
+			_, ok = ntest.CtxValue[string](ctx)
+			assert.False(t, ok, "underlying type does not match")
+
+			ctx = ntest.WithCtxValue(ctx, tenantID("t2"))
+			tenant, _ = ntest.CtxValue[tenantID](ctx)
+			assert.Equal(t, tenantID("t2"), tenant)
+		},
+	)
+}
@@ -0,0 +1,262 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// SeedEnv names the environment variable that sets the seed of DataGen.
+// Each test derives its own sequence from the seed and its name so
+// that a failing test can be repeated on its own.
+const SeedEnv = "NTEST_SEED"
+
+// DataGen generates fake but realistic data (names, emails, JSON
+// documents, structs, CSV) that is the same every time a test runs with
+// the same seed. The seed is logged. DataGen is safe for concurrent
+// use, but the order of concurrent calls then affects the results.
+type DataGen struct {
+	// Seed is the seed of the run, from $NTEST_SEED or the time.
+	Seed    int64
+	mu      sync.Mutex
+	rng     *rand.Rand
+	counter int
+}
+
+// DataGenFixture provides a *DataGen.
+var DataGenFixture = nject.Provide("data-gen", func(t T) *DataGen {
+	return NewDataGen(t)
+})
+
+// NewDataGen creates a DataGen for t and logs its seed, which is also
+// recorded as the "ntest.seed" attribute (see Attr).
+func NewDataGen(t T) *DataGen {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	if s := os.Getenv(SeedEnv); s != "" {
+		var err error
+		seed, err = strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			t.Fatalf("invalid $%s: %s", SeedEnv, err)
+		}
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(t.Name()))
+	t.Logf("data generator seed %d (repeat with %s=%d)", seed, SeedEnv, seed)
+	Attr(t, "ntest.seed", strconv.FormatInt(seed, 10))
+	return &DataGen{
+		Seed: seed,
+		rng:  rand.New(rand.NewSource(seed ^ int64(h.Sum64()))),
+	}
+}
+
+var (
+	firstNames = []string{"Ada", "Alan", "Barbara", "Carlos", "Dana", "Edsger", "Fatima", "Grace", "Hiro", "Ines", "Jun", "Kofi", "Leslie", "Mei", "Nikolai", "Olu", "Priya", "Quinn", "Radia", "Sven", "Tariq", "Uma", "Vint", "Wen", "Xavier", "Yara", "Zoe"}
+	lastNames  = []string{"Adeyemi", "Backus", "Chen", "Dijkstra", "Eriksson", "Fernandez", "Garcia", "Hopper", "Ivanova", "Johnson", "Kim", "Lamport", "Liskov", "Moreau", "Nakamura", "Okafor", "Perlman", "Quispe", "Ritchie", "Singh", "Thompson", "Ueda", "Varga", "Wirth", "Xu", "Yilmaz", "Zhang"}
+	domains    = []string{"example.com", "example.org", "example.net", "test.example"}
+	words      = []string{"alpha", "amber", "basin", "cedar", "delta", "ember", "fjord", "grove", "harbor", "island", "jade", "kelp", "lumen", "meadow", "nimbus", "orbit", "pebble", "quartz", "ridge", "summit", "tundra", "umber", "vale", "willow", "yonder", "zephyr"}
+)
+
+// Intn returns a number in [0, n).
+func (g *DataGen) Intn(n int) int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rng.Intn(n)
+}
+
+// Float64 returns a number in [0.0, 1.0).
+func (g *DataGen) Float64() float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.rng.Float64()
+}
+
+// Bool returns true or false.
+func (g *DataGen) Bool() bool {
+	return g.Intn(2) == 1
+}
+
+// Pick returns one of choices.
+func (g *DataGen) Pick(choices ...string) string {
+	return choices[g.Intn(len(choices))]
+}
+
+// Word returns a lowercase word.
+func (g *DataGen) Word() string {
+	return g.Pick(words...)
+}
+
+// Sentence returns n words separated by spaces.
+func (g *DataGen) Sentence(n int) string {
+	s := make([]string, n)
+	for i := range s {
+		s[i] = g.Word()
+	}
+	return strings.Join(s, " ")
+}
+
+// Name returns a first and last name.
+func (g *DataGen) Name() string {
+	return g.Pick(firstNames...) + " " + g.Pick(lastNames...)
+}
+
+// Email returns an email address that is unique for this DataGen.
+func (g *DataGen) Email() string {
+	name := strings.ToLower(strings.ReplaceAll(g.Name(), " ", "."))
+	domain := g.Pick(domains...)
+	g.mu.Lock()
+	g.counter++
+	n := g.counter
+	g.mu.Unlock()
+	return fmt.Sprintf("%s%d@%s", name, n, domain)
+}
+
+// Time returns a time in the year before the start of 2025, truncated
+// to the second.
+func (g *DataGen) Time() time.Time {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return start.Add(time.Duration(g.Intn(366*24*60*60)) * time.Second)
+}
+
+// Value returns a string suited to a column or field named name: an
+// email for names containing "email", a name for "name", a time for
+// "time", "date", or "_at", a number for "id", "count", or "age", and
+// otherwise a word.
+func (g *DataGen) Value(name string) string {
+	name = strings.ToLower(name)
+	switch {
+	case strings.Contains(name, "email"):
+		return g.Email()
+	case strings.Contains(name, "name"):
+		return g.Name()
+	case strings.Contains(name, "time"), strings.Contains(name, "date"), strings.HasSuffix(name, "_at"):
+		return g.Time().Format(time.RFC3339)
+	case strings.HasSuffix(name, "id"), strings.Contains(name, "count"), strings.Contains(name, "age"):
+		return strconv.Itoa(1 + g.Intn(1000))
+	default:
+		return g.Word()
+	}
+}
+
+// Document returns a JSON-compatible document with up to fields fields,
+// some of which are nested documents (up to depth levels) or arrays.
+func (g *DataGen) Document(fields, depth int) map[string]interface{} {
+	doc := make(map[string]interface{}, fields)
+	for i := 0; i < fields; i++ {
+		key := g.Word()
+		switch kind := g.Intn(6); {
+		case kind == 0 && depth > 0:
+			doc[key] = g.Document(fields/2+1, depth-1)
+		case kind == 1:
+			list := make([]interface{}, g.Intn(4))
+			for j := range list {
+				list[j] = g.Word()
+			}
+			doc[key] = list
+		case kind == 2:
+			doc[key] = g.Intn(10000)
+		case kind == 3:
+			doc[key] = g.Bool()
+		default:
+			doc[key] = g.Value(key)
+		}
+	}
+	return doc
+}
+
+// JSON returns a Document encoded as JSON.
+func (g *DataGen) JSON(fields, depth int) []byte {
+	enc, _ := json.Marshal(g.Document(fields, depth))
+	return enc
+}
+
+// CSV returns a header of columns followed by rows rows of values for
+// them (see Value).
+func (g *DataGen) CSV(rows int, columns ...string) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write(columns)
+	record := make([]string, len(columns))
+	for i := 0; i < rows; i++ {
+		for j, column := range columns {
+			record[j] = g.Value(column)
+		}
+		_ = w.Write(record)
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// Fill sets the exported fields of the struct that ptr points to. Strings
+// are chosen by field name (see Value). Numbers, bools, time.Time, slices,
+// maps with string keys, pointers, and nested structs are filled too.
+func (g *DataGen) Fill(ptr interface{}) {
+	g.fill(reflect.ValueOf(ptr).Elem(), "", 3)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (g *DataGen) fill(v reflect.Value, name string, depth int) {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(g.Value(name))
+	case reflect.Bool:
+		v.SetBool(g.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Type() == reflect.TypeOf(time.Duration(0)) {
+			v.SetInt(int64(g.Intn(3600)) * int64(time.Second))
+			return
+		}
+		v.SetInt(int64(g.Intn(100)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(g.Intn(100)))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(g.Intn(100000)) / 100)
+	case reflect.Ptr:
+		if depth > 0 {
+			v.Set(reflect.New(v.Type().Elem()))
+			g.fill(v.Elem(), name, depth-1)
+		}
+	case reflect.Slice:
+		if depth > 0 {
+			n := 1 + g.Intn(3)
+			v.Set(reflect.MakeSlice(v.Type(), n, n))
+			for i := 0; i < n; i++ {
+				g.fill(v.Index(i), name, depth-1)
+			}
+		}
+	case reflect.Map:
+		if depth > 0 && v.Type().Key().Kind() == reflect.String {
+			v.Set(reflect.MakeMap(v.Type()))
+			for i := 1 + g.Intn(3); i > 0; i-- {
+				value := reflect.New(v.Type().Elem()).Elem()
+				g.fill(value, name, depth-1)
+				v.SetMapIndex(reflect.ValueOf(g.Word()).Convert(v.Type().Key()), value)
+			}
+		}
+	case reflect.Struct:
+		if v.Type() == timeType {
+			v.Set(reflect.ValueOf(g.Time()))
+			return
+		}
+		if depth == 0 {
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if field := v.Type().Field(i); field.PkgPath == "" {
+				g.fill(v.Field(i), field.Name, depth-1)
+			}
+		}
+	}
+}
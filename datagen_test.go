@@ -0,0 +1,84 @@
+package ntest_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type generatedUser struct {
+	ID        int
+	Name      string
+	Email     string
+	Score     float64
+	Active    bool
+	CreatedAt time.Time
+	Tags      []string
+	Address   *struct{ City string }
+	hidden    string
+}
+
+func TestDataGen(t *testing.T) {
+	t.Setenv(ntest.SeedEnv, "42")
+	var logged []string
+	lt := ntest.ReplaceLogger(t, func(s string) { logged = append(logged, s) })
+	generate := func() (string, generatedUser) {
+		var out []string
+		ntest.RunTest(lt, ntest.DataGenFixture, func(g *ntest.DataGen) {
+			assert.Equal(t, int64(42), g.Seed)
+			out = append(out, g.Name(), g.Email(), g.Sentence(3), string(g.JSON(4, 2)))
+		})
+		g := ntest.NewDataGen(lt)
+		var u generatedUser
+		g.Fill(&u)
+		return strings.Join(out, "\n"), u
+	}
+	first, u1 := generate()
+	second, u2 := generate()
+	assert.Equal(t, first, second, "same seed, same data")
+	assert.Equal(t, u1, u2)
+	assert.Contains(t, strings.Join(logged, "\n"), "data generator seed 42 (repeat with NTEST_SEED=42)")
+
+	lines := strings.Split(first, "\n")
+	assert.Regexp(t, `^[A-Z][a-z]+ [A-Z][a-z]+$`, lines[0])
+	assert.Regexp(t, `^[a-z]+\.[a-z]+1@[a-z.]+$`, lines[1])
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(lines[3]), &doc))
+	assert.NotEmpty(t, doc)
+
+	assert.Contains(t, u1.Email, "@")
+	assert.Contains(t, u1.Name, " ")
+	assert.NotZero(t, u1.ID)
+	assert.False(t, u1.CreatedAt.IsZero())
+	assert.NotEmpty(t, u1.Tags)
+	require.NotNil(t, u1.Address)
+	assert.NotEmpty(t, u1.Address.City)
+	assert.Empty(t, u1.hidden)
+
+	capture := &attrCapturingT{T: t, attrs: make(map[string]string)}
+	ntest.NewDataGen(capture)
+	assert.Equal(t, map[string]string{"ntest.seed": "42"}, capture.attrs)
+}
+
+func TestDataGenCSV(t *testing.T) {
+	g := ntest.NewDataGen(t)
+	records, err := csv.NewReader(bytes.NewReader(g.CSV(100, "user_id", "email", "created_at"))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 101)
+	assert.Equal(t, []string{"user_id", "email", "created_at"}, records[0])
+	emails := make(map[string]bool)
+	for _, record := range records[1:] {
+		emails[record[1]] = true
+		_, err := time.Parse(time.RFC3339, record[2])
+		assert.NoError(t, err)
+	}
+	assert.Len(t, emails, 100, "emails are unique")
+}
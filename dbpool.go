@@ -0,0 +1,192 @@
+package ntest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// DBPool is a pool of test databases that tests check out and return
+// rather than create and drop their own. Databases are created as
+// needed, up to Size, and are reset (by default, truncated) when they
+// are returned. Make one pool per process, for example as a package
+// variable, and Close it from TestMain.
+//
+//	var pool = &ntest.DBPool{Name: "test", Size: 8, Create: createDatabase}
+//
+//	ntest.RunTest(t, ntest.DBPoolFixture(pool), func(db *sql.DB) { ... })
+type DBPool struct {
+	// Name prefixes the names passed to Create.
+	Name string
+	// Size limits how many databases are checked out at once. Tests
+	// that need more wait. If zero, there is no limit.
+	Size int
+	// WaitTimeout limits how long Checkout waits for a database. The
+	// wait also ends shortly before the deadline of the test (see
+	// EventuallyOptions). If zero, DefaultEventuallyTimeout is used.
+	WaitTimeout time.Duration
+	// Create creates and opens a database named name.
+	Create func(ctx context.Context, name string) (*sql.DB, error)
+	// Reset prepares a returned database for the next test. If it
+	// returns an error, the database is closed and not reused. If
+	// nil, TruncateTables is used.
+	Reset func(ctx context.Context, db *sql.DB) error
+
+	mu       sync.Mutex
+	slots    chan struct{}
+	idle     []*sql.DB
+	created  int
+	reused   int
+	discards int
+}
+
+// DBPoolStats counts what a DBPool has done.
+type DBPoolStats struct {
+	Created   int
+	Reused    int
+	Discarded int
+	Idle      int
+}
+
+// DBPoolFixture provides a *sql.DB checked out of pool.
+func DBPoolFixture(pool *DBPool) nject.Provider {
+	return nject.Provide("db-pool-"+pool.Name, func(t T) *sql.DB {
+		return pool.Checkout(t)
+	})
+}
+
+// Checkout takes a database from the pool, creating one if none is idle,
+// and returns it to the pool when the test finishes. If the pool has
+// Size databases checked out, Checkout waits, and fails the test with
+// the state of the pool if none is returned in time.
+func (p *DBPool) Checkout(t T) *sql.DB {
+	t.Helper()
+	p.mu.Lock()
+	if p.slots == nil && p.Size > 0 {
+		p.slots = make(chan struct{}, p.Size)
+	}
+	slots := p.slots
+	p.mu.Unlock()
+	if slots != nil {
+		select {
+		case slots <- struct{}{}:
+		default:
+			start := time.Now()
+			deadline := eventuallyDeadline(t, start, EventuallyOptions{Timeout: p.WaitTimeout})
+			t.Logf("waiting for a database from pool %s", p.Name)
+			timer := time.NewTimer(time.Until(deadline))
+			select {
+			case slots <- struct{}{}:
+				timer.Stop()
+			case <-timer.C:
+				stats := p.Stats()
+				t.Fatalf("no database from pool %s after %s: all %d are checked out (created %d, reused %d, discarded %d, idle %d)",
+					p.Name, time.Since(start).Round(time.Millisecond), p.Size, stats.Created, stats.Reused, stats.Discarded, stats.Idle)
+			}
+			t.Logf("checked out a database from pool %s after %s", p.Name, time.Since(start).Round(time.Millisecond))
+		}
+	}
+	release := func() {
+		if slots != nil {
+			<-slots
+		}
+	}
+	p.mu.Lock()
+	var db *sql.DB
+	if n := len(p.idle); n != 0 {
+		db = p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.reused++
+		p.mu.Unlock()
+	} else {
+		p.created++
+		name := fmt.Sprintf("%s_%d", p.Name, p.created)
+		p.mu.Unlock()
+		var err error
+		db, err = p.Create(context.Background(), name)
+		if err != nil {
+			release()
+			t.Fatalf("create database %s for pool %s: %s", name, p.Name, err)
+		}
+	}
+	t.Cleanup(func() {
+		defer release()
+		reset := p.Reset
+		if reset == nil {
+			reset = TruncateTables
+		}
+		if err := reset(context.Background(), db); err != nil {
+			t.Logf("discarding database from pool %s: reset failed: %s", p.Name, err)
+			_ = db.Close()
+			p.mu.Lock()
+			p.discards++
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Lock()
+		p.idle = append(p.idle, db)
+		p.mu.Unlock()
+	})
+	return db
+}
+
+// Stats returns counts of what the pool has done so far.
+func (p *DBPool) Stats() DBPoolStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return DBPoolStats{
+		Created:   p.created,
+		Reused:    p.reused,
+		Discarded: p.discards,
+		Idle:      len(p.idle),
+	}
+}
+
+// Close closes the idle databases of the pool. It does not drop them.
+func (p *DBPool) Close() error {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	var firstErr error
+	for _, db := range idle {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// TruncateTables truncates every table in the current database of db.
+// It uses information_schema as MySQL and SingleStore provide it.
+func TruncateTables(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'")
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if _, err := db.ExecContext(ctx, "TRUNCATE TABLE "+quoteIdentifier(table)); err != nil {
+			return fmt.Errorf("truncate %s: %w", table, err)
+		}
+	}
+	return nil
+}
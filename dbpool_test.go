@@ -0,0 +1,92 @@
+package ntest_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestDBPool(t *testing.T) {
+	t.Parallel()
+	var names []string
+	resets := 0
+	pool := &ntest.DBPool{
+		Name: "pool",
+		Size: 1,
+		Create: func(_ context.Context, name string) (*sql.DB, error) {
+			names = append(names, name)
+			return sql.Open("ntest-fake", "")
+		},
+		Reset: func(context.Context, *sql.DB) error {
+			resets++
+			if resets == 2 {
+				return errors.New("boom")
+			}
+			return nil
+		},
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+
+	first := &cleanupT{T: t}
+	var db1 *sql.DB
+	ntest.RunTest(first, ntest.DBPoolFixture(pool), func(db *sql.DB) { db1 = db })
+
+	checkedOut := make(chan *sql.DB)
+	second := &cleanupT{T: t}
+	go func() { checkedOut <- pool.Checkout(second) }()
+	select {
+	case <-checkedOut:
+		t.Fatal("checked out more than Size databases")
+	case <-time.After(20 * time.Millisecond):
+	}
+	first.runCleanups()
+	db2 := <-checkedOut
+	assert.Same(t, db1, db2, "reused after reset")
+	second.runCleanups()
+
+	third := &cleanupT{T: t}
+	assert.NotSame(t, db1, pool.Checkout(third), "discarded after failed reset")
+	third.runCleanups()
+
+	assert.Equal(t, []string{"pool_1", "pool_2"}, names)
+	assert.Equal(t, ntest.DBPoolStats{Created: 2, Reused: 1, Discarded: 1, Idle: 1}, pool.Stats())
+}
+
+func TestTruncateTables(t *testing.T) {
+	var executed []string
+	require.NoError(t, ntest.TruncateTables(context.Background(), migratedDB(t, &executed)))
+	assert.Equal(t, []string{
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE'",
+		"TRUNCATE TABLE `2`",
+		"TRUNCATE TABLE `1`",
+	}, executed)
+}
+
+func TestDBPoolWaitTimeout(t *testing.T) {
+	t.Parallel()
+	pool := &ntest.DBPool{
+		Name:        "busy",
+		Size:        1,
+		WaitTimeout: 50 * time.Millisecond,
+		Create: func(context.Context, string) (*sql.DB, error) {
+			return sql.Open("ntest-fake", "")
+		},
+		Reset: func(context.Context, *sql.DB) error { return nil },
+	}
+	defer func() { assert.NoError(t, pool.Close()) }()
+	first := &cleanupT{T: t}
+	pool.Checkout(first)
+	defer first.runCleanups()
+
+	ft := &fatalCapturingT{T: &cleanupT{T: t}}
+	assert.True(t, catchFatal(func() { pool.Checkout(ft) }))
+	require.Equal(t, 1, len(ft.fatals))
+	assert.Regexp(t, `^no database from pool busy after \d+ms: all 1 are checked out \(created 1, reused 0, discarded 0, idle 0\)$`, ft.fatals[0])
+}
@@ -0,0 +1,63 @@
+package ntest
+
+import (
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// Diff compares want and got with go-cmp and, if they differ, marks
+// the test as failed with a readable diff. The failure is reported with
+// t.Errorf after t.Helper() so that the line number is that of the
+// caller. Diff returns true if the values are equal.
+//
+// msgAndOpts may contain cmp.Option values and, optionally, a single
+// string that is included in the failure message.
+func Diff(t T, want, got interface{}, msgAndOpts ...interface{}) bool {
+	t.Helper()
+	var opts []cmp.Option
+	var msg string
+	for _, o := range msgAndOpts {
+		switch o := o.(type) {
+		case cmp.Option:
+			opts = append(opts, o)
+		case string:
+			msg = o
+		default:
+			t.Fatalf("ntest.Diff: unexpected argument of type %T", o)
+		}
+	}
+	diff := cmp.Diff(want, got, opts...)
+	if diff == "" {
+		return true
+	}
+	if msg != "" {
+		t.Errorf("%s: mismatch (-want +got):\n%s", msg, diff)
+	} else {
+		t.Errorf("mismatch (-want +got):\n%s", diff)
+	}
+	return false
+}
+
+// DiffIgnoreUnexported ignores all unexported struct fields, in every
+// struct type. Without it, cmp panics when it encounters unexported fields.
+var DiffIgnoreUnexported = cmp.FilterPath(func(p cmp.Path) bool {
+	sf, ok := p.Last().(cmp.StructField)
+	if !ok {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(sf.Name())
+	return !unicode.IsUpper(r)
+}, cmp.Ignore())
+
+// DiffEquateEmpty treats nil and empty slices and maps as equal.
+var DiffEquateEmpty = cmpopts.EquateEmpty()
+
+// DiffTimeJitter treats time.Time values within margin of each other as
+// equal.
+func DiffTimeJitter(margin time.Duration) cmp.Option {
+	return cmpopts.EquateApproxTime(margin)
+}
@@ -0,0 +1,36 @@
+package ntest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+type diffRecord struct {
+	Name    string
+	When    time.Time
+	Tags    []string
+	private int
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	want := diffRecord{Name: "a", When: now, private: 1}
+	got := diffRecord{Name: "a", When: now.Add(time.Millisecond), Tags: []string{}, private: 2}
+	assert.True(t, ntest.Diff(t, want, got,
+		ntest.DiffIgnoreUnexported,
+		ntest.DiffEquateEmpty,
+		ntest.DiffTimeJitter(time.Second)))
+
+	capture := &errorCapturingT{T: t}
+	got.Name = "b"
+	assert.False(t, ntest.Diff(capture, want, got, "record", ntest.DiffIgnoreUnexported))
+	if assert.Equal(t, 1, len(capture.errors)) {
+		assert.Contains(t, capture.errors[0], "record: mismatch (-want +got):")
+		assert.Contains(t, capture.errors[0], `"b"`)
+	}
+}
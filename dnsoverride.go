@@ -0,0 +1,66 @@
+package ntest
+
+import (
+	"context"
+	"net"
+	"net/http"
+
+	"github.com/muir/nject"
+)
+
+// HostOverrides maps hostnames to the addresses that should be used
+// instead. Keys may be either "host" or "host:port". Values may be either
+// "host:port" or just "host", in which case the original port is kept.
+//
+// Inject HostOverrides before DNSOverrideFixture.
+type HostOverrides map[string]string
+
+// OverrideDialer dials with a net.Dialer after rewriting the address
+// according to its HostOverrides. Addresses that are not overridden
+// are dialed normally.
+type OverrideDialer struct {
+	Overrides HostOverrides
+	Dialer    net.Dialer
+}
+
+// DNSOverrideFixture provides an *OverrideDialer and an *http.Client that
+// uses it, so that code under test can use real-looking hostnames that
+// reach test servers without changes to /etc/hosts.
+var DNSOverrideFixture = nject.Provide("dns-override", func(t T, overrides HostOverrides) (*OverrideDialer, *http.Client) {
+	dialer := &OverrideDialer{Overrides: overrides}
+	client := dialer.HTTPClient()
+	t.Cleanup(client.CloseIdleConnections)
+	return dialer, client
+})
+
+// Resolve returns the address that will actually be dialed for address.
+func (d *OverrideDialer) Resolve(address string) string {
+	if target, ok := d.Overrides[address]; ok {
+		return target
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	target, ok := d.Overrides[host]
+	if !ok {
+		return address
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target
+	}
+	return net.JoinHostPort(target, port)
+}
+
+// DialContext matches the signature of net.Dialer.DialContext.
+func (d *OverrideDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	return d.Dialer.DialContext(ctx, network, d.Resolve(address))
+}
+
+// HTTPClient creates an *http.Client whose transport dials with d.
+func (d *OverrideDialer) HTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = d.DialContext
+	transport.Proxy = nil
+	return &http.Client{Transport: transport}
+}
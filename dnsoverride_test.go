@@ -0,0 +1,40 @@
+package ntest_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestDNSOverride(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello from " + r.Host))
+	}))
+	t.Cleanup(server.Close)
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	ntest.RunTest(t,
+		func() ntest.HostOverrides {
+			return ntest.HostOverrides{"api.example.test": serverURL.Host}
+		},
+		ntest.DNSOverrideFixture,
+		func(client *http.Client, dialer *ntest.OverrideDialer) {
+			assert.Equal(t, "other.example.test:80", dialer.Resolve("other.example.test:80"))
+			resp, err := client.Get("http://api.example.test/")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, "hello from api.example.test", string(body))
+		},
+	)
+}
@@ -0,0 +1,137 @@
+package ntest
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/muir/nject"
+)
+
+// DefaultEnvFile is the file read by LoadEnvFile when no path is given.
+const DefaultEnvFile = ".env.test"
+
+// SecretsProvider looks up the value for a key. It is a hook for
+// loading values from a secrets manager instead of a file.
+type SecretsProvider func(key string) (value string, ok bool)
+
+// LoadEnvFile returns an injector that reads a dotenv-style file and
+// applies each variable with t.Setenv. Variables that are already set in
+// the environment are not overridden. A missing file is not an error.
+//
+// If any of the required keys are not set after loading the file, the test
+// is skipped.
+//
+// Since it uses t.Setenv, it cannot be used in parallel tests.
+func LoadEnvFile(path string, required ...string) nject.Provider {
+	return nject.Required(nject.Provide("load-env-file", func(t T) {
+		LoadEnv(t, path, required...)
+	}))
+}
+
+// LoadEnv is the non-injector version of LoadEnvFile.
+func LoadEnv(t T, path string, required ...string) {
+	t.Helper()
+	if path == "" {
+		path = DefaultEnvFile
+	}
+	vars, err := ReadEnvFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatalf("read env file %s: %s", path, err)
+	}
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		t.Setenv(key, vars[key])
+	}
+	requireEnv(t, "file "+path, required)
+}
+
+// LoadSecrets returns an injector that looks up each key with the
+// SecretsProvider and applies the values with t.Setenv. Keys that are
+// already set in the environment are not looked up. If any key is
+// not found, the test is skipped.
+func LoadSecrets(provider SecretsProvider, keys ...string) nject.Provider {
+	return nject.Required(nject.Provide("load-secrets", func(t T) {
+		t.Helper()
+		for _, key := range keys {
+			if _, ok := os.LookupEnv(key); ok {
+				continue
+			}
+			if value, ok := provider(key); ok {
+				t.Setenv(key, value)
+			}
+		}
+		requireEnv(t, "secrets provider", keys)
+	}))
+}
+
+func requireEnv(t T, source string, required []string) {
+	t.Helper()
+	var missing []string
+	for _, key := range required {
+		if _, ok := os.LookupEnv(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) != 0 {
+		t.Skipf("skipping %s: required environment variables not set (and not found in %s): %s",
+			t.Name(), source, strings.Join(missing, ", "))
+	}
+}
+
+// ReadEnvFile parses a dotenv-style file. Blank lines and lines starting
+// with # are ignored. Lines may start with "export ". Values may be
+// wrapped in single quotes (taken literally) or double quotes (Go
+// escapes are interpreted).
+func ReadEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	vars := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	var lineNumber int
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+		switch {
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			value, err = strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: invalid quoted value: %w", path, lineNumber, err)
+			}
+		default:
+			if j := strings.Index(value, " #"); j != -1 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
@@ -0,0 +1,49 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestLoadEnvFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env.test")
+	require.NoError(t, os.WriteFile(path, []byte(`
+# comment
+NTEST_DOTENV_A=plain # trailing comment
+export NTEST_DOTENV_B="quoted\tvalue"
+NTEST_DOTENV_C='single # not a comment'
+`), 0o600))
+
+	var inner *testing.T
+	t.Run("load", func(t *testing.T) {
+		inner = t
+		ntest.RunTest(t,
+			ntest.LoadEnvFile(path, "NTEST_DOTENV_A"),
+			func() {
+				assert.Equal(t, "plain", os.Getenv("NTEST_DOTENV_A"))
+				assert.Equal(t, "quoted\tvalue", os.Getenv("NTEST_DOTENV_B"))
+				assert.Equal(t, "single # not a comment", os.Getenv("NTEST_DOTENV_C"))
+			},
+		)
+	})
+	assert.False(t, inner.Skipped(), "skipped")
+	_, ok := os.LookupEnv("NTEST_DOTENV_A")
+	assert.False(t, ok, "restored after test")
+
+	t.Run("missing", func(t *testing.T) {
+		inner = t
+		ntest.RunTest(t,
+			ntest.LoadSecrets(func(string) (string, bool) { return "", false }, "NTEST_DOTENV_MISSING"),
+			func() {
+				t.Error("should have been skipped")
+			},
+		)
+	})
+	assert.True(t, inner.Skipped(), "skipped")
+}
@@ -0,0 +1,107 @@
+package ntest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+)
+
+// DumpThreshold is the size, in bytes, above which Dump writes content to
+// the artifact directory instead of logging all of it.
+var DumpThreshold = 64 << 10
+
+// DumpContextLines is the number of lines from the start and from the
+// end of oversized content that are logged.
+var DumpContextLines = 20
+
+// Dump logs diagnostic content, like a server log or a state dump, that
+// may be too large to read in test output. Content up to DumpThreshold
+// bytes is logged as is. Anything larger is written, gzip-compressed, to
+// name+".gz" in the ArtifactDir of the test and only its first and last
+// DumpContextLines lines are logged, along with the path.
+func Dump(t T, name string, content []byte) {
+	t.Helper()
+	if len(content) <= DumpThreshold {
+		t.Logf("%s:\n%s", name, content)
+		return
+	}
+	// Fatalf would abort the test for the sake of a diagnostic
+	dir, err := artifactDir(t)
+	path := filepath.Join(dir, artifactSegment(name)+".gz")
+	if err == nil {
+		err = writeGzip(path, content)
+	}
+	if err != nil {
+		t.Logf("%s: could not write %s: %s", name, path, err)
+		path = ""
+	}
+	logTruncated(t, name, content, path)
+}
+
+// logTruncated logs the start and end of content, which is saved in full
+// at path.
+func logTruncated(t T, name string, content []byte, path string) {
+	t.Helper()
+	head, tail := headTail(content, DumpContextLines)
+	omitted := len(content) - len(head) - len(tail)
+	where := "not saved"
+	if path != "" {
+		where = "full content in " + path
+	}
+	t.Logf("%s (%d bytes, %s):\n%s\n... %d bytes omitted ...\n%s", name, len(content), where, head, omitted, tail)
+}
+
+// headTail returns up to n lines from the start and end of content. Each
+// is limited to DumpThreshold/2 bytes so that content with very long
+// lines is still shortened.
+func headTail(content []byte, n int) (head []byte, tail []byte) {
+	limit := DumpThreshold / 2
+	head = content
+	for i, start := 0, 0; i < n; i++ {
+		j := bytes.IndexByte(content[start:], '\n')
+		if j == -1 {
+			break
+		}
+		start += j + 1
+		head = content[:start]
+	}
+	if len(head) > limit {
+		head = head[:limit]
+	}
+	rest := content[len(head):]
+	tail = rest
+	end := len(rest)
+	if end > 0 && rest[end-1] == '\n' {
+		end--
+	}
+	for i := 0; i < n; i++ {
+		j := bytes.LastIndexByte(rest[:end], '\n')
+		if j == -1 {
+			break
+		}
+		end = j
+		tail = rest[j+1:]
+	}
+	if len(tail) > limit {
+		tail = tail[len(tail)-limit:]
+	}
+	return bytes.TrimSuffix(head, []byte("\n")), tail
+}
+
+func writeGzip(path string, content []byte) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(f)
+	if _, err := zw.Write(content); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
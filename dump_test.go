@@ -0,0 +1,52 @@
+package ntest_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestDump(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		caught = append(caught, s)
+	})
+
+	ntest.Dump(captureT, "small", []byte("one\ntwo\n"))
+	require.Len(t, caught, 1)
+	assert.Equal(t, "small:\none\ntwo\n", caught[0])
+
+	var big bytes.Buffer
+	for i := 0; big.Len() <= ntest.DumpThreshold; i++ {
+		fmt.Fprintf(&big, "line %d of a large server log\n", i)
+	}
+	ntest.Dump(captureT, "server log", big.Bytes())
+	require.Len(t, caught, 2)
+	path := filepath.Join(ntest.ArtifactDir(t), "server_log.gz")
+	assert.Contains(t, caught[1], "full content in "+path)
+	assert.Contains(t, caught[1], "\nline 0 of a large server log\n")
+	assert.Contains(t, caught[1], fmt.Sprintf("\nline %d of a large server log\n", ntest.DumpContextLines-1))
+	assert.NotContains(t, caught[1], fmt.Sprintf("\nline %d of a large server log\n", ntest.DumpContextLines))
+	assert.True(t, strings.HasSuffix(caught[1], big.String()[strings.LastIndex(strings.TrimSuffix(big.String(), "\n"), "\n")+1:]))
+	assert.Less(t, len(caught[1]), 4096)
+
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	full, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Equal(t, big.Bytes(), full)
+}
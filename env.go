@@ -0,0 +1,49 @@
+package ntest
+
+import (
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/muir/nject"
+)
+
+// envBool reports whether the environment variable is set to a true
+// value as understood by strconv.ParseBool.
+func envBool(key string) bool {
+	b, _ := strconv.ParseBool(os.Getenv(key))
+	return b
+}
+
+// EnvMatrix creates a matrix, for RunMatrix, with a cell for each set of
+// environment variables. The variables are set with Setenv at the start
+// of the cell and restored when the cell finishes, so code that is
+// configured by the environment can be tested in each configuration:
+//
+//	ntest.RunMatrix(t,
+//		ntest.EnvMatrix(map[string]map[string]string{
+//			"default": nil,
+//			"tls":     {"DB_TLS": "true", "DB_PORT": "3307"},
+//		}),
+//		...
+//	)
+//
+// Since the testing package does not allow Setenv in parallel tests, it
+// cannot be used with RunParallelMatrix.
+func EnvMatrix(cells map[string]map[string]string) map[string]nject.Provider {
+	matrix := make(map[string]nject.Provider, len(cells))
+	for name, env := range cells {
+		keys := make([]string, 0, len(env))
+		for key := range env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		env := env
+		matrix[name] = nject.Required(nject.Provide("env-"+name, func(t T) {
+			for _, key := range keys {
+				Setenv(t, key, env[key])
+			}
+		}))
+	}
+	return matrix
+}
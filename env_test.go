@@ -0,0 +1,31 @@
+package ntest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestEnvMatrix(t *testing.T) {
+	t.Setenv("NTEST_ENV_MATRIX_PORT", "3306")
+	seen := make(map[string]string)
+	ntest.RunMatrix(t,
+		ntest.EnvMatrix(map[string]map[string]string{
+			"default": nil,
+			"tls":     {"NTEST_ENV_MATRIX_PORT": "3307", "NTEST_ENV_MATRIX_TLS": "true"},
+		}),
+		func(info ntest.CellInfo) {
+			seen[info.Path] = os.Getenv("NTEST_ENV_MATRIX_PORT") + "/" + os.Getenv("NTEST_ENV_MATRIX_TLS")
+		},
+	)
+	assert.Equal(t, map[string]string{
+		"default": "3306/",
+		"tls":     "3307/true",
+	}, seen)
+	assert.Equal(t, "3306", os.Getenv("NTEST_ENV_MATRIX_PORT"))
+	_, ok := os.LookupEnv("NTEST_ENV_MATRIX_TLS")
+	assert.False(t, ok)
+}
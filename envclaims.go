@@ -0,0 +1,98 @@
+package ntest
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvIsolation makes Setenv track which tests have set each environment
+// variable. A test fails if it sets a variable that another test that
+// is still running (other than its parent) has set, or if, when it
+// finishes, a variable that it set no longer has its value. In both
+// cases the failure names the tests involved. The testing package does
+// not allow Setenv in parallel tests, but other implementations of T,
+// and direct calls to os.Setenv, can still interfere with each other.
+var EnvIsolation = true
+
+type envClaim struct {
+	test  string
+	value string
+}
+
+var envClaims struct {
+	mu     sync.Mutex
+	claims map[string][]envClaim
+}
+
+// claimEnv records that t set key to value until it finishes.
+func claimEnv(t T, key, value string) {
+	if !EnvIsolation {
+		return
+	}
+	t.Helper()
+	claim := envClaim{test: t.Name(), value: value}
+	envClaims.mu.Lock()
+	if envClaims.claims == nil {
+		envClaims.claims = make(map[string][]envClaim)
+	}
+	var conflicting []string
+	values := make(map[string]string)
+	for _, other := range envClaims.claims[key] {
+		if other.test != claim.test && !strings.HasPrefix(claim.test, other.test+"/") {
+			if _, ok := values[other.test]; !ok {
+				conflicting = append(conflicting, other.test)
+			}
+			values[other.test] = other.value
+		}
+	}
+	envClaims.claims[key] = append(envClaims.claims[key], claim)
+	envClaims.mu.Unlock()
+	if len(conflicting) != 0 {
+		conflicts := make([]string, len(conflicting))
+		for i, test := range conflicting {
+			conflicts[i] = test + " (set it to " + quoteEnv(values[test]) + ")"
+		}
+		t.Errorf("Setenv(%s) in %s conflicts with tests that are still running: %s", key, t.Name(), strings.Join(conflicts, ", "))
+	}
+	t.Cleanup(func() {
+		envClaims.mu.Lock()
+		claims := envClaims.claims[key]
+		for i := len(claims) - 1; i >= 0; i-- {
+			if claims[i] == claim {
+				claims = append(claims[:i:i], claims[i+1:]...)
+				break
+			}
+		}
+		var others []string
+		seen := make(map[string]bool)
+		for _, other := range claims {
+			if other.test != claim.test && !seen[other.test] {
+				seen[other.test] = true
+				others = append(others, other.test)
+			}
+		}
+		if len(claims) == 0 {
+			delete(envClaims.claims, key)
+		} else {
+			envClaims.claims[key] = claims
+		}
+		envClaims.mu.Unlock()
+		if current, ok := os.LookupEnv(key); !ok || current != value {
+			if !ok {
+				current = "(unset)"
+			} else {
+				current = quoteEnv(current)
+			}
+			msg := "environment variable " + key + " was changed from " + quoteEnv(value) + " to " + current + " while " + t.Name() + " was running"
+			if len(others) != 0 {
+				msg += "; it was also set by " + strings.Join(others, ", ")
+			}
+			t.Errorf("%s", msg)
+		}
+	})
+}
+
+func quoteEnv(s string) string {
+	return `"` + s + `"`
+}
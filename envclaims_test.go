@@ -0,0 +1,43 @@
+package ntest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestEnvIsolation(t *testing.T) {
+	const key = "NTEST_ENV_ISOLATION"
+	t.Setenv(key, "")
+
+	a := &envT{T: t, name: "TestA"}
+	aSub := &envT{T: t, name: "TestA/sub"}
+	b := &envT{T: t, name: "TestB"}
+
+	ntest.Setenv(a, key, "a")
+	ntest.Setenv(a, key, "a2")
+	ntest.Setenv(aSub, key, "sub")
+	assert.Empty(t, aSub.errors, "subtests may override their parent")
+	aSub.finish()
+	assert.Empty(t, aSub.errors)
+
+	ntest.Setenv(b, key, "b")
+	require.Len(t, b.errors, 1)
+	assert.Contains(t, b.errors[0], "Setenv(NTEST_ENV_ISOLATION) in TestB conflicts with tests that are still running: TestA (set it to \"a2\")")
+
+	a.finish()
+	require.Len(t, a.errors, 1, "a's value was replaced by b")
+	assert.Contains(t, a.errors[0], `environment variable NTEST_ENV_ISOLATION was changed from "a2" to "b" while TestA was running; it was also set by TestB`)
+	b.finish()
+
+	c := &envT{T: t, name: "TestC"}
+	ntest.Setenv(c, key, "c")
+	_ = os.Unsetenv(key)
+	c.finish()
+	require.Len(t, c.errors, 1)
+	assert.Contains(t, c.errors[0], `changed from "c" to (unset) while TestC was running`)
+}
@@ -0,0 +1,149 @@
+package ntest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// EventsEnv names the environment variable that, when set to a file path,
+// enables an EventReporter writing to that path.
+const EventsEnv = "NTEST_EVENTS"
+
+func enableEvents(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create event stream: %w", err)
+	}
+	AddReporter(NewEventReporter(f))
+	return nil
+}
+
+// EventType identifies a lifecycle event
+type EventType string
+
+// Lifecycle events
+const (
+	EventTestStart       EventType = "test_start"
+	EventTestEnd         EventType = "test_end"
+	EventCellStart       EventType = "cell_start"
+	EventCellEnd         EventType = "cell_end"
+	EventFixtureCreated  EventType = "fixture_created"
+	EventFixtureReleased EventType = "fixture_released"
+	// EventInjector is emitted when an injector wrapped with TimeInjector
+	// returns. Elapsed is the time spent in the injector.
+	EventInjector EventType = "injector"
+)
+
+// Event is a test lifecycle event. Test is the full name of the test.
+// Name is the matrix cell or fixture name.
+type Event struct {
+	Time    time.Time     `json:"time"`
+	Type    EventType     `json:"type"`
+	Test    string        `json:"test"`
+	Name    string        `json:"name,omitempty"`
+	Elapsed time.Duration `json:"elapsed_ns,omitempty"`
+	// Outcome is "pass", "fail", or "skip" for end events
+	Outcome string `json:"outcome,omitempty"`
+}
+
+// EventListener can be implemented by a Reporter to receive matrix cell
+// and fixture events in addition to test results.
+type EventListener interface {
+	Event(Event)
+}
+
+func emitEvent(e Event) {
+	for _, r := range currentReporters() {
+		if listener, ok := r.(EventListener); ok {
+			listener.Event(e)
+		}
+	}
+}
+
+// ReportFixture emits a fixture created event now and a fixture released
+// event when the test cleans up. It also attaches the fixture name to the
+// test with Attr. Fixtures that hold external resources
+// (containers, databases) should call it so that they show up in event
+// streams and in the output of the hang watchdog.
+func ReportFixture(t T, name string) {
+	Attr(t, "ntest.fixture", name)
+	start := time.Now()
+	emitEvent(Event{Time: start, Type: EventFixtureCreated, Test: t.Name(), Name: name})
+	fixtureOpened(t.Name(), name, start)
+	t.Cleanup(func() {
+		fixtureClosed(t.Name(), name)
+		emitEvent(Event{
+			Time:    time.Now(),
+			Type:    EventFixtureReleased,
+			Test:    t.Name(),
+			Name:    name,
+			Elapsed: time.Since(start),
+		})
+	})
+}
+
+func outcome(failed, skipped bool) string {
+	switch {
+	case failed:
+		return "fail"
+	case skipped:
+		return "skip"
+	default:
+		return "pass"
+	}
+}
+
+// EventReporter is a Reporter that writes every lifecycle event as a line
+// of JSON (NDJSON).
+type EventReporter struct {
+	w   io.Writer
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+var (
+	_ Reporter      = &EventReporter{}
+	_ EventListener = &EventReporter{}
+)
+
+// NewEventReporter creates an EventReporter writing to w. If w is an
+// io.Closer other than os.Stdout, it is closed by Close.
+func NewEventReporter(w io.Writer) *EventReporter {
+	return &EventReporter{
+		w:   w,
+		enc: json.NewEncoder(w),
+	}
+}
+
+func (r *EventReporter) TestStarted(name string, start time.Time) {
+	r.Event(Event{Time: start, Type: EventTestStart, Test: name})
+}
+
+func (r *EventReporter) TestFinished(result TestResult) {
+	r.Event(Event{
+		Time:    result.Start.Add(result.Duration),
+		Type:    EventTestEnd,
+		Test:    result.Name,
+		Elapsed: result.Duration,
+		Outcome: outcome(result.Failed, result.Skipped),
+	})
+}
+
+func (r *EventReporter) Event(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		fmt.Fprintf(os.Stderr, "ntest: write event: %s\n", err)
+	}
+}
+
+func (r *EventReporter) Close() error {
+	if closer, ok := r.w.(io.Closer); ok && r.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package ntest_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// eventRecorder keeps events for tests whose names start with prefix.
+type eventRecorder struct {
+	recordingReporter
+	events []ntest.Event
+}
+
+func (r *eventRecorder) Event(e ntest.Event) {
+	if !strings.HasPrefix(e.Test, r.prefix) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+func TestEvents(t *testing.T) {
+	recorder := &eventRecorder{recordingReporter: recordingReporter{prefix: t.Name() + "/"}}
+	t.Cleanup(ntest.AddReporter(recorder))
+	ntest.RunMatrix(t,
+		map[string]nject.Provider{
+			"cell": nject.Provide("cell", func() int { return 1 }),
+		},
+		func(t ntest.T, _ int) {
+			ntest.ReportFixture(t, "widget")
+		},
+	)
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	var types []ntest.EventType
+	for _, e := range recorder.events {
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []ntest.EventType{
+		ntest.EventCellStart,
+		ntest.EventFixtureCreated,
+		ntest.EventFixtureReleased,
+		ntest.EventCellEnd,
+	}, types)
+	assert.Equal(t, "widget", recorder.events[1].Name)
+	assert.Equal(t, "pass", recorder.events[3].Outcome)
+}
+
+func TestEventReporter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	reporter := ntest.NewEventReporter(&buf)
+	reporter.TestStarted("TestA", time.Now())
+	reporter.TestFinished(ntest.TestResult{Name: "TestA", Failed: true})
+	require.NoError(t, reporter.Close())
+	var events []ntest.Event
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e ntest.Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	require.Equal(t, 2, len(events))
+	assert.Equal(t, ntest.EventTestStart, events[0].Type)
+	assert.Equal(t, ntest.EventTestEnd, events[1].Type)
+	assert.Equal(t, "fail", events[1].Outcome)
+}
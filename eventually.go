@@ -0,0 +1,120 @@
+package ntest
+
+import (
+	"strings"
+	"time"
+)
+
+// EventuallyOptions controls Eventually. The zero value is usable.
+type EventuallyOptions struct {
+	// Timeout is the maximum time to keep trying. If zero, Eventually
+	// tries until DeadlineMargin before the test deadline or, if the
+	// test has no deadline, for DefaultEventuallyTimeout. Timeouts are
+	// multiplied by TimeoutScale.
+	Timeout time.Duration
+	// Interval is the time between attempts. If zero,
+	// DefaultEventuallyInterval is used.
+	Interval time.Duration
+	// DeadlineMargin is how much time to leave before the test's deadline
+	// (go test -timeout) so that the failure can be reported before the
+	// test binary is killed. If zero, DefaultDeadlineMargin is used.
+	DeadlineMargin time.Duration
+	// Description is included in log lines and the failure message.
+	Description string
+}
+
+// Defaults for EventuallyOptions
+const (
+	DefaultEventuallyTimeout  = time.Minute
+	DefaultEventuallyInterval = 100 * time.Millisecond
+	DefaultDeadlineMargin     = 5 * time.Second
+)
+
+type deadliner interface {
+	Deadline() (time.Time, bool)
+}
+
+// Eventually calls cond until it returns nil. The error returned by cond
+// describes the current state and is logged each time it changes. If cond
+// does not succeed in time, the test is marked as failed with a message that
+// includes every distinct state that was seen. Eventually returns true if
+// cond succeeded.
+//
+// Eventually is aware of the test's deadline (if the T provides Deadline(),
+// as *testing.T does) and will give up early enough to report the failure.
+func Eventually(t T, cond func() error, opts EventuallyOptions) bool {
+	t.Helper()
+	start := time.Now()
+	deadline := eventuallyDeadline(t, start, opts)
+	interval := opts.Interval
+	if interval == 0 {
+		interval = DefaultEventuallyInterval
+	}
+	what := "condition"
+	if opts.Description != "" {
+		what = opts.Description
+	}
+
+	var states []string
+	var lastState string
+	var attempt int
+	for {
+		attempt++
+		err := cond()
+		if err == nil {
+			if attempt > 1 {
+				t.Logf("%s met after %d attempts (%s)", what, attempt, time.Since(start).Round(time.Millisecond))
+			}
+			return true
+		}
+		state := err.Error()
+		if attempt == 1 || state != lastState {
+			t.Logf("%s not yet met (attempt %d, %s): %s", what, attempt, time.Since(start).Round(time.Millisecond), state)
+			states = append(states, state)
+			lastState = state
+		}
+		if !time.Now().Add(interval).Before(deadline) {
+			break
+		}
+		time.Sleep(interval)
+	}
+	t.Errorf("%s not met after %d attempts over %s\nlast state: %s\nstates seen:\n\t%s",
+		what, attempt, time.Since(start).Round(time.Millisecond), lastState, strings.Join(states, "\n\t"))
+	return false
+}
+
+// safeDeadline calls Deadline, which panics inside a synctest bubble; in
+// that case there is no deadline.
+func safeDeadline(d deadliner) (deadline time.Time, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return d.Deadline()
+}
+
+func eventuallyDeadline(t T, start time.Time, opts EventuallyOptions) time.Time {
+	timeout := ScaledTimeout(opts.Timeout)
+	margin := opts.DeadlineMargin
+	if margin == 0 {
+		margin = DefaultDeadlineMargin
+	}
+	margin = ScaledTimeout(margin)
+	var deadline time.Time
+	if timeout != 0 {
+		deadline = start.Add(timeout)
+	}
+	if d, ok := unwrapAll(t).(deadliner); ok {
+		if testDeadline, ok := safeDeadline(d); ok {
+			testDeadline = testDeadline.Add(-margin)
+			if deadline.IsZero() || testDeadline.Before(deadline) {
+				deadline = testDeadline
+			}
+		}
+	}
+	if deadline.IsZero() {
+		deadline = start.Add(ScaledTimeout(DefaultEventuallyTimeout))
+	}
+	return deadline
+}
@@ -0,0 +1,54 @@
+package ntest_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestEventually(t *testing.T) {
+	t.Parallel()
+	var calls int
+	ok := ntest.Eventually(t, func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("calls=%d", calls)
+		}
+		return nil
+	}, ntest.EventuallyOptions{Interval: time.Millisecond})
+	assert.True(t, ok)
+	assert.Equal(t, 3, calls)
+
+	capture := &errorCapturingT{T: t}
+	ok = ntest.Eventually(capture, func() error {
+		return errors.New("still waiting")
+	}, ntest.EventuallyOptions{
+		Timeout:     20 * time.Millisecond,
+		Interval:    time.Millisecond,
+		Description: "service ready",
+	})
+	assert.False(t, ok)
+	if assert.Equal(t, 1, len(capture.errors)) {
+		assert.Contains(t, capture.errors[0], "service ready not met after")
+		assert.Contains(t, capture.errors[0], "last state: still waiting")
+	}
+}
+
+func TestEventuallyDeadlineThroughWrappers(t *testing.T) {
+	t.Setenv(ntest.TimeoutScaleEnv, "1")
+	capture := &errorCapturingT{T: t}
+	dt := &deadlineT{T: capture, deadline: time.Now().Add(ntest.DefaultDeadlineMargin + 100*time.Millisecond)}
+	wrapped := ntest.ReplaceLogger(dt, func(string) {})
+	start := time.Now()
+	ok := ntest.Eventually(wrapped, func() error {
+		return errors.New("never")
+	}, ntest.EventuallyOptions{Timeout: time.Hour, Interval: 10 * time.Millisecond})
+	assert.False(t, ok)
+	assert.Less(t, time.Since(start), 5*time.Second, "gave up before the deadline of the wrapped T")
+	assert.Len(t, capture.errors, 1)
+}
@@ -0,0 +1,105 @@
+package ntest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FailureHookTimeout is how long (scaled with ScaledTimeout) the hooks
+// registered with OnFailure have, through FailureContext.Ctx, to
+// collect diagnostics.
+var FailureHookTimeout = time.Minute
+
+// FailureContext is passed to the hooks registered with OnFailure.
+type FailureContext struct {
+	T T
+	// Ctx is canceled after FailureHookTimeout.
+	Ctx context.Context
+}
+
+// ArtifactDir returns the ArtifactDir of the test. Unlike ArtifactDir,
+// it returns an error rather than abort a test that is already being
+// diagnosed.
+func (fc FailureContext) ArtifactDir() (string, error) {
+	return artifactDir(fc.T)
+}
+
+type failureHook struct {
+	once sync.Once
+	f    func(FailureContext)
+}
+
+var failureHooks struct {
+	mu sync.Mutex
+	// byTest has the hooks registered for tests run with RunTest
+	// that have not yet run
+	byTest map[string][]*failureHook
+}
+
+// OnFailure registers a hook that collects diagnostics, like a state
+// dump, a screenshot, or logs fetched from a service, if t fails. Hooks
+// run once, while the cleanup functions of t run: when the cleanup
+// functions registered after OnFailure have finished (so while what was
+// set up before it still exists) if t has failed by then. For tests run
+// with RunTest, hooks also run, once the other cleanup functions have
+// finished, if t only fails in a later cleanup function. Either way
+// they run before t is reported (see Reporter).
+//
+// A hook that panics is logged and does not stop the others.
+func OnFailure(t T, hook func(FailureContext)) {
+	h := &failureHook{f: hook}
+	name := t.Name()
+	failureHooks.mu.Lock()
+	_, watched := failureHooks.byTest[name]
+	if watched {
+		failureHooks.byTest[name] = append(failureHooks.byTest[name], h)
+	}
+	failureHooks.mu.Unlock()
+	t.Cleanup(func() {
+		if t.Failed() {
+			runFailureHook(t, h)
+		}
+	})
+}
+
+// watchFailureHooks is called by RunTest so that the hooks registered
+// for t run if t fails after their own cleanup functions have run.
+func watchFailureHooks(t T) {
+	name := t.Name()
+	failureHooks.mu.Lock()
+	if failureHooks.byTest == nil {
+		failureHooks.byTest = make(map[string][]*failureHook)
+	}
+	if _, ok := failureHooks.byTest[name]; ok {
+		// nested RunTest for the same test
+		failureHooks.mu.Unlock()
+		return
+	}
+	failureHooks.byTest[name] = []*failureHook{}
+	failureHooks.mu.Unlock()
+	t.Cleanup(func() {
+		failureHooks.mu.Lock()
+		hooks := failureHooks.byTest[name]
+		delete(failureHooks.byTest, name)
+		failureHooks.mu.Unlock()
+		if t.Failed() {
+			for _, h := range hooks {
+				runFailureHook(t, h)
+			}
+		}
+	})
+}
+
+func runFailureHook(t T, h *failureHook) {
+	h.once.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), ScaledTimeout(FailureHookTimeout))
+		defer cancel()
+		defer func() {
+			if r := recover(); r != nil {
+				t.Logf("failure hook of %s panicked: %v", t.Name(), r)
+			}
+		}()
+		h.f(FailureContext{T: t, Ctx: ctx})
+	})
+}
@@ -0,0 +1,63 @@
+package ntest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestOnFailure(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	var logged []string
+	ct := &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		logged = append(logged, s)
+	})}
+	ft := &failingT{cleanupT: ct}
+	var order []string
+	ntest.RunTest(ft, func(t ntest.T) {
+		ntest.OnFailure(t, func(ntest.FailureContext) {
+			order = append(order, "first")
+			panic("oops")
+		})
+		ntest.OnFailure(t, func(fc ntest.FailureContext) {
+			order = append(order, "second")
+			dir, err := fc.ArtifactDir()
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(dir, root), dir)
+			assert.NoError(t, fc.Ctx.Err())
+		})
+		t.Errorf("fail")
+	})
+	assert.Empty(t, order, "hooks run during cleanup")
+	ct.runCleanups()
+	assert.Equal(t, []string{"second", "first"}, order)
+	assert.Contains(t, logged, "failure hook of TestOnFailure panicked: oops")
+
+	order = nil
+	ct = &cleanupT{T: t}
+	ntest.RunTest(ct, func(t ntest.T) {
+		ntest.OnFailure(t, func(ntest.FailureContext) {
+			order = append(order, "hook")
+		})
+	})
+	ct.runCleanups()
+	assert.Empty(t, order, "not run for tests that pass")
+}
+
+func TestOnFailureLate(t *testing.T) {
+	ft := &failingT{cleanupT: &cleanupT{T: t}}
+	var calls int
+	ntest.RunTest(ft, func(t ntest.T) {
+		// runs after the hook's own cleanup function
+		t.Cleanup(func() { t.Errorf("teardown failed") })
+		ntest.OnFailure(t, func(ntest.FailureContext) { calls++ })
+	})
+	ft.runCleanups()
+	require.Equal(t, []string{"teardown failed"}, ft.errors)
+	assert.Equal(t, 1, calls)
+}
@@ -0,0 +1,129 @@
+package ntest
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+var fakes struct {
+	mu     sync.Mutex
+	byTest map[string]map[reflect.Type]reflect.Value
+}
+
+// Fake registers fake as the I for t and its subtests. When RunTest
+// runs a chain for them, fake is injected wherever an I is wanted,
+// even if the chain also has a provider for I (or for a type that
+// implements I). That makes it possible to stub one dependency of a
+// large shared chain:
+//
+//	ntest.Fake[Mailer](t, &fakeMailer{})
+//	ntest.RunTest(t, integrationChain, func(s *Server) { ... })
+//
+// Real providers that are only needed for I are no longer called. The
+// registration is removed when t finishes.
+func Fake[I any](t T, fake I) {
+	typ := reflect.TypeOf((*I)(nil)).Elem()
+	name := t.Name()
+	fakes.mu.Lock()
+	if fakes.byTest == nil {
+		fakes.byTest = make(map[string]map[reflect.Type]reflect.Value)
+	}
+	if fakes.byTest[name] == nil {
+		fakes.byTest[name] = make(map[reflect.Type]reflect.Value)
+	}
+	fakes.byTest[name][typ] = reflect.ValueOf(&fake).Elem()
+	fakes.mu.Unlock()
+	t.Cleanup(func() {
+		fakes.mu.Lock()
+		defer fakes.mu.Unlock()
+		delete(fakes.byTest[name], typ)
+		if len(fakes.byTest[name]) == 0 {
+			delete(fakes.byTest, name)
+		}
+	})
+}
+
+// fakesFor returns the fakes registered for a test and its parents, the
+// innermost registration for each type winning.
+func fakesFor(name string) map[reflect.Type]reflect.Value {
+	fakes.mu.Lock()
+	defer fakes.mu.Unlock()
+	var found map[reflect.Type]reflect.Value
+	for {
+		for typ, v := range fakes.byTest[name] {
+			if found == nil {
+				found = make(map[reflect.Type]reflect.Value)
+			}
+			if _, ok := found[typ]; !ok {
+				found[typ] = v
+			}
+		}
+		i := strings.LastIndexByte(name, '/')
+		if i == -1 {
+			return found
+		}
+		name = name[:i]
+	}
+}
+
+// substituteFakes adds providers for the fakes registered for t to
+// chain: one at the start and one after each provider of the faked type.
+// The number of elements in chain does not change.
+func substituteFakes(t T, chain []interface{}) []interface{} {
+	registered := fakesFor(t.Name())
+	if len(registered) == 0 || len(chain) == 0 {
+		return chain
+	}
+	types := make([]reflect.Type, 0, len(registered))
+	for typ := range registered {
+		types = append(types, typ)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].String() < types[j].String() })
+	providers := make([]nject.Provider, len(types))
+	for i, typ := range types {
+		v := registered[typ]
+		fn := reflect.MakeFunc(reflect.FuncOf(nil, []reflect.Type{typ}, false),
+			func([]reflect.Value) []reflect.Value { return []reflect.Value{v} })
+		providers[i] = nject.Provide("fake-"+typ.String(), fn.Interface())
+	}
+	substituted := make([]interface{}, len(chain))
+	for i, element := range chain {
+		var changed bool
+		var contents []interface{}
+		if i == 0 {
+			for _, p := range providers {
+				contents = append(contents, p)
+			}
+			changed = true
+		}
+		nject.Sequence("fakes", element).ForEachProvider(func(p nject.Provider) {
+			contents = append(contents, p)
+			_, outputs := p.DownFlows()
+			for j, typ := range types {
+				if providesType(outputs, typ) {
+					contents = append(contents, providers[j])
+					changed = true
+				}
+			}
+		})
+		if changed {
+			substituted[i] = nject.Sequence("fakes", contents...)
+		} else {
+			substituted[i] = element
+		}
+	}
+	return substituted
+}
+
+func providesType(outputs []reflect.Type, typ reflect.Type) bool {
+	for _, out := range outputs {
+		if out == typ || (typ.Kind() == reflect.Interface && out.Implements(typ)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+type mailer interface {
+	Send(to string) string
+}
+
+type realMailer struct{}
+
+func (realMailer) Send(to string) string { return "real mail to " + to }
+
+type fakeMailer struct{}
+
+func (fakeMailer) Send(to string) string { return "fake mail to " + to }
+
+type notifier struct {
+	mailer mailer
+}
+
+func TestFake(t *testing.T) {
+	var realCalls int
+	chain := []interface{}{
+		func() mailer {
+			realCalls++
+			return realMailer{}
+		},
+		func(m mailer) *notifier { return &notifier{mailer: m} },
+	}
+
+	t.Run("registered", func(t *testing.T) {
+		ntest.Fake[mailer](t, fakeMailer{})
+		ntest.RunTest(t, append(chain, func(m mailer, n *notifier) {
+			assert.Equal(t, "fake mail to a", m.Send("a"))
+			assert.Equal(t, "fake mail to b", n.mailer.Send("b"), "injectors after the real provider get the fake")
+		})...)
+		assert.Equal(t, 0, realCalls, "real provider not needed")
+
+		t.Run("subtest", func(t *testing.T) {
+			ntest.RunTest(t, append(chain, func(m mailer) {
+				assert.Equal(t, "fake mail to c", m.Send("c"))
+			})...)
+		})
+	})
+
+	t.Run("implementation", func(t *testing.T) {
+		ntest.Fake[mailer](t, fakeMailer{})
+		ntest.RunTest(t,
+			func() realMailer { return realMailer{} },
+			func(m mailer) {
+				assert.Equal(t, "fake mail to d", m.Send("d"))
+			})
+	})
+
+	t.Run("unregistered", func(t *testing.T) {
+		ntest.RunTest(t, append(chain, func(m mailer) {
+			assert.Equal(t, "real mail to e", m.Send("e"))
+		})...)
+		assert.Equal(t, 1, realCalls)
+	})
+}
@@ -0,0 +1,234 @@
+package ntest
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// ProxyTarget is the address (host:port) that FaultProxyFixture forwards to.
+// Inject one before FaultProxyFixture.
+type ProxyTarget string
+
+// FaultProxy is a TCP proxy that can inject faults: latency, bandwidth
+// limits, and connection resets. Faults can be changed at any time
+// from the test and apply to data that is forwarded after the change.
+//
+// Point the client under test at Addr() instead of the real target.
+type FaultProxy struct {
+	target   string
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu        sync.Mutex
+	latency   time.Duration
+	bandwidth int
+	refuse    bool
+	closed    bool
+	conns     map[net.Conn]struct{}
+}
+
+// FaultProxyFixture provides a *FaultProxy that forwards to the injected
+// ProxyTarget. The proxy is shut down when the test finishes.
+var FaultProxyFixture = nject.Provide("fault-proxy", func(t T, target ProxyTarget) *FaultProxy {
+	return NewFaultProxy(t, string(target))
+})
+
+// NewFaultProxy starts a FaultProxy listening on a random local port. It is
+// shut down when the test finishes.
+func NewFaultProxy(t T, target string) *FaultProxy {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fault proxy listen: %s", err)
+	}
+	p := &FaultProxy{
+		target:   target,
+		listener: listener,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	p.wg.Add(1)
+	go p.accept(t)
+	t.Cleanup(p.close)
+	return p
+}
+
+// Addr is the address that clients should connect to.
+func (p *FaultProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Target is the address that the proxy forwards to.
+func (p *FaultProxy) Target() string {
+	return p.target
+}
+
+// SetLatency delays each chunk of data forwarded, in either direction,
+// by d.
+func (p *FaultProxy) SetLatency(d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = d
+}
+
+// SetBandwidth limits each direction of each connection to roughly
+// bytesPerSecond. Zero means unlimited.
+func (p *FaultProxy) SetBandwidth(bytesPerSecond int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.bandwidth = bytesPerSecond
+}
+
+// ResetConnections abruptly closes (with a TCP RST where possible) all
+// connections that are currently open through the proxy.
+func (p *FaultProxy) ResetConnections() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for conn := range p.conns {
+		reset(conn)
+	}
+}
+
+// RefuseConnections causes new connections to be reset immediately after
+// they are accepted.
+func (p *FaultProxy) RefuseConnections(refuse bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refuse = refuse
+}
+
+// Clear removes all faults. It does not restore connections that were
+// already reset.
+func (p *FaultProxy) Clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.latency = 0
+	p.bandwidth = 0
+	p.refuse = false
+}
+
+func (p *FaultProxy) faults() (time.Duration, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.latency, p.bandwidth
+}
+
+func (p *FaultProxy) accept(t T) {
+	defer p.wg.Done()
+	for {
+		client, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		p.mu.Lock()
+		refuse, closed := p.refuse, p.closed
+		p.mu.Unlock()
+		if refuse || closed {
+			reset(client)
+			continue
+		}
+		server, err := net.Dial("tcp", p.target)
+		if err != nil {
+			t.Logf("fault proxy dial %s: %s", p.target, err)
+			reset(client)
+			continue
+		}
+		if !p.track(client, server) {
+			continue
+		}
+		p.wg.Add(1)
+		go p.forward(client, server)
+	}
+}
+
+func (p *FaultProxy) track(conns ...net.Conn) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+		return false
+	}
+	for _, conn := range conns {
+		p.conns[conn] = struct{}{}
+	}
+	return true
+}
+
+func (p *FaultProxy) untrack(conns ...net.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range conns {
+		delete(p.conns, conn)
+	}
+}
+
+func (p *FaultProxy) forward(client, server net.Conn) {
+	defer p.wg.Done()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go p.pipe(&wg, client, server)
+	go p.pipe(&wg, server, client)
+	wg.Wait()
+	_ = client.Close()
+	_ = server.Close()
+	p.untrack(client, server)
+}
+
+// pipe copies from src to dst applying the current faults. An orderly
+// close of src is passed along as a half-close so that the other direction
+// can finish.  Any other error closes both connections.
+func (p *FaultProxy) pipe(wg *sync.WaitGroup, dst, src net.Conn) {
+	defer wg.Done()
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			latency, bandwidth := p.faults()
+			delay := latency
+			if bandwidth > 0 {
+				delay += time.Duration(n) * time.Second / time.Duration(bandwidth)
+			}
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				_ = src.Close()
+				return
+			}
+		}
+		if err != nil {
+			if tcp, ok := dst.(*net.TCPConn); ok && err == io.EOF {
+				_ = tcp.CloseWrite()
+				return
+			}
+			_ = dst.Close()
+			_ = src.Close()
+			return
+		}
+	}
+}
+
+func (p *FaultProxy) close() {
+	p.mu.Lock()
+	p.closed = true
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.mu.Unlock()
+	_ = p.listener.Close()
+	p.wg.Wait()
+}
+
+// reset closes a connection so that the peer sees a connection reset
+// rather than an orderly shutdown.
+func reset(conn net.Conn) {
+	if tcp, ok := conn.(*net.TCPConn); ok {
+		_ = tcp.SetLinger(0)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,72 @@
+package ntest_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func echoServer(t ntest.T) ntest.ProxyTarget {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ntest.ProxyTarget(listener.Addr().String())
+}
+
+func TestFaultProxy(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t,
+		echoServer,
+		ntest.FaultProxyFixture,
+		func(t *testing.T, proxy *ntest.FaultProxy) {
+			conn, err := net.Dial("tcp", proxy.Addr())
+			require.NoError(t, err)
+			defer conn.Close()
+			reader := bufio.NewReader(conn)
+
+			roundTrip := func() (string, time.Duration, error) {
+				start := time.Now()
+				_, err := conn.Write([]byte("ping\n"))
+				if err != nil {
+					return "", 0, err
+				}
+				line, err := reader.ReadString('\n')
+				return line, time.Since(start), err
+			}
+
+			line, _, err := roundTrip()
+			require.NoError(t, err)
+			assert.Equal(t, "ping\n", line)
+
+			proxy.SetLatency(25 * time.Millisecond)
+			line, elapsed, err := roundTrip()
+			require.NoError(t, err)
+			assert.Equal(t, "ping\n", line)
+			assert.GreaterOrEqual(t, elapsed, 50*time.Millisecond, "latency in both directions")
+
+			proxy.Clear()
+			proxy.ResetConnections()
+			_, _, err = roundTrip()
+			assert.Error(t, err, "after reset")
+		},
+	)
+}
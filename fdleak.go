@@ -0,0 +1,67 @@
+package ntest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// FDLeakGrace is how long (scaled with ScaledTimeout) CheckFDLeaks waits
+// for file descriptors that are closed in the background, like the
+// connections of a closing database pool, before reporting them.
+var FDLeakGrace = time.Second
+
+// FDLeakFixture is an injector that applies CheckFDLeaks.
+var FDLeakFixture = nject.Required(nject.Provide("fd-leak-check", CheckFDLeaks))
+
+// CheckFDLeaks fails the test if, when it finishes, the process has file
+// descriptors open that it did not have when CheckFDLeaks was called. The
+// failure lists them with what they refer to: a path or, on Linux, the
+// addresses of a socket. That catches files, listeners, and connections
+// that are never closed. Cleanup functions registered after CheckFDLeaks
+// run before the check.
+//
+// File descriptors are process-wide, so tests that run in parallel with
+// the test can cause false reports, as can idle keep-alive connections of
+// HTTP clients. CheckFDLeaks works on Linux and macOS and does nothing
+// elsewhere.
+func CheckFDLeaks(t T) {
+	before, ok := openFDs()
+	if !ok {
+		return
+	}
+	t.Cleanup(func() {
+		deadline := time.Now().Add(ScaledTimeout(FDLeakGrace))
+		for {
+			after, _ := openFDs()
+			leaked := newFDs(before, after)
+			if len(leaked) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%s leaked %d file descriptors:\n\t%s", t.Name(), len(leaked), strings.Join(leaked, "\n\t"))
+				return
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+}
+
+// newFDs describes the file descriptors in after that are not in before.
+func newFDs(before, after map[int]string) []string {
+	fds := make([]int, 0, len(after))
+	for fd, description := range after {
+		if previous, ok := before[fd]; (!ok || previous != description) && !ignoredFD(description) {
+			fds = append(fds, fd)
+		}
+	}
+	sort.Ints(fds)
+	leaked := make([]string, len(fds))
+	for i, fd := range fds {
+		leaked[i] = strconv.Itoa(fd) + ": " + after[fd]
+	}
+	return leaked
+}
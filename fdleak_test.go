@@ -0,0 +1,51 @@
+package ntest_test
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// Not parallel: file descriptors are process-wide.
+func TestCheckFDLeaks(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("file descriptors are not listed on " + runtime.GOOS)
+	}
+	defer func(grace time.Duration) { ntest.FDLeakGrace = grace }(ntest.FDLeakGrace)
+	ntest.FDLeakGrace = 100 * time.Millisecond
+	path := filepath.Join(t.TempDir(), "leaked.txt")
+
+	capture := &errorCapturingT{T: t}
+	ct := &cleanupT{T: capture}
+	ntest.CheckFDLeaks(ct)
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	ct.runCleanups()
+	require.Len(t, capture.errors, 1)
+	assert.Contains(t, capture.errors[0], "leaked 2 file descriptors")
+	assert.Contains(t, capture.errors[0], "leaked.txt")
+	if runtime.GOOS == "linux" {
+		assert.Contains(t, capture.errors[0], "tcp "+listener.Addr().String())
+	}
+
+	capture = &errorCapturingT{T: t}
+	ct = &cleanupT{T: capture}
+	ntest.CheckFDLeaks(ct)
+	closed, err := os.Open(path)
+	require.NoError(t, err)
+	require.NoError(t, closed.Close())
+	ct.runCleanups()
+	assert.Empty(t, capture.errors)
+}
@@ -0,0 +1,63 @@
+package ntest
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// openFDs describes the open file descriptors of the process
+func openFDs() (map[int]string, bool) {
+	dir, err := os.Open("/dev/fd")
+	if err != nil {
+		return nil, false
+	}
+	names, err := dir.Readdirnames(-1)
+	_ = dir.Close()
+	if err != nil {
+		return nil, false
+	}
+	fds := make(map[int]string, len(names))
+	for _, name := range names {
+		fd, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		var stat syscall.Stat_t
+		if err := syscall.Fstat(fd, &stat); err != nil {
+			// the directory that was being read, now closed
+			continue
+		}
+		switch stat.Mode & syscall.S_IFMT {
+		case syscall.S_IFSOCK:
+			fds[fd] = "socket"
+		case syscall.S_IFIFO:
+			fds[fd] = "pipe"
+		case 0:
+			fds[fd] = "kqueue"
+		default:
+			fds[fd] = fdPath(fd)
+		}
+	}
+	return fds, true
+}
+
+func fdPath(fd int) string {
+	var buf [1024]byte // MAXPATHLEN
+	_, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_GETPATH, uintptr(unsafe.Pointer(&buf[0])))
+	if errno != 0 {
+		return "file"
+	}
+	if i := bytes.IndexByte(buf[:], 0); i != -1 {
+		return string(buf[:i])
+	}
+	return string(buf[:])
+}
+
+// ignoredFD reports if a file descriptor belongs to the runtime
+// network poller, which is created the first time it is needed.
+func ignoredFD(description string) bool {
+	return description == "kqueue"
+}
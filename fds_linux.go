@@ -0,0 +1,112 @@
+package ntest
+
+import (
+	"bufio"
+	"encoding/hex"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// openFDs describes the open file descriptors of the process
+func openFDs() (map[int]string, bool) {
+	dir, err := os.Open("/proc/self/fd")
+	if err != nil {
+		return nil, false
+	}
+	names, err := dir.Readdirnames(-1)
+	_ = dir.Close()
+	if err != nil {
+		return nil, false
+	}
+	fds := make(map[int]string, len(names))
+	var sockets map[string]string
+	for _, name := range names {
+		fd, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		target, err := os.Readlink("/proc/self/fd/" + name)
+		if err != nil {
+			// the directory that was being read, now closed
+			continue
+		}
+		if strings.HasPrefix(target, "socket:[") {
+			if sockets == nil {
+				sockets = procSockets()
+			}
+			if description, ok := sockets[strings.TrimSuffix(strings.TrimPrefix(target, "socket:["), "]")]; ok {
+				target = description + " (" + target + ")"
+			}
+		}
+		fds[fd] = target
+	}
+	return fds, true
+}
+
+// ignoredFD reports if a file descriptor belongs to the runtime
+// network poller, which is created the first time it is needed.
+func ignoredFD(description string) bool {
+	return description == "anon_inode:[eventpoll]" || description == "anon_inode:[eventfd]"
+}
+
+// procSockets describes the sockets of the process by inode.
+func procSockets() map[string]string {
+	sockets := make(map[string]string)
+	for _, network := range []string{"tcp", "tcp6", "udp", "udp6"} {
+		readProcNet(network, func(fields []string) {
+			if len(fields) < 10 {
+				return
+			}
+			description := network + " " + procAddr(fields[1])
+			if remote := procAddr(fields[2]); !strings.HasSuffix(remote, ":0") {
+				description += " -> " + remote
+			}
+			sockets[fields[9]] = description
+		})
+	}
+	readProcNet("unix", func(fields []string) {
+		if len(fields) < 7 {
+			return
+		}
+		description := "unix"
+		if len(fields) > 7 {
+			description += " " + fields[7]
+		}
+		sockets[fields[6]] = description
+	})
+	return sockets
+}
+
+func readProcNet(network string, f func(fields []string)) {
+	file, err := os.Open("/proc/self/net/" + network)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // header
+	for scanner.Scan() {
+		f(strings.Fields(scanner.Text()))
+	}
+}
+
+// procAddr decodes an address like "0100007F:1F90" from /proc/net/tcp,
+// where the IP address is in groups of four bytes in host (little
+// endian) order.
+func procAddr(s string) string {
+	i := strings.IndexByte(s, ':')
+	if i == -1 {
+		return s
+	}
+	b, err := hex.DecodeString(s[:i])
+	port, portErr := strconv.ParseUint(s[i+1:], 16, 16)
+	if err != nil || portErr != nil || len(b)%4 != 0 {
+		return s
+	}
+	for j := 0; j < len(b); j += 4 {
+		b[j], b[j+1], b[j+2], b[j+3] = b[j+3], b[j+2], b[j+1], b[j]
+	}
+	return net.JoinHostPort(net.IP(b).String(), strconv.FormatUint(port, 10))
+}
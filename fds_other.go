@@ -0,0 +1,12 @@
+//go:build !linux && !darwin
+
+package ntest
+
+// open file descriptors are not listed on this platform
+func openFDs() (map[int]string, bool) {
+	return nil, false
+}
+
+func ignoredFD(string) bool {
+	return false
+}
@@ -0,0 +1,91 @@
+package ntest
+
+import (
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// FeatureFlags is the minimal interface that ntest needs in order
+// to override feature flags during a test. Applications can adapt
+// their own flag system to it or use MapFeatureFlags.
+type FeatureFlags interface {
+	Enabled(name string) bool
+	Set(name string, enabled bool)
+}
+
+// MapFeatureFlags is a concurrency-safe FeatureFlags backed by a map.
+// Flags that have never been set are disabled.
+type MapFeatureFlags struct {
+	mu    sync.Mutex
+	flags map[string]bool
+}
+
+var _ FeatureFlags = &MapFeatureFlags{}
+
+// NewMapFeatureFlags creates a MapFeatureFlags with optional initial values.
+func NewMapFeatureFlags(initial map[string]bool) *MapFeatureFlags {
+	flags := make(map[string]bool, len(initial))
+	for name, enabled := range initial {
+		flags[name] = enabled
+	}
+	return &MapFeatureFlags{flags: flags}
+}
+
+func (f *MapFeatureFlags) Enabled(name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.flags[name]
+}
+
+func (f *MapFeatureFlags) Set(name string, enabled bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags[name] = enabled
+}
+
+// FeatureFlagsFixture provides a fresh FeatureFlags for each test. It is named
+// "feature-flags" so that it can be replaced with nject.ReplaceNamed, or
+// more simply with InjectFeatureFlags.
+var FeatureFlagsFixture = nject.Provide("feature-flags", func() FeatureFlags {
+	return NewMapFeatureFlags(nil)
+})
+
+// InjectFeatureFlags replaces FeatureFlagsFixture so that a specific
+// FeatureFlags (perhaps the application's global one) is used instead.
+// Since overrides modify the shared flags, tests that override flags on a
+// shared FeatureFlags should not be run in parallel with each other.
+func InjectFeatureFlags(flags FeatureFlags) nject.Provider {
+	return nject.ReplaceNamed("feature-flags", func() FeatureFlags {
+		return flags
+	})
+}
+
+// OverrideFeatureFlag sets a flag for the duration of a test. The
+// previous value is restored when the test finishes.
+func OverrideFeatureFlag(name string, enabled bool) nject.Provider {
+	return nject.Required(nject.Provide("override-feature-flag-"+name,
+		func(t T, flags FeatureFlags) {
+			SetFeatureFlag(t, flags, name, enabled)
+		}))
+}
+
+// SetFeatureFlag sets a flag immediately and restores the previous value
+// when the test finishes. It is for use inside tests and injectors.
+func SetFeatureFlag(t T, flags FeatureFlags, name string, enabled bool) {
+	previous := flags.Enabled(name)
+	flags.Set(name, enabled)
+	t.Cleanup(func() {
+		flags.Set(name, previous)
+	})
+}
+
+// FeatureFlagMatrix creates a matrix that runs the test once with the
+// named flag enabled and once with it disabled. Pass it to RunMatrix
+// or RunParallelMatrix.
+func FeatureFlagMatrix(name string) map[string]nject.Provider {
+	return map[string]nject.Provider{
+		name + "=on":  OverrideFeatureFlag(name, true),
+		name + "=off": OverrideFeatureFlag(name, false),
+	}
+}
@@ -0,0 +1,41 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestOverrideFeatureFlag(t *testing.T) {
+	t.Parallel()
+	flags := ntest.NewMapFeatureFlags(map[string]bool{"fast-path": false})
+	t.Run("override", func(t *testing.T) {
+		ntest.RunTest(t,
+			ntest.FeatureFlagsFixture,
+			ntest.InjectFeatureFlags(flags),
+			ntest.OverrideFeatureFlag("fast-path", true),
+			func(flags ntest.FeatureFlags) {
+				assert.True(t, flags.Enabled("fast-path"))
+			},
+		)
+	})
+	assert.False(t, flags.Enabled("fast-path"), "restored after test")
+}
+
+func TestFeatureFlagMatrix(t *testing.T) {
+	t.Parallel()
+	seen := make(map[string]bool)
+	ntest.RunMatrix(t,
+		ntest.FeatureFlagsFixture,
+		ntest.FeatureFlagMatrix("fast-path"),
+		func(t *testing.T, flags ntest.FeatureFlags) {
+			seen[t.Name()] = flags.Enabled("fast-path")
+		},
+	)
+	assert.Equal(t, map[string]bool{
+		"TestFeatureFlagMatrix/fast-path=on":  true,
+		"TestFeatureFlagMatrix/fast-path=off": false,
+	}, seen)
+}
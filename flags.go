@@ -0,0 +1,112 @@
+package ntest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// option is a setting that can come from an environment variable or,
+// with RegisterFlags, from a command line flag.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	// isBool options can be given as a flag without a value
+	isBool bool
+	apply  func(string) error
+}
+
+// setEnv is for options that are read from the environment when they
+// are used.
+func setEnv(key string) func(string) error {
+	return func(value string) error {
+		return os.Setenv(key, value)
+	}
+}
+
+var options = []option{
+	{flag: "artifacts", env: ArtifactRootEnv, usage: "directory for per-test failure artifacts", apply: setEnv(ArtifactRootEnv)},
+	{flag: "timeout-scale", env: TimeoutScaleEnv, usage: "multiply built-in timeouts by this factor", apply: setEnv(TimeoutScaleEnv)},
+	{flag: "update-snapshots", env: UpdateSnapshotsEnv, usage: "write snapshots instead of comparing against them", isBool: true, apply: setEnv(UpdateSnapshotsEnv)},
+	{flag: "docker", env: DockerEnabledEnv, usage: `set to "false" to skip tests that would start docker containers`, apply: setEnv(DockerEnabledEnv)},
+	{flag: "fixture-mode", env: FixtureModeEnv, usage: `choose between the implementations of plugin fixtures, in order of preference, like "docker,memory"`, apply: setEnv(FixtureModeEnv)},
+	{flag: "seed", env: SeedEnv, usage: "seed for DataGen so that generated data can be repeated", apply: setEnv(SeedEnv)},
+	{flag: "matrix-filter", env: MatrixFilterEnv, usage: "run only the matrix cells whose paths, like mysql/tls, match this regular expression", apply: setMatrixFilter},
+	{flag: "resource-limits", env: ResourceLimitsEnv, usage: "capacity of shared resources for Acquire, like db-connections=20,browsers=4", apply: setResourceLimits},
+	{flag: "run-id", env: RunIDEnv, usage: "identifier for this test run, such as a CI job ID (see TestIdentityContext)", apply: setEnv(RunIDEnv)},
+	{flag: "chain-graph", env: ChainGraphEnv, usage: `write the injection chain of each test to its artifact directory as "dot", "mermaid", or "dot,mermaid"`, apply: enableChainGraph},
+	{flag: "cleanup-hang", env: CleanupHangEnv, usage: `report cleanup functions that run longer than this, like "2m" ("0" to disable)`, apply: setCleanupHangTimeout},
+	{flag: "profile-on-failure", env: ProfileOnFailureEnv, usage: `write these pprof profiles, like "heap,allocs", to the artifact directory of failed tests`, apply: enableProfileOnFailure},
+	{flag: "profile-slow", env: ProfileSlowEnv, usage: `write block and mutex profiles to the artifact directory of tests that take longer than this, like "30s"`, apply: enableProfileSlow},
+	{flag: "cpu-profile", env: CPUProfileEnv, usage: "write a CPU profile to the artifact directory of tests whose names match this regular expression", apply: enableCPUProfile},
+	{flag: "trace", env: TraceEnv, usage: "record an execution trace of tests whose names match this regular expression, kept if they fail or are slow", apply: enableTrace},
+	{flag: "trace-slow", env: TraceSlowEnv, usage: `keep the execution traces of tests that take longer than this, like "10s"`, apply: setTraceSlow},
+	{flag: "bench-repetitions", env: BenchRepetitionsEnv, usage: "run each cell of a benchmark matrix this many times and summarize its metrics", apply: setBenchRepetitions},
+	{flag: "bench-max-variation", env: BenchMaxVariationEnv, usage: "flag benchmark matrix cells whose metrics vary by more than this fraction between repetitions", apply: setBenchMaxVariation},
+	{flag: "junit", env: JUnitEnv, usage: "write a JUnit XML report to this file", apply: enableJUnit},
+	{flag: "tap", env: TAPEnv, usage: `write TAP to this file ("-" for standard output)`, apply: enableTAP},
+	{flag: "events", env: EventsEnv, usage: "write lifecycle events as NDJSON to this file", apply: enableEvents},
+	{flag: "timing", env: TimingEnv, usage: `report the slowest tests and injectors ("-" to print, or a file for JSON)`, apply: enableTiming},
+	{flag: "memory-growth", env: MemoryGrowthEnv, usage: `report tests after which the heap grew by more than this and did not shrink back, like "1M"`, apply: enableMemoryGrowth},
+	{flag: "flakiness", env: FlakinessEnv, usage: "keep pass/fail history in this file and report flaky tests", apply: enableFlakiness},
+	{flag: "otlp", env: OTLPEndpointEnv, usage: "export test spans to this OTLP/HTTP collector URL", apply: enableOTLP},
+	{flag: "log-sink", env: LogSinkEnv, usage: "post the output of failed tests to this URL as NDJSON", apply: enableLogSink},
+	{flag: "otlp-logs", env: OTLPLogsEndpointEnv, usage: "export the output of failed tests to this OTLP/HTTP collector URL", apply: enableOTLPLogs},
+	{flag: "github", env: GitHubEnv, usage: "annotate failures on the pull request diff when running under GitHub Actions", isBool: true, apply: enableGitHub},
+	{flag: "teamcity", env: TeamCityEnv, usage: "write TeamCity service messages for each test when running under TeamCity", isBool: true, apply: enableTeamCity},
+	{flag: "webhook", env: WebhookEnv, usage: "post test failures to this URL", apply: enableWebhook},
+}
+
+func init() {
+	for _, opt := range options {
+		if value := os.Getenv(opt.env); value != "" {
+			if err := opt.apply(value); err != nil {
+				fmt.Fprintf(os.Stderr, "ntest: %s: %s\n", opt.env, err)
+			}
+		}
+	}
+}
+
+// RegisterFlags adds flags for the ntest options to fs (flag.CommandLine
+// if fs is nil) so that they can be discovered with "go test -args -help".
+// Each flag is prefixed with "ntest." and is an alternative to an
+// environment variable, for example -ntest.junit=report.xml instead of
+// NTEST_JUNIT=report.xml. Call it from TestMain before m.Run (or from an
+// init function in a test file):
+//
+//	func TestMain(m *testing.M) {
+//		ntest.RegisterFlags(nil)
+//		os.Exit(ntest.Main(m))
+//	}
+//
+// Options that enable reporters add another reporter if the environment
+// variable was also set.
+func RegisterFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	for _, opt := range options {
+		fs.Var(&optionValue{option: opt}, "ntest."+opt.flag, opt.usage+" (or $"+opt.env+")")
+	}
+}
+
+type optionValue struct {
+	option
+	value string
+}
+
+func (v *optionValue) String() string { return v.value }
+
+func (v *optionValue) Set(value string) error {
+	if v.isBool {
+		if _, err := strconv.ParseBool(value); err != nil {
+			return err
+		}
+	}
+	v.value = value
+	return v.apply(value)
+}
+
+func (v *optionValue) IsBoolFlag() bool { return v.isBool }
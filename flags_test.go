@@ -0,0 +1,63 @@
+package ntest_test
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestRegisterFlags(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, "")
+	t.Setenv(ntest.UpdateSnapshotsEnv, "")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var help bytes.Buffer
+	fs.SetOutput(&help)
+	ntest.RegisterFlags(fs)
+
+	root := filepath.Join(t.TempDir(), "artifacts")
+	require.NoError(t, fs.Parse([]string{"-ntest.artifacts", root, "-ntest.update-snapshots"}))
+	assert.Equal(t, root, os.Getenv(ntest.ArtifactRootEnv))
+	assert.Equal(t, "true", os.Getenv(ntest.UpdateSnapshotsEnv))
+
+	assert.Error(t, fs.Parse([]string{"-ntest.resource-limits", "nonsense"}))
+
+	fs.PrintDefaults()
+	assert.Contains(t, help.String(), "-ntest.junit")
+	assert.Contains(t, help.String(), "$NTEST_JUNIT")
+}
+
+func TestMatrixFilterFlag(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	ntest.RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-ntest.matrix-filter", "^mysql/"}))
+	defer func() { require.NoError(t, fs.Parse([]string{"-ntest.matrix-filter", ""})) }()
+
+	var ran []string
+	var results *ntest.MatrixResults
+	t.Run("matrix", func(t *testing.T) {
+		results = ntest.RunMatrix(t,
+			map[string]nject.Provider{
+				"mysql":    nject.Provide("mysql", func() string { return "mysql" }),
+				"postgres": nject.Provide("postgres", func() string { return "postgres" }),
+			},
+			map[string]nject.Provider{
+				"plain": nject.Provide("plain", func() bool { return false }),
+				"tls":   nject.Provide("tls", func() bool { return true }),
+			},
+			func(info ntest.CellInfo, _ string, _ bool) {
+				ran = append(ran, info.Path)
+			},
+		)
+	})
+	assert.Equal(t, []string{"mysql/plain", "mysql/tls"}, ran)
+	assert.Equal(t, 2, results.Skipped())
+	assert.Contains(t, results.Summary(), "postgres/tls skipped: not selected by NTEST_MATRIX_FILTER")
+}
@@ -0,0 +1,186 @@
+package ntest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FlakinessEnv names the environment variable that, when set to a file
+// path, enables a FlakinessRecorder that keeps its history in that file.
+const FlakinessEnv = "NTEST_FLAKINESS"
+
+// FlakinessWindow is the number of recent runs of each test that are
+// kept in the history.
+var FlakinessWindow = 50
+
+func enableFlakiness(path string) error {
+	recorder, err := NewFlakinessRecorder(FileFlakinessStore(path), os.Stdout)
+	if err != nil {
+		return fmt.Errorf("load flakiness history: %w", err)
+	}
+	AddReporter(recorder)
+	return nil
+}
+
+// FlakinessHistory holds the outcomes ("pass" or "fail") of recent runs
+// of each test, oldest first. Skipped runs are not recorded.
+type FlakinessHistory map[string][]string
+
+// FlakinessStore persists FlakinessHistory between test runs.
+type FlakinessStore interface {
+	Load() (FlakinessHistory, error)
+	Save(FlakinessHistory) error
+}
+
+// FileFlakinessStore is a FlakinessStore that keeps the history as JSON in
+// a local file. A missing file is an empty history.
+type FileFlakinessStore string
+
+var _ FlakinessStore = FileFlakinessStore("")
+
+func (path FileFlakinessStore) Load() (FlakinessHistory, error) {
+	data, err := os.ReadFile(string(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return FlakinessHistory{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history FlakinessHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return history, nil
+}
+
+func (path FileFlakinessStore) Save(history FlakinessHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(string(path), data)
+}
+
+// FlakyTest is a test that has both passed and failed in its recent runs.
+type FlakyTest struct {
+	Name     string
+	Failures int
+	Runs     int
+}
+
+// Rate is the fraction of recent runs that failed.
+func (f FlakyTest) Rate() float64 {
+	return float64(f.Failures) / float64(f.Runs)
+}
+
+// FlakinessRecorder is a Reporter that records the outcome of each test
+// in a FlakinessStore. When a test that has failed before fails again, a
+// note like "this test failed 3 of last 50 runs" is added to its log.
+// When closed, it saves the history and writes a report of flaky tests.
+type FlakinessRecorder struct {
+	store   FlakinessStore
+	w       io.Writer
+	mu      sync.Mutex
+	history FlakinessHistory
+}
+
+var (
+	_ Reporter         = &FlakinessRecorder{}
+	_ FailureAnnotator = &FlakinessRecorder{}
+)
+
+// NewFlakinessRecorder loads the history from store. The flaky test report
+// is written to w when the recorder is closed; w may be nil.
+func NewFlakinessRecorder(store FlakinessStore, w io.Writer) (*FlakinessRecorder, error) {
+	history, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	if history == nil {
+		history = FlakinessHistory{}
+	}
+	return &FlakinessRecorder{
+		store:   store,
+		w:       w,
+		history: history,
+	}, nil
+}
+
+func (r *FlakinessRecorder) TestStarted(string, time.Time) {}
+
+func (r *FlakinessRecorder) TestFinished(result TestResult) {
+	if result.Skipped && !result.Failed {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := append(r.history[result.Name], outcome(result.Failed, false))
+	if over := len(runs) - FlakinessWindow; over > 0 {
+		runs = runs[over:]
+	}
+	r.history[result.Name] = runs
+}
+
+// AnnotateFailure describes the failure history of a test before this run.
+func (r *FlakinessRecorder) AnnotateFailure(name string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := r.history[name]
+	var failures int
+	for _, o := range runs {
+		if o == "fail" {
+			failures++
+		}
+	}
+	if failures == 0 {
+		return ""
+	}
+	return fmt.Sprintf("this test failed %d of last %d runs", failures, len(runs))
+}
+
+// Flaky returns the tests that have both passed and failed in the
+// history, most frequently failing first.
+func (r *FlakinessRecorder) Flaky() []FlakyTest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var flaky []FlakyTest
+	for name, runs := range r.history {
+		var failures int
+		for _, o := range runs {
+			if o == "fail" {
+				failures++
+			}
+		}
+		if failures != 0 && failures != len(runs) {
+			flaky = append(flaky, FlakyTest{Name: name, Failures: failures, Runs: len(runs)})
+		}
+	}
+	sort.Slice(flaky, func(i, j int) bool {
+		if flaky[i].Rate() != flaky[j].Rate() {
+			return flaky[i].Rate() > flaky[j].Rate()
+		}
+		return flaky[i].Name < flaky[j].Name
+	})
+	return flaky
+}
+
+func (r *FlakinessRecorder) Close() error {
+	if r.w != nil {
+		if flaky := r.Flaky(); len(flaky) != 0 {
+			fmt.Fprintln(r.w, "Flaky tests:")
+			for _, f := range flaky {
+				fmt.Fprintf(r.w, "  %3.0f%%  %d of %d runs failed  %s\n", 100*f.Rate(), f.Failures, f.Runs, f.Name)
+			}
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.store.Save(r.history)
+}
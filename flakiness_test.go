@@ -0,0 +1,37 @@
+package ntest_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestFlakinessRecorder(t *testing.T) {
+	t.Parallel()
+	store := ntest.FileFlakinessStore(filepath.Join(t.TempDir(), "history.json"))
+	var buf bytes.Buffer
+	recorder, err := ntest.NewFlakinessRecorder(store, &buf)
+	require.NoError(t, err)
+	for _, failed := range []bool{false, true, false} {
+		recorder.TestFinished(ntest.TestResult{Name: "TestFlaky", Failed: failed})
+	}
+	recorder.TestFinished(ntest.TestResult{Name: "TestSolid"})
+	recorder.TestFinished(ntest.TestResult{Name: "TestSkipped", Skipped: true})
+	assert.Equal(t, "this test failed 1 of last 3 runs", recorder.AnnotateFailure("TestFlaky"))
+	assert.Equal(t, "", recorder.AnnotateFailure("TestSolid"))
+	require.NoError(t, recorder.Close())
+	assert.Contains(t, buf.String(), "1 of 3 runs failed  TestFlaky")
+
+	reloaded, err := ntest.NewFlakinessRecorder(store, nil)
+	require.NoError(t, err)
+	reloaded.TestFinished(ntest.TestResult{Name: "TestFlaky", Failed: true})
+	flaky := reloaded.Flaky()
+	require.Equal(t, 1, len(flaky))
+	assert.Equal(t, ntest.FlakyTest{Name: "TestFlaky", Failures: 2, Runs: 4}, flaky[0])
+	assert.Equal(t, 0.5, flaky[0].Rate())
+}
@@ -0,0 +1,95 @@
+package ntest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitHubEnv names the environment variable that, when set to a true
+// value, enables a GitHubReporter writing to standard output. It is
+// meant to be set in a GitHub Actions workflow; ntest does not turn it
+// on just because it is running under GitHub Actions.
+const GitHubEnv = "NTEST_GITHUB"
+
+func enableGitHub(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		AddReporter(NewGitHubReporter(os.Stdout))
+	}
+	return nil
+}
+
+// GitHubReporter is a Reporter that writes GitHub Actions workflow
+// commands so that failures show up as annotations on the pull request
+// diff. Set NTEST_GITHUB=true in the workflow to enable it.
+//
+// Only failures reported through the T that RunTest injects have a
+// location; others are annotated without a file.
+type GitHubReporter struct {
+	w         io.Writer
+	workspace string
+	mu        sync.Mutex
+}
+
+var _ Reporter = &GitHubReporter{}
+
+// NewGitHubReporter creates a GitHubReporter that writes to w. File names
+// are made relative to $GITHUB_WORKSPACE.
+func NewGitHubReporter(w io.Writer) *GitHubReporter {
+	return &GitHubReporter{
+		w:         w,
+		workspace: os.Getenv("GITHUB_WORKSPACE"),
+	}
+}
+
+func (r *GitHubReporter) TestStarted(string, time.Time) {}
+
+func (r *GitHubReporter) TestFinished(result TestResult) {
+	if !result.Failed {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	title := githubEscapeProperty(result.Name)
+	if len(result.Failures) == 0 {
+		fmt.Fprintf(r.w, "::error title=%s::%s\n", title, githubEscapeData(result.Name+" failed"))
+		return
+	}
+	for _, failure := range result.Failures {
+		var location string
+		if failure.File != "" {
+			location = fmt.Sprintf("file=%s,line=%d,", githubEscapeProperty(r.relative(failure.File)), failure.Line)
+		}
+		fmt.Fprintf(r.w, "::error %stitle=%s::%s\n", location, title, githubEscapeData(failure.Message))
+	}
+}
+
+func (r *GitHubReporter) Close() error { return nil }
+
+func (r *GitHubReporter) relative(file string) string {
+	if r.workspace == "" {
+		return file
+	}
+	rel, err := filepath.Rel(r.workspace, file)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return file
+	}
+	return filepath.ToSlash(rel)
+}
+
+func githubEscapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func githubEscapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,68 @@
+package ntest_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestGitHubReporter(t *testing.T) {
+	var buf bytes.Buffer
+	t.Setenv("GITHUB_WORKSPACE", "/work")
+	reporter := ntest.NewGitHubReporter(&buf)
+	reporter.TestFinished(ntest.TestResult{Name: "TestPass"})
+	reporter.TestFinished(ntest.TestResult{
+		Name:   "TestA/x=1,y=2",
+		Failed: true,
+		Failures: []ntest.TestFailure{
+			{Message: "want 1\ngot 50%", File: "/work/pkg/a_test.go", Line: 12},
+		},
+	})
+	reporter.TestFinished(ntest.TestResult{Name: "TestB", Failed: true})
+	assert.Equal(t, "::error file=pkg/a_test.go,line=12,title=TestA/x=1%2Cy=2::want 1%0Agot 50%25\n"+
+		"::error title=TestB::TestB failed\n", buf.String())
+}
+
+func TestGitHubReporterOptIn(t *testing.T) {
+	if failInChild(t) {
+		return
+	}
+	out := runInChild(t, "^TestGitHubReporterOptIn$", "GITHUB_ACTIONS=true", "NTEST_GITHUB=")
+	assert.NotContains(t, out, "::error", "not enabled by GITHUB_ACTIONS alone")
+	assert.Contains(t, out, "--- FAIL: TestGitHubReporterOptIn")
+	out = runInChild(t, "^TestGitHubReporterOptIn$", "GITHUB_ACTIONS=true", "NTEST_GITHUB=true")
+	assert.Contains(t, out, "::error file=")
+	assert.Contains(t, out, "title=TestGitHubReporterOptIn::failed in child")
+}
+
+// failInChild, in a test binary started by runInChild, fails a test
+// through RunTest and returns true.
+func failInChild(t *testing.T) bool {
+	if os.Getenv("NTEST_TEST_CHILD") == "" {
+		return false
+	}
+	ntest.RunTest(t, func(t ntest.T) {
+		t.Error("failed in child")
+	})
+	return true
+}
+
+// runInChild runs the tests matching pattern in another copy of the test
+// binary, with env added to its environment, and returns its output.
+// The tests are expected to fail.
+func runInChild(t *testing.T, pattern string, env ...string) string {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run="+pattern, "-test.count=1", "-test.v")
+	cmd.Env = append(append(os.Environ(), "NTEST_TEST_CHILD=true"), env...)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if !assert.ErrorAs(t, err, &exitErr, "%s", out) {
+		t.FailNow()
+	}
+	return string(out)
+}
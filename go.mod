@@ -3,8 +3,11 @@ module github.com/memsql/ntest
 go 1.18
 
 require (
+	github.com/google/go-cmp v0.6.0
 	github.com/muir/nject v1.8.0
+	github.com/pmezard/go-difflib v1.0.0
 	github.com/stretchr/testify v1.10.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -12,6 +15,4 @@ require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/muir/reflectutils v0.7.0 // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
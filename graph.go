@@ -0,0 +1,166 @@
+package ntest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// ChainGraphEnv names the environment variable that makes RunTest write
+// a graph of the injection chain of each test to its ArtifactDir. Set it
+// to "dot" (chain.dot, for Graphviz), "mermaid" (chain.mmd), or
+// "dot,mermaid".
+const ChainGraphEnv = "NTEST_CHAIN_GRAPH"
+
+var chainGraph struct {
+	mu      sync.Mutex
+	formats []string
+}
+
+func enableChainGraph(value string) error {
+	var formats []string
+	for _, format := range strings.Split(value, ",") {
+		switch format = strings.TrimSpace(format); format {
+		case "":
+		case "dot", "mermaid":
+			formats = append(formats, format)
+		default:
+			return fmt.Errorf("unknown chain graph format %q (want dot or mermaid)", format)
+		}
+	}
+	chainGraph.mu.Lock()
+	defer chainGraph.mu.Unlock()
+	chainGraph.formats = formats
+	return nil
+}
+
+func chainGraphFormats() []string {
+	chainGraph.mu.Lock()
+	defer chainGraph.mu.Unlock()
+	return chainGraph.formats
+}
+
+const chainGraphName = "ntest-chain-graph"
+
+// chainGrapher returns an injector that, when added to chain, writes a
+// graph of the providers of chain that nject included. Providers are
+// nodes and there is an edge, labeled with the type, from each provider
+// to the providers that consume what it provides.
+func chainGrapher(t T, formats []string, chain *nject.Collection) nject.Provider {
+	return nject.Required(nject.Provide(chainGraphName, func(d *nject.Debugging) {
+		graph := newProviderGraph(chain, d.NamesIncluded)
+		// Fatalf would abort the test for the sake of a diagnostic
+		dir, err := artifactDir(t)
+		if err != nil {
+			t.Logf("chain graph: %s", err)
+			return
+		}
+		for _, format := range formats {
+			var path, text string
+			switch format {
+			case "dot":
+				path, text = filepath.Join(dir, "chain.dot"), graph.dot(t.Name())
+			case "mermaid":
+				path, text = filepath.Join(dir, "chain.mmd"), graph.mermaid()
+			}
+			if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+				t.Logf("chain graph: %s", err)
+			}
+		}
+	}))
+}
+
+type graphNode struct {
+	name     string
+	function string
+}
+
+type graphEdge struct {
+	from, to int
+	typ      string
+}
+
+type providerGraph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+// newProviderGraph builds the graph of the providers in chain that are
+// in included. Each input of a provider comes from the closest provider
+// before it with that output. Inputs that nject supplies itself, like
+// *nject.Debugging, have no edge.
+func newProviderGraph(chain *nject.Collection, included []string) *providerGraph {
+	isIncluded := make(map[string]bool, len(included))
+	for _, name := range included {
+		isIncluded[name] = true
+	}
+	g := &providerGraph{}
+	producers := make(map[reflect.Type]int)
+	chain.ForEachProvider(func(p nject.Provider) {
+		name, function := splitProviderString(p.String())
+		if name == chainGraphName || !isIncluded[name] {
+			return
+		}
+		index := len(g.nodes)
+		g.nodes = append(g.nodes, graphNode{name: name, function: function})
+		inputs, outputs := p.DownFlows()
+		for _, in := range inputs {
+			if from, ok := producers[in]; ok {
+				g.edges = append(g.edges, graphEdge{from: from, to: index, typ: in.String()})
+			}
+		}
+		for _, out := range outputs {
+			producers[out] = index
+		}
+	})
+	return g
+}
+
+// splitProviderString splits the result of nject.Provider.String(), like
+// "user-chain(3) [func(ntest.T) *sql.DB]", into its name and type.
+func splitProviderString(s string) (name string, function string) {
+	i := strings.Index(s, " [")
+	if i == -1 || !strings.HasSuffix(s, "]") {
+		return s, ""
+	}
+	return s[:i], s[i+2 : len(s)-1]
+}
+
+func (g *providerGraph) dot(title string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(title))
+	b.WriteString("\tnode [shape=box];\n")
+	for i, node := range g.nodes {
+		fmt.Fprintf(&b, "\tn%d [label=%s];\n", i, dotQuote(node.name+"\n"+node.function))
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "\tn%d -> n%d [label=%s];\n", edge.from, edge.to, dotQuote(edge.typ))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func (g *providerGraph) mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for i, node := range g.nodes {
+		fmt.Fprintf(&b, "\tn%d[\"%s<br/>%s\"]\n", i, mermaidEscape(node.name), mermaidEscape(node.function))
+	}
+	for _, edge := range g.edges {
+		fmt.Fprintf(&b, "\tn%d -->|\"%s\"| n%d\n", edge.from, mermaidEscape(edge.typ), edge.to)
+	}
+	return b.String()
+}
+
+func mermaidEscape(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;").Replace(s)
+}
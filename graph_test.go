@@ -0,0 +1,51 @@
+package ntest_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type graphDB string
+
+type graphUnused int
+
+func TestChainGraph(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	ntest.RegisterFlags(fs)
+	require.NoError(t, fs.Set("ntest.chain-graph", "dot,mermaid"))
+	defer func() { require.NoError(t, fs.Set("ntest.chain-graph", "")) }()
+	assert.Error(t, fs.Set("ntest.chain-graph", "png"))
+
+	ntest.RunTest(t,
+		nject.Provide("database", func(t ntest.T) graphDB { return graphDB(t.Name()) }),
+		nject.Provide("unused", func() graphUnused { return 7 }),
+		func(db graphDB) {
+			assert.Equal(t, graphDB(t.Name()), db)
+		},
+	)
+
+	dir := ntest.ArtifactDir(t)
+	dot, err := os.ReadFile(filepath.Join(dir, "chain.dot"))
+	require.NoError(t, err)
+	t.Log(string(dot))
+	assert.Contains(t, string(dot), `[label="database\nfunc(ntest.T) ntest_test.graphDB"]`)
+	assert.Contains(t, string(dot), `[label="ntest_test.graphDB"]`)
+	assert.Contains(t, string(dot), `[label="ntest.T"]`)
+	assert.NotContains(t, string(dot), "unused")
+
+	mermaid, err := os.ReadFile(filepath.Join(dir, "chain.mmd"))
+	require.NoError(t, err)
+	t.Log(string(mermaid))
+	assert.Contains(t, string(mermaid), "flowchart TD")
+	assert.Contains(t, string(mermaid), `-->|"ntest_test.graphDB"|`)
+	assert.NotContains(t, string(mermaid), "unused")
+}
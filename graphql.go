@@ -0,0 +1,137 @@
+package ntest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/muir/nject"
+)
+
+// GraphQLHandler is the handler for a GraphQL endpoint. Inject one
+// before GraphQLServerFixture.
+type GraphQLHandler http.Handler
+
+// GraphQLClient sends GraphQL requests to a server and logs each request
+// and response to the test.
+type GraphQLClient struct {
+	URL    string
+	Client *http.Client
+	// Header is added to every request.
+	Header http.Header
+	// MaxLogBody limits how much of each response body is logged.
+	MaxLogBody int
+	t          T
+}
+
+// GraphQLError is a single error as returned in a GraphQL response.
+type GraphQLError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// GraphQLErrors is returned by GraphQLClient.Do when the response
+// contains errors.
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Message
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+// GraphQLServerFixture mounts the injected GraphQLHandler on an
+// httptest.Server at /graphql and provides a *GraphQLClient for it. The
+// server is closed when the test finishes.
+var GraphQLServerFixture = nject.Provide("graphql-server", func(t T, handler GraphQLHandler) *GraphQLClient {
+	mux := http.NewServeMux()
+	mux.Handle("/graphql", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return NewGraphQLClient(t, server.URL+"/graphql")
+})
+
+// NewGraphQLClient creates a client for a GraphQL endpoint that logs
+// to t.
+func NewGraphQLClient(t T, url string) *GraphQLClient {
+	return &GraphQLClient{
+		URL:        url,
+		Client:     http.DefaultClient,
+		Header:     make(http.Header),
+		MaxLogBody: 4096,
+		t:          t,
+	}
+}
+
+// Do sends a query with variables and decodes the "data" of the response
+// into result (which may be nil). If the response has errors, they are
+// returned as GraphQLErrors.
+func (c *GraphQLClient) Do(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": variables,
+	})
+	if err != nil {
+		return fmt.Errorf("encode graphql request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	c.t.Logf("graphql request: %s", body)
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	logged := respBody
+	if c.MaxLogBody > 0 && len(logged) > c.MaxLogBody {
+		logged = append(logged[:c.MaxLogBody:c.MaxLogBody], "..."...)
+	}
+	c.t.Logf("graphql response (%d): %s", resp.StatusCode, logged)
+	var decoded struct {
+		Data   json.RawMessage `json:"data"`
+		Errors GraphQLErrors   `json:"errors"`
+	}
+	if err := json.Unmarshal(respBody, &decoded); err != nil {
+		return fmt.Errorf("decode graphql response (status %d): %w", resp.StatusCode, err)
+	}
+	if len(decoded.Errors) != 0 {
+		return decoded.Errors
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("graphql response status %d", resp.StatusCode)
+	}
+	if result != nil && len(decoded.Data) != 0 {
+		if err := json.Unmarshal(decoded.Data, result); err != nil {
+			return fmt.Errorf("decode graphql data: %w", err)
+		}
+	}
+	return nil
+}
+
+// GraphQLQuery sends a query and returns the data decoded as R. The test
+// fails immediately if there is any error.
+func GraphQLQuery[R any](t T, c *GraphQLClient, query string, variables map[string]interface{}) R {
+	t.Helper()
+	var result R
+	if err := c.Do(context.Background(), query, variables, &result); err != nil {
+		t.Fatalf("graphql query failed: %s", err)
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package ntest_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestGraphQLServer(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t,
+		func() ntest.GraphQLHandler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Query     string                 `json:"query"`
+					Variables map[string]interface{} `json:"variables"`
+				}
+				_ = json.NewDecoder(r.Body).Decode(&req)
+				if req.Query == "bad" {
+					_, _ = w.Write([]byte(`{"errors":[{"message":"syntax error"}]}`))
+					return
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"data": map[string]interface{}{
+						"user": map[string]interface{}{"id": req.Variables["id"], "name": "Ada"},
+					},
+				})
+			})
+		},
+		ntest.GraphQLServerFixture,
+		func(t *testing.T, client *ntest.GraphQLClient) {
+			type userResult struct {
+				User struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"user"`
+			}
+			result := ntest.GraphQLQuery[userResult](t, client,
+				`query($id: ID!) { user(id: $id) { id name } }`,
+				map[string]interface{}{"id": "u1"})
+			assert.Equal(t, "u1", result.User.ID)
+			assert.Equal(t, "Ada", result.User.Name)
+
+			err := client.Do(context.Background(), "bad", nil, nil)
+			var gqlErrors ntest.GraphQLErrors
+			require.ErrorAs(t, err, &gqlErrors)
+			assert.Equal(t, "syntax error", gqlErrors[0].Message)
+		},
+	)
+}
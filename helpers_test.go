@@ -0,0 +1,215 @@
+package ntest_test
+
+// Fake implementations of ntest.T that are shared by the tests.
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/memsql/ntest"
+)
+
+// cleanupT runs cleanup functions when runCleanups is called rather
+// than when the test finishes.
+type cleanupT struct {
+	ntest.T
+	mu       sync.Mutex
+	cleanups []func()
+}
+
+func (t *cleanupT) Cleanup(f func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *cleanupT) runCleanups() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// errorCapturingT records Errorf messages instead of failing the test.
+type errorCapturingT struct {
+	ntest.T
+	errors []string
+}
+
+func (t *errorCapturingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+// failNowCapturingT also records Error messages, and its FailNow stops
+// like Fatalf on fatalCapturingT (see catchFatal).
+type failNowCapturingT struct {
+	errorCapturingT
+}
+
+func (t *failNowCapturingT) Error(args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprint(args...))
+}
+
+func (t *failNowCapturingT) FailNow() {
+	panic(fatalCalled{})
+}
+
+// fatalCapturingT records Fatalf messages and then stops the function
+// run by catchFatal.
+type fatalCapturingT struct {
+	ntest.T
+	fatals []string
+}
+
+type fatalCalled struct{}
+
+func (t *fatalCapturingT) Fatalf(format string, args ...interface{}) {
+	t.fatals = append(t.fatals, fmt.Sprintf(format, args...))
+	panic(fatalCalled{})
+}
+
+// catchFatal runs f and reports whether it called Fatalf
+func catchFatal(f func()) (fataled bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(fatalCalled); !ok {
+				panic(r)
+			}
+			fataled = true
+		}
+	}()
+	f()
+	return false
+}
+
+// testingFatalT is a *testing.T (so it has Parallel) whose Fatalf is
+// captured like fatalCapturingT.
+type testingFatalT struct {
+	*testing.T
+	fatals []string
+}
+
+func (t *testingFatalT) Fatalf(format string, args ...interface{}) {
+	t.fatals = append(t.fatals, fmt.Sprintf(format, args...))
+	panic(fatalCalled{})
+}
+
+// failingT records failures instead of failing the test. It may fail,
+// and be checked for failure, from other goroutines.
+type failingT struct {
+	*cleanupT
+	errorsMu sync.Mutex
+	errors   []string
+}
+
+func (t *failingT) Errorf(format string, args ...interface{}) {
+	t.errorsMu.Lock()
+	defer t.errorsMu.Unlock()
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *failingT) Failed() bool {
+	t.errorsMu.Lock()
+	defer t.errorsMu.Unlock()
+	return len(t.errors) != 0
+}
+
+// failedT reports that the test has failed.
+type failedT struct {
+	*cleanupT
+}
+
+func (failedT) Failed() bool { return true }
+
+// logCapturingT records Logf output.
+type logCapturingT struct {
+	ntest.T
+	logs []string
+}
+
+func (t *logCapturingT) Logf(format string, args ...interface{}) {
+	t.logs = append(t.logs, fmt.Sprintf(format, args...))
+}
+
+func (t *logCapturingT) warnings() []string {
+	var warnings []string
+	for _, line := range t.logs {
+		if strings.HasPrefix(line, "ntest warning:") {
+			warnings = append(warnings, line)
+		}
+	}
+	return warnings
+}
+
+// attrCapturingT records Attr calls.
+type attrCapturingT struct {
+	ntest.T
+	attrs map[string]string
+}
+
+func (t *attrCapturingT) Attr(key, value string) {
+	t.attrs[key] = value
+}
+
+// deadlineT has a deadline that is about to pass and records log lines.
+type deadlineT struct {
+	ntest.T
+	deadline time.Time
+	mu       sync.Mutex
+	logs     []string
+}
+
+func (t *deadlineT) Deadline() (time.Time, bool) { return t.deadline, true }
+
+func (t *deadlineT) Logf(format string, args ...interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.logs = append(t.logs, format)
+}
+
+// envT is a T, other than *testing.T, that allows Setenv in tests
+// that run at the same time.
+type envT struct {
+	ntest.T
+	name     string
+	errors   []string
+	cleanups []func()
+}
+
+func (t *envT) Name() string { return t.name }
+
+func (t *envT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *envT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func (t *envT) Setenv(key, value string) {
+	previous, ok := os.LookupEnv(key)
+	_ = os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	})
+}
+
+func (t *envT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+// bareSetenvT is a T whose Setenv does not restore the environment.
+type bareSetenvT struct {
+	ntest.T
+}
+
+func (t bareSetenvT) Setenv(key, value string) { _ = os.Setenv(key, value) }
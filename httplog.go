@@ -0,0 +1,254 @@
+package ntest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// HTTPLog logs HTTP requests to a test with LogKV: method, URL, status,
+// and duration, and optionally the start of the request and response
+// bodies. Use Transport for clients and Middleware for servers.
+type HTTPLog struct {
+	T T
+	// MaxBody limits how much of each body is logged. Bodies are not
+	// logged if it is 0.
+	MaxBody int
+	// Redact, if set, is applied to URLs and bodies before they are
+	// logged, for example to remove tokens.
+	Redact func(string) string
+	// Chaos, if set, injects faults into requests.
+	Chaos *ChaosPolicy
+	// Transcript, if positive, is how many of the most recent client
+	// round trips are kept in memory, with their headers and bodies, to
+	// be written as a HAR file, http.har, to the ArtifactDir of the test
+	// if it fails. Passing tests log nothing more.
+	Transcript int
+	// TranscriptBody limits how much of each body is kept in the
+	// transcript. The default is 64KiB.
+	TranscriptBody int
+	mu             sync.Mutex
+	transcript     httpTranscript
+}
+
+// HTTPLogFixture provides an *HTTPLog that logs to the test without
+// bodies. Set MaxBody to include them.
+var HTTPLogFixture = nject.Provide("http-log", func(t T) *HTTPLog {
+	return &HTTPLog{T: t}
+})
+
+// Client returns an *http.Client that logs its requests.
+func (l *HTTPLog) Client() *http.Client {
+	return &http.Client{Transport: l.Transport(nil)}
+}
+
+// Transport wraps base (http.DefaultTransport if nil) so that each
+// round trip is logged.
+func (l *HTTPLog) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return httpLogTransport{log: l, base: base}
+}
+
+type httpLogTransport struct {
+	log  *HTTPLog
+	base http.RoundTripper
+}
+
+func (rt httpLogTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	peek := rt.log.MaxBody
+	if rt.log.Transcript > 0 && rt.log.transcriptBody() > peek {
+		peek = rt.log.transcriptBody()
+	}
+	var reqBody []byte
+	if peek > 0 && req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		reqBody, req.Body = peekBody(req.Body, peek)
+	}
+	pairs := []interface{}{
+		"method", req.Method,
+		"url", rt.log.redact(req.URL.String()),
+	}
+	action, err := rt.log.Chaos.decide(req.Context())
+	if action != chaosNone {
+		pairs = append(pairs, "chaos", action)
+	}
+	var resp *http.Response
+	switch {
+	case err != nil:
+	case action == chaosError:
+		err = ErrChaos
+	default:
+		resp, err = rt.base.RoundTrip(req)
+		if err == nil && action == chaosDrop {
+			_ = resp.Body.Close()
+			resp, err = nil, ErrChaosDrop
+		}
+	}
+	if err != nil {
+		if req.Body != nil {
+			_ = req.Body.Close()
+		}
+		pairs = append(pairs, "error", err, "elapsed", time.Since(start))
+		pairs = rt.log.appendBody(pairs, "request_body", reqBody, req.ContentLength)
+		LogKV(rt.log.T, "http client", pairs...)
+		if rt.log.Transcript > 0 {
+			rt.log.record(start, req, reqBody, nil, nil, err)
+		}
+		return nil, err
+	}
+	pairs = append(pairs, "status", resp.StatusCode, "elapsed", time.Since(start))
+	pairs = rt.log.appendBody(pairs, "request_body", reqBody, req.ContentLength)
+	var respBody []byte
+	if peek > 0 && resp.Body != nil && resp.Body != http.NoBody {
+		respBody, resp.Body = peekBody(resp.Body, peek)
+		pairs = rt.log.appendBody(pairs, "response_body", respBody, resp.ContentLength)
+	}
+	LogKV(rt.log.T, "http client", pairs...)
+	if rt.log.Transcript > 0 {
+		rt.log.record(start, req, reqBody, resp, respBody, nil)
+	}
+	return resp, nil
+}
+
+// Middleware wraps a server handler so that each request it serves is
+// logged.
+func (l *HTTPLog) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		var reqBody []byte
+		if l.MaxBody > 0 && r.Body != nil && r.Body != http.NoBody {
+			reqBody, r.Body = peekBody(r.Body, l.MaxBody)
+		}
+		lw := &httpLogWriter{ResponseWriter: w, max: l.MaxBody}
+		action, err := l.Chaos.decide(r.Context())
+		switch {
+		case err != nil:
+			return
+		case action == chaosError:
+			http.Error(lw, ErrChaos.Error(), http.StatusServiceUnavailable)
+		case action == chaosDrop:
+			if dropConnection(w) {
+				LogKV(l.T, "http server", "method", r.Method, "url", l.redact(r.URL.String()), "chaos", action)
+				return
+			}
+			http.Error(lw, ErrChaosDrop.Error(), http.StatusServiceUnavailable)
+		default:
+			next.ServeHTTP(lw, r)
+		}
+		if lw.status == 0 {
+			lw.status = http.StatusOK
+		}
+		pairs := []interface{}{
+			"method", r.Method,
+			"url", l.redact(r.URL.String()),
+			"status", lw.status,
+			"elapsed", time.Since(start),
+		}
+		if action != chaosNone {
+			pairs = append(pairs, "chaos", action)
+		}
+		pairs = l.appendBody(pairs, "request_body", reqBody, r.ContentLength)
+		pairs = l.appendBody(pairs, "response_body", lw.body.Bytes(), lw.size)
+		LogKV(l.T, "http server", pairs...)
+	})
+}
+
+// dropConnection closes the connection without a response, if w
+// allows that.
+func dropConnection(w http.ResponseWriter) bool {
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return false
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func (l *HTTPLog) redact(s string) string {
+	if l.Redact == nil {
+		return s
+	}
+	return l.Redact(s)
+}
+
+// appendBody adds the logged part of a body to pairs. size is the full
+// size of the body if it is known.
+func (l *HTTPLog) appendBody(pairs []interface{}, key string, body []byte, size int64) []interface{} {
+	if l.MaxBody <= 0 || len(body) == 0 {
+		return pairs
+	}
+	truncated := len(body) > l.MaxBody
+	if truncated {
+		body = body[:l.MaxBody]
+	}
+	text := l.redact(string(body))
+	if truncated {
+		text += "..."
+		if size > 0 {
+			text += " (" + strconv.FormatInt(size, 10) + " bytes)"
+		}
+	}
+	return append(pairs, key, text)
+}
+
+// peekBody reads up to max+1 bytes of body (one more so that truncation
+// can be detected) and returns them along with a body that still
+// produces everything.
+func peekBody(body io.ReadCloser, max int) ([]byte, io.ReadCloser) {
+	prefix, _ := io.ReadAll(io.LimitReader(body, int64(max)+1))
+	return prefix, struct {
+		io.Reader
+		io.Closer
+	}{
+		Reader: io.MultiReader(bytes.NewReader(prefix), body),
+		Closer: body,
+	}
+}
+
+type httpLogWriter struct {
+	http.ResponseWriter
+	status int
+	size   int64
+	max    int
+	body   bytes.Buffer
+}
+
+func (w *httpLogWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *httpLogWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if room := w.max + 1 - w.body.Len(); w.max > 0 && room > 0 {
+		if room > len(b) {
+			room = len(b)
+		}
+		w.body.Write(b[:room])
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *httpLogWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
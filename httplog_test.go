@@ -0,0 +1,129 @@
+package ntest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestHTTPLog(t *testing.T) {
+	var mu sync.Mutex
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		mu.Lock()
+		defer mu.Unlock()
+		caught = append(caught, strings.TrimSpace(s))
+	})
+	ntest.RunTest(captureT, ntest.HTTPLogFixture, func(log *ntest.HTTPLog) {
+		log.MaxBody = 5
+		log.Redact = func(s string) string { return strings.ReplaceAll(s, "secret", "REDACTED") }
+		server := httptest.NewServer(log.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, err := io.ReadAll(r.Body)
+			assert.NoError(t, err)
+			assert.Equal(t, "request body", string(body))
+			w.WriteHeader(http.StatusTeapot)
+			_, _ = w.Write([]byte("response body"))
+		})))
+		defer server.Close()
+
+		resp, err := log.Client().Post(server.URL+"/brew?token=secret", "text/plain", strings.NewReader("request body"))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "response body", string(body))
+		assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+	})
+
+	require.Len(t, caught, 2)
+	assert.Regexp(t, `^http server method=POST url="/brew\?token=REDACTED" status=418 elapsed=\S+ request_body="reque\.\.\. \(12 bytes\)" response_body="respo\.\.\. \(13 bytes\)"$`, caught[0])
+	assert.Regexp(t, `^http client method=POST url="http://\S+/brew\?token=REDACTED" status=418 elapsed=\S+ request_body="reque\.\.\. \(12 bytes\)" response_body="respo\.\.\. \(13 bytes\)"$`, caught[1])
+}
+
+func TestHTTPLogTranscript(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte("reply to " + r.URL.Path))
+	}))
+	defer server.Close()
+
+	run := func(t ntest.T) {
+		ntest.RunTest(t, ntest.HTTPLogFixture, func(log *ntest.HTTPLog) {
+			log.Transcript = 2
+			log.TranscriptBody = 10
+			log.Redact = func(s string) string { return strings.ReplaceAll(s, "secret", "REDACTED") }
+			client := log.Client()
+			for _, path := range []string{"/first", "/second", "/third"} {
+				req, err := http.NewRequest(http.MethodPut, server.URL+path, strings.NewReader("secret payload"))
+				require.NoError(t, err)
+				req.Header.Set("Authorization", "Bearer secret")
+				resp, err := client.Do(req)
+				require.NoError(t, err)
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				_ = resp.Body.Close()
+				assert.Equal(t, "reply to "+path, string(body))
+			}
+		})
+	}
+
+	var logged []string
+	ct := &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		logged = append(logged, s)
+	})}
+	run(failedT{ct})
+	ct.runCleanups()
+	path := filepath.Join(root, "TestHTTPLogTranscript", "http.har")
+	assert.Contains(t, logged, "HTTP transcript of 2 requests written to "+path)
+	enc, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var har struct {
+		Log struct {
+			Comment string
+			Entries []struct {
+				Request struct {
+					Method   string
+					URL      string
+					Headers  []struct{ Name, Value string }
+					PostData struct{ Text string }
+				}
+				Response struct {
+					Status  int
+					Content struct{ Text, Comment string }
+				}
+			}
+		}
+	}
+	require.NoError(t, json.Unmarshal(enc, &har))
+	assert.Equal(t, "1 earlier requests were dropped", har.Log.Comment)
+	require.Len(t, har.Log.Entries, 2)
+	entry := har.Log.Entries[0]
+	assert.Equal(t, http.MethodPut, entry.Request.Method)
+	assert.Equal(t, server.URL+"/second", entry.Request.URL)
+	assert.Contains(t, entry.Request.Headers, struct{ Name, Value string }{"Authorization", "Bearer REDACTED"})
+	assert.Equal(t, "REDACTED pay", entry.Request.PostData.Text)
+	assert.Equal(t, http.StatusOK, entry.Response.Status)
+	assert.Equal(t, "reply to /", entry.Response.Content.Text)
+	assert.Equal(t, "truncated to 10 bytes", entry.Response.Content.Comment)
+
+	require.NoError(t, os.Remove(path))
+	ct = &cleanupT{T: t}
+	run(ct)
+	ct.runCleanups()
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "not written for tests that pass")
+}
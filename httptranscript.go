@@ -0,0 +1,202 @@
+package ntest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// defaultTranscriptBody is the TranscriptBody of an HTTPLog that does
+// not set it.
+const defaultTranscriptBody = 64 << 10
+
+// httpTranscript is the client round trips that an HTTPLog keeps for
+// when the test fails.
+type httpTranscript struct {
+	entries []harEntry
+	dropped int
+	watched bool
+}
+
+// The subset of HAR 1.2 (http://www.softwareishard.com/blog/har-12-spec/)
+// that a transcript fills in. Fields starting with an underscore are
+// custom, as the format allows.
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+	Comment string     `json:"comment,omitempty"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // milliseconds
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	BodySize    int64       `json:"bodySize"`
+	PostData    *harContent `json:"postData,omitempty"`
+}
+
+type harResponse struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Headers     []harHeader `json:"headers"`
+	BodySize    int64       `json:"bodySize"`
+	Content     harContent  `json:"content"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+func (l *HTTPLog) transcriptBody() int {
+	if l.TranscriptBody > 0 {
+		return l.TranscriptBody
+	}
+	return defaultTranscriptBody
+}
+
+// record adds a round trip to the transcript. resp is nil if the round
+// trip failed.
+func (l *HTTPLog) record(start time.Time, req *http.Request, reqBody []byte, resp *http.Response, respBody []byte, err error) {
+	entry := harEntry{
+		StartedDateTime: start,
+		Time:            float64(time.Since(start).Microseconds()) / 1000,
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         l.redact(req.URL.String()),
+			HTTPVersion: req.Proto,
+			Headers:     l.harHeaders(req.Header),
+			BodySize:    req.ContentLength,
+		},
+		Response: harResponse{BodySize: -1},
+	}
+	if entry.Request.HTTPVersion == "" {
+		entry.Request.HTTPVersion = "HTTP/1.1"
+	}
+	if len(reqBody) != 0 {
+		content := l.harContent(reqBody, req.ContentLength, req.Header.Get("Content-Type"))
+		entry.Request.PostData = &content
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Response = harResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Headers:     l.harHeaders(resp.Header),
+			BodySize:    resp.ContentLength,
+			Content:     l.harContent(respBody, resp.ContentLength, resp.Header.Get("Content-Type")),
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.transcript.watched {
+		l.transcript.watched = true
+		OnFailure(l.T, l.writeTranscript)
+	}
+	if len(l.transcript.entries) == l.Transcript {
+		l.transcript.entries = l.transcript.entries[1:]
+		l.transcript.dropped++
+	}
+	l.transcript.entries = append(l.transcript.entries, entry)
+}
+
+func (l *HTTPLog) harHeaders(header http.Header) []harHeader {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	headers := []harHeader{}
+	for _, name := range names {
+		for _, value := range header[name] {
+			headers = append(headers, harHeader{Name: name, Value: l.redact(value)})
+		}
+	}
+	return headers
+}
+
+// harContent describes a body of which body is the start. size is the
+// full size of the body if it is known.
+func (l *HTTPLog) harContent(body []byte, size int64, mimeType string) harContent {
+	content := harContent{Size: size, MimeType: mimeType}
+	if size < 0 {
+		content.Size = int64(len(body))
+	}
+	if limit := l.transcriptBody(); len(body) > limit {
+		body = body[:limit]
+		content.Comment = fmt.Sprintf("truncated to %d bytes", limit)
+	}
+	if utf8.Valid(body) {
+		content.Text = l.redact(string(body))
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+	return content
+}
+
+// writeTranscript writes the transcript to http.har in the ArtifactDir
+// of the test.
+func (l *HTTPLog) writeTranscript(fc FailureContext) {
+	l.mu.Lock()
+	har := harFile{Log: harLog{
+		Version: "1.2",
+		Creator: harCreator{Name: "ntest", Version: "1"},
+		Entries: append([]harEntry(nil), l.transcript.entries...),
+	}}
+	if l.transcript.dropped != 0 {
+		har.Log.Comment = fmt.Sprintf("%d earlier requests were dropped", l.transcript.dropped)
+	}
+	l.mu.Unlock()
+	dir, err := fc.ArtifactDir()
+	path := filepath.Join(dir, "http.har")
+	if err == nil {
+		var enc []byte
+		enc, err = json.MarshalIndent(har, "", "  ")
+		if err == nil {
+			err = os.WriteFile(path, append(enc, '\n'), 0o644)
+		}
+	}
+	if err != nil {
+		l.T.Logf("could not write HTTP transcript: %s", err)
+		return
+	}
+	l.T.Logf("HTTP transcript of %d requests written to %s", len(har.Log.Entries), path)
+}
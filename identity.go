@@ -0,0 +1,90 @@
+package ntest
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RunIDEnv names the environment variable that sets the RunID. CI
+// systems can set it to their build or job ID. If it is not set, a
+// ULID is generated when the RunID is first used.
+const RunIDEnv = "NTEST_RUN_ID"
+
+var runID struct {
+	once sync.Once
+	id   string
+}
+
+// RunID identifies this run of the test binary. All tests in the process
+// share the same RunID.
+func RunID() string {
+	runID.once.Do(func() {
+		runID.id = os.Getenv(RunIDEnv)
+		if runID.id == "" {
+			runID.id = strings.ToLower(newULID(time.Now()))
+		}
+	})
+	return runID.id
+}
+
+// TestIdentity identifies the test that owns a context.
+type TestIdentity struct {
+	// Test is the full name of the test, as from t.Name()
+	Test string
+	// Cell is the CellInfo.Path of the matrix cell, or "" if the test
+	// is not a matrix cell.
+	Cell  string
+	RunID string
+}
+
+type testIdentityKey struct{}
+
+var cellPaths sync.Map // test name -> CellInfo.Path
+
+// TestIdentityContext is an injector that adds the TestIdentity of the
+// test to the context. Code under test and fixtures can then use
+// TestIdentityFromContext to tag outbound requests and queries with the
+// test that made them, for example in a header or a SQL comment, so that
+// server-side logs can be matched up with tests.
+func TestIdentityContext(ctx context.Context, t T) (context.Context, TestIdentity) {
+	id := TestIdentity{
+		Test:  t.Name(),
+		RunID: RunID(),
+	}
+	if cell, ok := cellPaths.Load(t.Name()); ok {
+		id.Cell = cell.(string)
+	}
+	return WithTestIdentity(ctx, id), id
+}
+
+// WithTestIdentity returns a copy of ctx that carries id.
+func WithTestIdentity(ctx context.Context, id TestIdentity) context.Context {
+	return context.WithValue(ctx, testIdentityKey{}, id)
+}
+
+// TestIdentityFromContext returns the TestIdentity added by
+// TestIdentityContext or WithTestIdentity.
+func TestIdentityFromContext(ctx context.Context) (TestIdentity, bool) {
+	id, ok := ctx.Value(testIdentityKey{}).(TestIdentity)
+	return id, ok
+}
+
+// TestNameFromContext returns the name of the test that owns ctx, or ""
+// if the context does not carry a TestIdentity.
+func TestNameFromContext(ctx context.Context) string {
+	id, _ := TestIdentityFromContext(ctx)
+	return id.Test
+}
+
+// String returns the identity as key=value pairs, suitable for a
+// header value or a SQL comment.
+func (id TestIdentity) String() string {
+	s := "test=" + id.Test
+	if id.Cell != "" {
+		s += " cell=" + id.Cell
+	}
+	return s + " run=" + id.RunID
+}
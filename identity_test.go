@@ -0,0 +1,48 @@
+package ntest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTestIdentityContext(t *testing.T) {
+	_, ok := ntest.TestIdentityFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Equal(t, "", ntest.TestNameFromContext(context.Background()))
+
+	ntest.RunTest(t, context.Background, ntest.TestIdentityContext, func(ctx context.Context, id ntest.TestIdentity) {
+		got, ok := ntest.TestIdentityFromContext(ctx)
+		require.True(t, ok)
+		assert.Equal(t, id, got)
+		assert.Equal(t, t.Name(), got.Test)
+		assert.Equal(t, "", got.Cell)
+		assert.Equal(t, ntest.RunID(), got.RunID)
+		assert.NotEmpty(t, got.RunID)
+		assert.Equal(t, t.Name(), ntest.TestNameFromContext(ctx))
+		assert.Equal(t, "test="+t.Name()+" run="+got.RunID, got.String())
+	})
+}
+
+func TestTestIdentityContextMatrix(t *testing.T) {
+	ntest.RunMatrix(t,
+		context.Background,
+		map[string]nject.Provider{
+			"a": nject.Provide("a", func() int { return 1 }),
+			"b": nject.Provide("b", func() int { return 2 }),
+		},
+		ntest.TestIdentityContext,
+		func(t *testing.T, ctx context.Context, info ntest.CellInfo) {
+			id, ok := ntest.TestIdentityFromContext(ctx)
+			require.True(t, ok)
+			assert.Equal(t, t.Name(), id.Test)
+			assert.Equal(t, info.Path, id.Cell)
+			assert.Contains(t, id.String(), " cell="+info.Path+" ")
+		},
+	)
+}
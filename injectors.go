@@ -2,6 +2,12 @@ package ntest
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
 // This file contains example injectors that may be useful
@@ -10,12 +16,101 @@ import (
 // a Context that has been augmented with AutoCancel.
 type Cancel func()
 
+// Causes of the cancellation of contexts from AutoCancel and
+// AutoCancelTimeout, as returned by CancelCause. A context from
+// AutoCancelTimeout that runs out of time has context.DeadlineExceeded
+// as its cause.
+var (
+	ErrTestFinished = errors.New("context canceled because the test finished")
+	ErrCancelCalled = errors.New("context canceled by Cancel")
+	ErrTestFailed   = errors.New("context canceled because the test failed")
+	ErrInterrupted  = errors.New("context canceled because the test was interrupted")
+)
+
+// FailurePollInterval is how often the context from CancelOnFailure
+// checks whether the test has failed.
+var FailurePollInterval = 50 * time.Millisecond
+
 // AutoCancel adjusts context.Context so that it will be cancelled
 // when the test finishes. It can be cancelled early by calling
-// the returned Cancel function.
+// the returned Cancel function. CancelCause tells which happened.
 func AutoCancel(ctx context.Context, t T) (context.Context, Cancel) {
-	ctx, cancel := context.WithCancel(ctx)
-	onlyOnce := onceFunc(cancel)
-	t.Cleanup(onlyOnce)
-	return ctx, onlyOnce
+	ctx, cancel := withCancelCause(ctx)
+	t.Cleanup(func() { cancel(ErrTestFinished) })
+	return ctx, func() { cancel(ErrCancelCalled) }
+}
+
+// AutoCancelTimeout returns an injector that is like AutoCancel except
+// that the context is also cancelled after timeout (scaled with
+// ScaledTimeout).
+//
+//	ntest.RunTest(t, context.Background, ntest.AutoCancelTimeout(time.Minute), ...)
+func AutoCancelTimeout(timeout time.Duration) func(context.Context, T) (context.Context, Cancel) {
+	return func(ctx context.Context, t T) (context.Context, Cancel) {
+		ctx, cancelTimeout := context.WithTimeout(ctx, ScaledTimeout(timeout))
+		ctx, cancel := AutoCancel(ctx, t)
+		t.Cleanup(cancelTimeout)
+		return ctx, cancel
+	}
+}
+
+// CancelOnFailure adjusts context.Context so that it is cancelled, with
+// ErrTestFailed as its CancelCause, soon after the test fails, so that
+// background goroutines and fixture operations that use it stop
+// promptly rather than running until the test finishes. Like
+// AutoCancel, it is also cancelled when the test finishes.
+//
+//	ntest.RunTest(t, context.Background, ntest.CancelOnFailure, ...)
+func CancelOnFailure(ctx context.Context, t T) context.Context {
+	ctx, cancel := withCancelCause(ctx)
+	t.Cleanup(func() { cancel(ErrTestFinished) })
+	go func() {
+		ticker := time.NewTicker(FailurePollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if t.Failed() {
+					cancel(ErrTestFailed)
+					return
+				}
+			}
+		}
+	}()
+	return ctx
+}
+
+// CancelOnInterrupt adjusts context.Context so that it is cancelled
+// when the test binary receives SIGINT or SIGTERM, as well as when the
+// test finishes. Its CancelCause then wraps ErrInterrupted. That way a
+// local run that is interrupted can tear down external resources, like
+// containers and remote schemas, rather than leave them behind.
+//
+// Each context catches only one signal: a second one terminates the
+// test binary as usual.
+func CancelOnInterrupt(ctx context.Context, t T) context.Context {
+	ctx, cancel := withCancelCause(ctx)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	t.Cleanup(func() {
+		signal.Stop(signals)
+		cancel(ErrTestFinished)
+	})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case sig := <-signals:
+			signal.Stop(signals)
+			cancel(fmt.Errorf("%w: %s", ErrInterrupted, sig))
+		}
+	}()
+	return ctx
+}
+
+// CancelCause returns why ctx was cancelled, like context.Cause (which
+// requires Go 1.20), or nil if it has not been cancelled.
+func CancelCause(ctx context.Context) error {
+	return contextCause(ctx)
 }
@@ -2,7 +2,12 @@ package ntest_test
 
 import (
 	"context"
+	"os"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/memsql/ntest"
 )
@@ -27,3 +32,76 @@ func TestCancel(t *testing.T) {
 		}
 	})
 }
+
+func TestCancelCause(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t, context.Background, ntest.AutoCancel, func(ctx context.Context, cancel ntest.Cancel) {
+		cancel()
+		assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrCancelCalled)
+		assert.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	var ctx context.Context
+	ct := &cleanupT{T: t}
+	ntest.RunTest(ct, context.Background, ntest.AutoCancel, func(c context.Context) {
+		ctx = c
+		assert.NoError(t, ntest.CancelCause(ctx))
+	})
+	ct.runCleanups()
+	assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrTestFinished)
+}
+
+func TestAutoCancelTimeout(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t, context.Background, ntest.AutoCancelTimeout(time.Millisecond), func(ctx context.Context, cancel ntest.Cancel) {
+		<-ctx.Done()
+		assert.ErrorIs(t, ntest.CancelCause(ctx), context.DeadlineExceeded)
+		cancel()
+		assert.ErrorIs(t, ntest.CancelCause(ctx), context.DeadlineExceeded, "first cause is kept")
+	})
+	ntest.RunTest(t, context.Background, ntest.AutoCancelTimeout(time.Hour), func(ctx context.Context, cancel ntest.Cancel) {
+		cancel()
+		assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrCancelCalled)
+	})
+}
+
+func TestCancelOnFailure(t *testing.T) {
+	t.Parallel()
+	ft := &failingT{cleanupT: &cleanupT{T: t}}
+	ntest.RunTest(ft, context.Background, ntest.CancelOnFailure, func(ctx context.Context) {
+		time.Sleep(2 * ntest.FailurePollInterval)
+		assert.NoError(t, ctx.Err(), "not cancelled before the test fails")
+		ft.Errorf("failed")
+		select {
+		case <-ctx.Done():
+		case <-time.After(ntest.ScaledTimeout(5 * time.Second)):
+			t.Fatal("context is not cancelled after the test failed")
+		}
+		assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrTestFailed)
+	})
+	ft.runCleanups()
+
+	var ctx context.Context
+	ct := &cleanupT{T: t}
+	ntest.RunTest(ct, context.Background, ntest.CancelOnFailure, func(c context.Context) { ctx = c })
+	ct.runCleanups()
+	assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrTestFinished)
+}
+
+func TestCancelOnInterrupt(t *testing.T) {
+	ntest.RunTest(t, context.Background, ntest.CancelOnInterrupt, func(ctx context.Context) {
+		assert.NoError(t, ctx.Err())
+		p, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		if err := p.Signal(os.Interrupt); err != nil {
+			t.Skipf("cannot interrupt the test: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(ntest.ScaledTimeout(5 * time.Second)):
+			t.Fatal("context is not cancelled after the interrupt")
+		}
+		assert.ErrorIs(t, ntest.CancelCause(ctx), ntest.ErrInterrupted)
+		assert.Contains(t, ntest.CancelCause(ctx).Error(), "interrupt")
+	})
+}
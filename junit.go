@@ -0,0 +1,200 @@
+package ntest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// JUnitEnv names the environment variable that, when set to a file
+// path, enables a JUnitReporter writing to that path.
+const JUnitEnv = "NTEST_JUNIT"
+
+func enableJUnit(path string) error {
+	AddReporter(NewJUnitReporter(path))
+	return nil
+}
+
+// JUnitReporter is a Reporter that writes JUnit XML when it is closed,
+// which Main does after all tests have run. Without Main, the file is
+// instead rewritten at most every junitWriteInterval while tests finish,
+// so the results of the last tests may be missing from it.
+type JUnitReporter struct {
+	path    string
+	suite   string
+	mu      sync.Mutex
+	results []TestResult
+	written time.Time
+	timer   *time.Timer
+}
+
+const junitWriteInterval = 2 * time.Second
+
+var _ Reporter = &JUnitReporter{}
+
+// NewJUnitReporter creates a JUnitReporter that writes to path. The test
+// suite is named after the test binary.
+func NewJUnitReporter(path string) *JUnitReporter {
+	return &JUnitReporter{
+		path:  path,
+		suite: testBinary(),
+	}
+}
+
+func (r *JUnitReporter) TestStarted(string, time.Time) {}
+
+func (r *JUnitReporter) TestFinished(result TestResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+	if usingMain() || r.timer != nil {
+		return
+	}
+	wait := junitWriteInterval - time.Since(r.written)
+	if wait <= 0 {
+		r.writeOrComplain()
+		return
+	}
+	r.timer = time.AfterFunc(wait, func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.timer != nil {
+			r.timer = nil
+			r.writeOrComplain()
+		}
+	})
+}
+
+// writeOrComplain must be called with r.mu held
+func (r *JUnitReporter) writeOrComplain() {
+	r.written = time.Now()
+	if err := r.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "ntest: write junit report: %s\n", err)
+	}
+}
+
+func (r *JUnitReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.timer != nil {
+		r.timer.Stop()
+		r.timer = nil
+	}
+	return r.write()
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// write must be called with r.mu held
+func (r *JUnitReporter) write() error {
+	results := append([]TestResult(nil), r.results...)
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Start.Before(results[j].Start) })
+	suite := junitTestSuite{Name: r.suite}
+	var total time.Duration
+	for _, result := range results {
+		classname := result.Name
+		if i := strings.IndexByte(classname, '/'); i != -1 {
+			classname = classname[:i]
+		}
+		tc := junitTestCase{
+			Name:      result.Name,
+			Classname: r.suite + "." + classname,
+			Time:      seconds(result.Duration),
+		}
+		switch {
+		case result.Failed:
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: firstLine(result.Messages),
+				Text:    strings.Join(result.Messages, "\n"),
+			}
+			tc.SystemOut = strings.Join(result.Log, "\n")
+			if result.ArtifactDir != "" {
+				// attachment syntax understood by the Jenkins JUnit plugin
+				tc.SystemOut += "\n[[ATTACHMENT|" + result.ArtifactDir + "]]"
+			}
+		case result.Skipped:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: firstLine(result.Messages)}
+		}
+		suite.Cases = append(suite.Cases, tc)
+		total += result.Duration
+	}
+	suite.Tests = len(results)
+	suite.Time = seconds(total)
+	if len(results) != 0 {
+		suite.Timestamp = results[0].Start.UTC().Format("2006-01-02T15:04:05")
+	}
+	out, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.path, append([]byte(xml.Header), append(out, '\n')...))
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
+
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.SplitN(lines[0], "\n", 2)[0]
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into
+// place so that readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,140 @@
+package ntest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// JWTClaims are the claims for a token minted by a TokenIssuer.
+type JWTClaims map[string]interface{}
+
+// TokenIssuer is a stand-in identity provider. It holds an RSA signing
+// key and serves its public key as a JWKS document so that services that
+// validate JWTs can be tested without a real identity provider.
+type TokenIssuer struct {
+	// Issuer is the "iss" claim added to every token. It is the URL of
+	// the test server.
+	Issuer string
+	// JWKSURL is where the public key can be fetched.
+	JWKSURL string
+	// KeyID is the "kid" of the signing key.
+	KeyID string
+	// Key is the signing key.
+	Key *rsa.PrivateKey
+}
+
+// JWT is a signed token as produced by TokenIssuer.MintToken
+type JWT string
+
+// TokenIssuerFixture provides a *TokenIssuer whose JWKS endpoint and
+// OpenID discovery document are served by an httptest.Server that is
+// closed when the test finishes.
+var TokenIssuerFixture = nject.Provide("token-issuer", func(t T) *TokenIssuer {
+	return NewTokenIssuer(t)
+})
+
+// NewTokenIssuer creates a TokenIssuer and starts its server.
+func NewTokenIssuer(t T) *TokenIssuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate signing key: %s", err)
+	}
+	issuer := &TokenIssuer{
+		Key:   key,
+		KeyID: keyID(&key.PublicKey),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(issuer.JWKS())
+	})
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.Issuer,
+			"jwks_uri":                              issuer.JWKSURL,
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	issuer.Issuer = server.URL
+	issuer.JWKSURL = server.URL + "/.well-known/jwks.json"
+	return issuer
+}
+
+// JWKS returns the JSON Web Key Set for the public signing key.
+func (i *TokenIssuer) JWKS() map[string]interface{} {
+	return map[string]interface{}{
+		"keys": []map[string]interface{}{
+			{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": i.KeyID,
+				"n":   b64(i.Key.PublicKey.N.Bytes()),
+				"e":   b64(big.NewInt(int64(i.Key.PublicKey.E)).Bytes()),
+			},
+		},
+	}
+}
+
+// MintToken creates an RS256 signed token. Unless overridden in claims,
+// "iss" is set to the Issuer, "iat" and "nbf" to now, and "exp" to an hour
+// from now.  To leave out one of the defaults, set it to nil in claims.
+func (i *TokenIssuer) MintToken(t T, claims JWTClaims) JWT {
+	t.Helper()
+	now := time.Now().Unix()
+	all := JWTClaims{
+		"iss": i.Issuer,
+		"iat": now,
+		"nbf": now,
+		"exp": now + 3600,
+	}
+	for k, v := range claims {
+		if v == nil {
+			delete(all, k)
+			continue
+		}
+		all[k] = v
+	}
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+		"kid": i.KeyID,
+	})
+	if err != nil {
+		t.Fatalf("encode token header: %s", err)
+	}
+	payload, err := json.Marshal(all)
+	if err != nil {
+		t.Fatalf("encode token claims: %s", err)
+	}
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("sign token: %s", err)
+	}
+	return JWT(signingInput + "." + b64(signature))
+}
+
+func keyID(key *rsa.PublicKey) string {
+	sum := sha256.Sum256(key.N.Bytes())
+	return b64(sum[:8])
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
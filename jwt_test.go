@@ -0,0 +1,62 @@
+package ntest_test
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTokenIssuer(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t,
+		ntest.TokenIssuerFixture,
+		func(t *testing.T, issuer *ntest.TokenIssuer) {
+			token := issuer.MintToken(t, ntest.JWTClaims{"sub": "user-1", "nbf": nil})
+			parts := strings.Split(string(token), ".")
+			require.Equal(t, 3, len(parts))
+
+			resp, err := http.Get(issuer.JWKSURL)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			var jwks struct {
+				Keys []struct {
+					Kid string `json:"kid"`
+					N   string `json:"n"`
+					E   string `json:"e"`
+				} `json:"keys"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&jwks))
+			require.Equal(t, 1, len(jwks.Keys))
+			assert.Equal(t, issuer.KeyID, jwks.Keys[0].Kid)
+
+			decode := func(s string) []byte {
+				b, err := base64.RawURLEncoding.DecodeString(s)
+				require.NoError(t, err)
+				return b
+			}
+			pub := &rsa.PublicKey{
+				N: new(big.Int).SetBytes(decode(jwks.Keys[0].N)),
+				E: int(new(big.Int).SetBytes(decode(jwks.Keys[0].E)).Int64()),
+			}
+			digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+			assert.NoError(t, rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], decode(parts[2])), "verify")
+
+			var claims map[string]interface{}
+			require.NoError(t, json.Unmarshal(decode(parts[1]), &claims))
+			assert.Equal(t, "user-1", claims["sub"])
+			assert.Equal(t, issuer.Issuer, claims["iss"])
+			assert.NotContains(t, claims, "nbf")
+		},
+	)
+}
@@ -1,7 +1,11 @@
 package ntest_test
 
 import (
+	"encoding/json"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -25,3 +29,53 @@ func TestPrefixLogger(t *testing.T) {
 	assert.Regexp(t, `some-prefix \d\d:\d\d:\d\d not-formatted 3$`, caught[0], "unformatted")
 	assert.Regexp(t, `some-prefix \d\d:\d\d:\d\d formatted 'quoted'$`, caught[1], "formatted")
 }
+
+func TestLogKV(t *testing.T) {
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		caught = append(caught, s)
+	})
+	ntest.LogKV(captureT, "database ready",
+		"host", "127.0.0.1",
+		"port", 3306,
+		"elapsed", 1500*time.Millisecond,
+		"query", `select "x"`,
+		"empty", "",
+		7)
+	ntest.LogKV(ntest.JSONLogger(captureT), "database ready", "port", 3306, "elapsed", time.Second, "err", errors.New("oops"))
+	ntest.JSONLogger(captureT).Logf("plain %d", 1)
+
+	require.Equal(t, 3, len(caught), "len caught")
+	assert.Equal(t, `database ready host=127.0.0.1 port=3306 elapsed=1.5s query="select \"x\"" empty="" !BADKEY=7`, strings.TrimSpace(caught[0]))
+
+	var structured map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(caught[1]), &structured))
+	assert.Equal(t, "database ready", structured["msg"])
+	assert.Equal(t, float64(3306), structured["port"])
+	assert.Equal(t, "1s", structured["elapsed"])
+	assert.Equal(t, "oops", structured["err"])
+	assert.Contains(t, structured, "time")
+
+	require.NoError(t, json.Unmarshal([]byte(caught[2]), &structured))
+	assert.Equal(t, "plain 1", structured["msg"])
+}
+
+func TestExtraDetailCaller(t *testing.T) {
+	ntest.ExtraDetailCaller = true
+	defer func() { ntest.ExtraDetailCaller = false }()
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		caught = append(caught, s)
+	})
+	extraDetail := ntest.ExtraDetailLogger(captureT, "some-prefix")
+	extraDetail.Log("direct")
+	func() {
+		extraDetail.Logf("in %s", "closure")
+	}()
+
+	require.Equal(t, 2, len(caught), "len caught")
+	assert.Regexp(t, `some-prefix \d\d:\d\d:\d\d ntest_test\.TestExtraDetailCaller direct$`, caught[0])
+	assert.Regexp(t, `some-prefix \d\d:\d\d:\d\d ntest_test\.TestExtraDetailCaller.func\d+ in closure$`, caught[1])
+}
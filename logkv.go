@@ -0,0 +1,137 @@
+package ntest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kvLogger is implemented by T wrappers that render LogKV output
+// themselves.
+type kvLogger interface {
+	logKV(msg string, pairs []interface{})
+}
+
+// LogKV logs msg followed by key/value pairs, rendered as key=value:
+//
+//	ntest.LogKV(t, "database ready", "host", host, "port", port, "elapsed", time.Since(start))
+//
+// logs
+//
+//	database ready host=127.0.0.1 port=3306 elapsed=1.2s
+//
+// Values that contain spaces, quotes, or "=" are quoted. As with log/slog,
+// a key that is not a string, or a final key without a value, is logged
+// with the key "!BADKEY".
+//
+// If t is (or wraps) a T from JSONLogger, the message and pairs are logged
+// as one JSON object instead.
+func LogKV(t T, msg string, pairs ...interface{}) {
+	t.Helper()
+	for wrapped := t; ; {
+		if l, ok := wrapped.(kvLogger); ok {
+			l.logKV(msg, pairs)
+			return
+		}
+		w, ok := wrapped.(wrappedT)
+		if !ok {
+			break
+		}
+		wrapped = w.unwrap()
+	}
+	var b strings.Builder
+	b.WriteString(msg)
+	forEachPair(pairs, func(key string, value interface{}) {
+		b.WriteByte(' ')
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(kvText(value))
+	})
+	t.Log(b.String())
+}
+
+// forEachPair calls fn for each key/value pair, using "!BADKEY" like
+// log/slog for keys that are not strings.
+func forEachPair(pairs []interface{}, fn func(key string, value interface{})) {
+	for len(pairs) != 0 {
+		key, ok := pairs[0].(string)
+		if !ok || len(pairs) == 1 {
+			fn("!BADKEY", pairs[0])
+			pairs = pairs[1:]
+			continue
+		}
+		fn(key, pairs[1])
+		pairs = pairs[2:]
+	}
+}
+
+func kvText(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case error:
+		s = v.Error()
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprint(v)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// kvJSON returns the JSON encoding of value. Errors and fmt.Stringers
+// (like time.Duration) are encoded as their string form.
+func kvJSON(value interface{}) []byte {
+	switch v := value.(type) {
+	case error:
+		value = v.Error()
+	case json.Marshaler:
+	case fmt.Stringer:
+		value = v.String()
+	}
+	enc, err := json.Marshal(value)
+	if err != nil {
+		enc, _ = json.Marshal(fmt.Sprint(value))
+	}
+	return enc
+}
+
+type jsonLoggedT struct {
+	logWrappedT
+}
+
+// JSONLogger creates a T that logs each line as a JSON object with "time"
+// and "msg" fields, for tests whose output is consumed by tools. LogKV
+// adds its pairs as additional fields.
+func JSONLogger(t T) T {
+	jt := jsonLoggedT{}
+	jt.logWrappedT = logWrappedT{
+		T: t,
+		logger: func(s string) {
+			jt.logKV(strings.TrimSuffix(s, "\n"), nil)
+		},
+	}
+	return jt
+}
+
+func (t jsonLoggedT) logKV(msg string, pairs []interface{}) {
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	b.Write(kvJSON(time.Now().Format(time.RFC3339Nano)))
+	b.WriteString(`,"msg":`)
+	b.Write(kvJSON(msg))
+	forEachPair(pairs, func(key string, value interface{}) {
+		b.WriteByte(',')
+		b.Write(kvJSON(key))
+		b.WriteByte(':')
+		b.Write(kvJSON(value))
+	})
+	b.WriteByte('}')
+	t.T.Log(b.String())
+}
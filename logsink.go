@@ -0,0 +1,235 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables that enable a LogSink for the output of failed
+// tests: LogSinkEnv is a URL that NDJSON is posted to (HTTPLogSink) and
+// OTLPLogsEndpointEnv is the base URL of an OTLP/HTTP collector
+// (OTLPLogSink).
+const (
+	LogSinkEnv          = "NTEST_LOG_SINK"
+	OTLPLogsEndpointEnv = "NTEST_OTLP_LOGS_ENDPOINT"
+)
+
+// LogSinkBatchSize is the number of failed tests that are buffered before
+// they are shipped.
+var LogSinkBatchSize = 50
+
+func enableLogSink(url string) error {
+	AddLogSink(NewHTTPLogSink(url))
+	return nil
+}
+
+func enableOTLPLogs(endpoint string) error {
+	AddLogSink(NewOTLPLogSink(endpoint))
+	return nil
+}
+
+// LogRecord is the output of a failed test along with what is needed to
+// find it again.
+type LogRecord struct {
+	Time     time.Time     `json:"time"`
+	Test     string        `json:"test"`
+	RunID    string        `json:"run_id"`
+	Binary   string        `json:"binary"`
+	Duration time.Duration `json:"duration_ns"`
+	// Failures are the failure messages of the test.
+	Failures    []string `json:"failures"`
+	Log         []string `json:"log"`
+	ArtifactDir string   `json:"artifact_dir,omitempty"`
+}
+
+// LogSink ships the output of failed tests somewhere that keeps it longer
+// than CI console logs, such as a central log store for nightly runs.
+type LogSink interface {
+	Ship(records []LogRecord) error
+}
+
+// AddLogSink ships the output of every subsequent failed test to sink.
+// Records are shipped in batches of LogSinkBatchSize and when Main
+// finishes.
+func AddLogSink(sink LogSink) {
+	AddFailureHook(&logShipper{sink: sink})
+}
+
+type logShipper struct {
+	sink    LogSink
+	mu      sync.Mutex
+	pending []LogRecord
+}
+
+func (s *logShipper) OnTestFailed(result TestResult) {
+	s.mu.Lock()
+	s.pending = append(s.pending, LogRecord{
+		Time:        result.Start.Add(result.Duration),
+		Test:        result.Name,
+		RunID:       RunID(),
+		Binary:      testBinary(),
+		Duration:    result.Duration,
+		Failures:    result.Messages,
+		Log:         result.Log,
+		ArtifactDir: result.ArtifactDir,
+	})
+	if len(s.pending) < LogSinkBatchSize {
+		s.mu.Unlock()
+		return
+	}
+	records := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if err := s.sink.Ship(records); err != nil {
+		fmt.Fprintf(os.Stderr, "ntest: ship logs: %s\n", err)
+	}
+}
+
+func (s *logShipper) Close() error {
+	s.mu.Lock()
+	records := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	if len(records) == 0 {
+		return nil
+	}
+	return s.sink.Ship(records)
+}
+
+func testBinary() string {
+	return strings.TrimSuffix(filepath.Base(os.Args[0]), ".test")
+}
+
+// HTTPLogSink is a LogSink that posts each batch of records as NDJSON.
+type HTTPLogSink struct {
+	URL string
+	// Header is added to every request, for example for authorization.
+	Header http.Header
+	Client *http.Client
+}
+
+var _ LogSink = &HTTPLogSink{}
+
+// NewHTTPLogSink creates an HTTPLogSink that posts to url.
+func NewHTTPLogSink(url string) *HTTPLogSink {
+	return &HTTPLogSink{
+		URL:    url,
+		Header: make(http.Header),
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *HTTPLogSink) Ship(records []LogRecord) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return postLogs(s.Client, s.URL, "application/x-ndjson", s.Header, body.Bytes())
+}
+
+// OTLPLogSink is a LogSink that exports records to an OpenTelemetry
+// collector using OTLP/HTTP with JSON encoding. Each failed test is one
+// log record with severity ERROR whose body is its failure messages and
+// log.
+type OTLPLogSink struct {
+	URL    string
+	Client *http.Client
+}
+
+var _ LogSink = &OTLPLogSink{}
+
+// NewOTLPLogSink creates an OTLPLogSink that exports to endpoint (the
+// collector base URL; "/v1/logs" is added).
+func NewOTLPLogSink(endpoint string) *OTLPLogSink {
+	return &OTLPLogSink{
+		URL:    strings.TrimSuffix(endpoint, "/") + "/v1/logs",
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *OTLPLogSink) Ship(records []LogRecord) error {
+	logs := make([]otlpLogRecord, len(records))
+	for i, record := range records {
+		attributes := []otlpAttribute{
+			stringAttribute("ntest.test", record.Test),
+			stringAttribute("ntest.run_id", record.RunID),
+		}
+		if record.ArtifactDir != "" {
+			attributes = append(attributes, stringAttribute("ntest.artifact_dir", record.ArtifactDir))
+		}
+		logs[i] = otlpLogRecord{
+			Time:           unixNano(record.Time),
+			SeverityNumber: 17, // ERROR
+			SeverityText:   "ERROR",
+			Body:           otlpValue{StringValue: strings.Join(combineSlices(record.Failures, record.Log), "\n")},
+			Attributes:     attributes,
+		}
+	}
+	body, err := json.Marshal(otlpLogsRequest{
+		ResourceLogs: []otlpResourceLogs{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{stringAttribute("service.name", testBinary())},
+			},
+			ScopeLogs: []otlpScopeLogs{{
+				Scope:      otlpScope{Name: "github.com/memsql/ntest"},
+				LogRecords: logs,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	return postLogs(s.Client, s.URL, "application/json", nil, body)
+}
+
+func postLogs(client *http.Client, url string, contentType string, header http.Header, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post %s: status %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+type otlpLogsRequest struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpLogRecord struct {
+	Time           string          `json:"timeUnixNano"`
+	SeverityNumber int             `json:"severityNumber"`
+	SeverityText   string          `json:"severityText"`
+	Body           otlpValue       `json:"body"`
+	Attributes     []otlpAttribute `json:"attributes,omitempty"`
+}
@@ -0,0 +1,133 @@
+package ntest_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// prefixLogSink keeps records for tests whose names start with prefix.
+type prefixLogSink struct {
+	prefix  string
+	mu      sync.Mutex
+	records []ntest.LogRecord
+}
+
+func (s *prefixLogSink) Ship(records []ntest.LogRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, record := range records {
+		if strings.HasPrefix(record.Test, s.prefix) {
+			s.records = append(s.records, record)
+		}
+	}
+	return nil
+}
+
+func TestAddLogSink(t *testing.T) {
+	batchSize := ntest.LogSinkBatchSize
+	ntest.LogSinkBatchSize = 1
+	defer func() { ntest.LogSinkBatchSize = batchSize }()
+	sink := &prefixLogSink{prefix: t.Name() + "/"}
+	ntest.AddLogSink(sink)
+	t.Run("pass", func(t *testing.T) {
+		ntest.RunTest(t, func(t ntest.T) {
+			t.Log("fine")
+		})
+	})
+	t.Run("fail", func(t *testing.T) {
+		ct := &cleanupT{T: &errorCapturingT{T: t}}
+		ntest.RunTest(failedT{ct}, func(t ntest.T) {
+			t.Log("connecting")
+			t.Errorf("boom")
+		})
+		ct.runCleanups()
+	})
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	require.Len(t, sink.records, 1)
+	record := sink.records[0]
+	assert.Equal(t, "TestAddLogSink/fail", record.Test)
+	assert.Equal(t, ntest.RunID(), record.RunID)
+	assert.Equal(t, []string{"boom"}, record.Failures)
+	assert.Equal(t, []string{"connecting"}, record.Log)
+}
+
+func TestHTTPLogSink(t *testing.T) {
+	t.Parallel()
+	var lines []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer xyz", r.Header.Get("Authorization"))
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	sink := ntest.NewHTTPLogSink(server.URL)
+	sink.Header.Set("Authorization", "Bearer xyz")
+	require.NoError(t, sink.Ship([]ntest.LogRecord{
+		{Test: "TestA", RunID: "r1", Failures: []string{"boom"}, Log: []string{"one"}},
+		{Test: "TestB", RunID: "r1"},
+	}))
+	require.Len(t, lines, 2)
+	var record ntest.LogRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	assert.Equal(t, "TestA", record.Test)
+	assert.Equal(t, []string{"one"}, record.Log)
+}
+
+func TestOTLPLogSink(t *testing.T) {
+	t.Parallel()
+	var request struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []struct {
+					TimeUnixNano string `json:"timeUnixNano"`
+					SeverityText string `json:"severityText"`
+					Body         struct {
+						StringValue string `json:"stringValue"`
+					} `json:"body"`
+					Attributes []struct {
+						Key   string `json:"key"`
+						Value struct {
+							StringValue string `json:"stringValue"`
+						} `json:"value"`
+					} `json:"attributes"`
+				} `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/logs", r.URL.Path)
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+	}))
+	t.Cleanup(server.Close)
+
+	end := time.Unix(1700000000, 0)
+	require.NoError(t, ntest.NewOTLPLogSink(server.URL+"/").Ship([]ntest.LogRecord{
+		{Time: end, Test: "TestA", RunID: "r1", Failures: []string{"boom"}, Log: []string{"one", "two"}},
+	}))
+	require.Len(t, request.ResourceLogs, 1)
+	require.Len(t, request.ResourceLogs[0].ScopeLogs, 1)
+	records := request.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(t, records, 1)
+	assert.Equal(t, "1700000000000000000", records[0].TimeUnixNano)
+	assert.Equal(t, "ERROR", records[0].SeverityText)
+	assert.Equal(t, "boom\none\ntwo", records[0].Body.StringValue)
+	require.Len(t, records[0].Attributes, 2)
+	assert.Equal(t, "ntest.test", records[0].Attributes[0].Key)
+	assert.Equal(t, "TestA", records[0].Attributes[0].Value.StringValue)
+}
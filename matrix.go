@@ -1,7 +1,13 @@
 package ntest
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/muir/nject"
 )
@@ -21,9 +27,12 @@ import (
 //
 // Matrix values must be direct arguments to RunMatrix -- they will not be extracted
 // from nject.Sequences. RunParallelMatrix will fail if there is no matrix provided.
-func RunParallelMatrix(t *testing.T, chain ...any) {
+//
+// The returned MatrixResults is only complete once the cells have finished, which
+// for parallel cells is after RunParallelMatrix returns: check it in a t.Cleanup.
+func RunParallelMatrix(t *testing.T, chain ...any) *MatrixResults {
 	t.Parallel()
-	runMatrixTest(t, true, chain)
+	return runMatrixTest(t, true, chain)
 }
 
 // RunMatrix uses t.Run() separate execution for each
@@ -34,51 +43,288 @@ func RunParallelMatrix(t *testing.T, chain ...any) {
 // A matrix is a specific type: map[string]nject.Provider. Add those to the
 // chain to trigger matrix testing.
 //
+// Cells are run in order of their names and each one is given a CellInfo.
+//
 // Matrix values must be direct arguments to RunMatrix -- they will not be extracted
 // from nject.Sequences. RunMatrix will fail if there is no matrix provided.
-func RunMatrix(t *testing.T, chain ...any) {
-	runMatrixTest(t, false, chain)
+//
+// The returned MatrixResults can be used to make assertions about the matrix run
+// as a whole.
+func RunMatrix(t *testing.T, chain ...any) *MatrixResults {
+	return runMatrixTest(t, false, chain)
+}
+
+// MatrixResults collects the outcome of each cell of a matrix test.
+type MatrixResults struct {
+	mu    sync.Mutex
+	cells []CellResult
+}
+
+// CellResult is the outcome of one cell of a matrix test.
+type CellResult struct {
+	CellInfo
+	Name     string
+	Duration time.Duration
+	Failed   bool
+	Skipped  bool
+	// SkipReason is the message given to SkipCell or, when a Reporter
+	// is registered, to Skip or Skipf on the T that RunTest injects
+	// (ntest.T). The messages of other skips are not captured.
+	SkipReason string
 }
 
-func runMatrixTest(t *testing.T, parallel bool, chain []any) {
-	breakChain := func(t *testing.T, chain []any) (matrix map[string]nject.Provider, before []any, after []any) {
-		for i, injector := range chain {
-			matrix, ok := injector.(map[string]nject.Provider)
-			if ok {
-				return matrix, chain[:i], chain[i+1:]
+// Cells returns the results of the cells that have finished, in order of
+// their CellInfo.Index.
+func (r *MatrixResults) Cells() []CellResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cells := append([]CellResult(nil), r.cells...)
+	sort.Slice(cells, func(i, j int) bool { return cells[i].Index < cells[j].Index })
+	return cells
+}
+
+// Ran returns the number of cells that finished without being skipped.
+func (r *MatrixResults) Ran() int {
+	passed, failed, _ := r.counts()
+	return passed + failed
+}
+
+// Passed, Failed, and Skipped return the number of cells that finished
+// with each outcome. A cell that failed and then skipped counts as
+// failed.
+func (r *MatrixResults) Passed() int {
+	passed, _, _ := r.counts()
+	return passed
+}
+
+func (r *MatrixResults) Failed() int {
+	_, failed, _ := r.counts()
+	return failed
+}
+
+func (r *MatrixResults) Skipped() int {
+	_, _, skipped := r.counts()
+	return skipped
+}
+
+func (r *MatrixResults) counts() (passed, failed, skipped int) {
+	for _, cell := range r.Cells() {
+		switch {
+		case cell.Failed:
+			failed++
+		case cell.Skipped:
+			skipped++
+		default:
+			passed++
+		}
+	}
+	return passed, failed, skipped
+}
+
+// Summary is like "4 cells: 2 passed, 1 failed, 1 skipped", followed by
+// a line for each skipped cell with why it was skipped.
+func (r *MatrixResults) Summary() string {
+	passed, failed, skipped := r.counts()
+	summary := fmt.Sprintf("%d cells: %d passed, %d failed, %d skipped", passed+failed+skipped, passed, failed, skipped)
+	for _, cell := range r.Cells() {
+		if cell.Skipped && !cell.Failed {
+			reason := cell.SkipReason
+			if reason == "" {
+				reason = "no reason given"
 			}
+			summary += fmt.Sprintf("\n\t%s skipped: %s", cell.Path, reason)
 		}
-		return nil, nil, chain
 	}
+	return summary
+}
+
+func (r *MatrixResults) add(cell CellResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cells = append(r.cells, cell)
+}
+
+// MatrixFilterEnv names the environment variable that selects, with a
+// regular expression that is matched against the path of each cell
+// (like "mysql/tls", see CellInfo), which cells of matrix tests run.
+// The others are skipped. For example NTEST_MATRIX_FILTER='^mysql/'
+// runs only the mysql cells.
+const MatrixFilterEnv = "NTEST_MATRIX_FILTER"
+
+var matrixFilter struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+}
+
+func setMatrixFilter(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	matrixFilter.mu.Lock()
+	defer matrixFilter.mu.Unlock()
+	matrixFilter.pattern = re
+	return nil
+}
+
+func cellSelected(path string) bool {
+	matrixFilter.mu.Lock()
+	defer matrixFilter.mu.Unlock()
+	return matrixFilter.pattern == nil || matrixFilter.pattern.MatchString(path)
+}
+
+// cellSkips has the reasons given to SkipCell, by test name.
+var cellSkips sync.Map
+
+// SkipCell skips t, a matrix cell, with a reason that is recorded in
+// the CellResult of the cell and the matrix summary, whether t is the
+// *testing.T or the ntest.T of the cell. Use it in providers when
+// something the cell needs is absent:
+//
+//	func(t ntest.T) *Client {
+//		if os.Getenv("SERVICE_URL") == "" {
+//			ntest.SkipCell(t, "SERVICE_URL is not set")
+//		}
+//		...
+//	}
+//
+// SkipCell can be used for tests that are not matrix cells too; it is
+// then t.Skipf.
+func SkipCell(t T, format string, args ...interface{}) {
+	t.Helper()
+	recordCellSkip(t.Name(), fmt.Sprintf(format, args...))
+	t.Skipf(format, args...)
+}
+
+// recordCellSkip keeps the reason a matrix cell was skipped for its
+// CellResult. It does nothing for tests that are not matrix cells.
+func recordCellSkip(name, reason string) {
+	if _, ok := cellPaths.Load(name); ok {
+		cellSkips.Store(name, reason)
+	}
+}
+
+// CellInfo is injected into each cell of a matrix test. Cells are
+// numbered in order of their sorted names (nested matrices count as one
+// combined matrix) so Index can be used for deterministic partitioning,
+// like choosing a port or a seed.
+type CellInfo struct {
+	// Index is from 0 to Total-1.
+	Index int
+	// Total is the number of cells in the whole matrix.
+	Total int
+	// Path is the cell names joined with "/", as in the test name.
+	Path string
+}
+
+// String returns something like "cell 3/12 (mysql/tls)", counting from 1.
+func (c CellInfo) String() string {
+	return fmt.Sprintf("cell %d/%d (%s)", c.Index+1, c.Total, c.Path)
+}
+
+func runMatrixTest(t *testing.T, parallel bool, chain []any) *MatrixResults {
 	testingT := func(t *testing.T) []any {
 		return []any{nject.Provide("testing.T", func() *testing.T { return t })}
 	}
 
-	matrix, before, after := breakChain(t, chain)
+	matrix, before, after := breakChain(chain)
 	if matrix == nil {
 		t.Log("No matrix found in matrix testing, perhaps the specifier is in a Sequence? (not allowed)")
 		t.Fail()
-		return
+		return &MatrixResults{}
 	}
+	results := &MatrixResults{}
+	// the cells, even parallel ones, have finished when this runs
+	t.Cleanup(func() {
+		if results.Failed() != 0 || results.Skipped() != 0 {
+			t.Logf("matrix %s", results.Summary())
+		}
+	})
 
-	var startTest func(t *testing.T, matrix map[string]nject.Provider, before []any, after []any)
-	startTest = func(t *testing.T, matrix map[string]nject.Provider, before []any, after []any) {
-		for name, subChain := range matrix {
-			subChain := subChain
+	total := len(matrix)
+	for rest := after; ; {
+		var next map[string]nject.Provider
+		next, _, rest = breakChain(rest)
+		if next == nil {
+			break
+		}
+		total *= len(next)
+	}
+
+	var startTest func(t *testing.T, matrix map[string]nject.Provider, before []any, after []any, index int, path []string)
+	startTest = func(t *testing.T, matrix map[string]nject.Provider, before []any, after []any, index int, path []string) {
+		names := make([]string, 0, len(matrix))
+		for name := range matrix {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for i, name := range names {
+			name, subChain := name, matrix[name]
+			cellIndex := index*len(matrix) + i
+			cellPath := combineSlices(path, []string{name})
 			t.Run(name, func(t *testing.T) {
 				if parallel {
 					t.Parallel()
 				}
-				matrix, newBefore, newAfter := breakChain(t, after)
+				Attr(t, "ntest.cell", name)
+				start := time.Now()
+				emitEvent(Event{Time: start, Type: EventCellStart, Test: t.Name(), Name: name})
+				t.Cleanup(func() {
+					emitEvent(Event{
+						Time:    time.Now(),
+						Type:    EventCellEnd,
+						Test:    t.Name(),
+						Name:    name,
+						Elapsed: time.Since(start),
+						Outcome: outcome(t.Failed(), t.Skipped()),
+					})
+				})
+				matrix, newBefore, newAfter := breakChain(after)
 				if matrix == nil {
-					RunTest(t, combineSlices(testingT(t), before, []any{subChain}, after)...)
+					info := CellInfo{
+						Index: cellIndex,
+						Total: total,
+						Path:  strings.Join(cellPath, "/"),
+					}
+					cellPaths.Store(t.Name(), info.Path)
+					t.Cleanup(func() {
+						cellPaths.Delete(t.Name())
+						var reason string
+						if r, ok := cellSkips.LoadAndDelete(t.Name()); ok {
+							reason = r.(string)
+						}
+						results.add(CellResult{
+							CellInfo:   info,
+							Name:       t.Name(),
+							Duration:   time.Since(start),
+							Failed:     t.Failed(),
+							Skipped:    t.Skipped(),
+							SkipReason: reason,
+						})
+					})
+					if !cellSelected(info.Path) {
+						SkipCell(t, "not selected by %s", MatrixFilterEnv)
+					}
+					RunTest(t, combineSlices(testingT(t), []any{nject.Provide("cell-info", func() CellInfo { return info })}, before, []any{subChain}, after)...)
 				} else {
-					startTest(t, matrix, combineSlices(before, newBefore, []any{subChain}), newAfter)
+					startTest(t, matrix, combineSlices(before, newBefore, []any{subChain}), newAfter, cellIndex, cellPath)
 				}
 			})
 		}
 	}
-	startTest(t, matrix, before, after)
+	startTest(t, matrix, before, after, 0, nil)
+	return results
+}
+
+// breakChain splits a chain at its first matrix.
+func breakChain(chain []any) (matrix map[string]nject.Provider, before []any, after []any) {
+	for i, injector := range chain {
+		matrix, ok := injector.(map[string]nject.Provider)
+		if ok {
+			return matrix, chain[:i], chain[i+1:]
+		}
+	}
+	return nil, nil, chain
 }
 
 func combineSlices[T any](first []T, more ...[]T) []T {
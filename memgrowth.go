@@ -0,0 +1,186 @@
+package ntest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryGrowthEnv names the environment variable that enables a
+// MemoryGrowthReporter printing to standard output. Its value is the
+// threshold, in bytes (with an optional K, M, or G suffix), like "1M".
+const MemoryGrowthEnv = "NTEST_MEMORY_GROWTH"
+
+func enableMemoryGrowth(value string) error {
+	threshold, err := parseBytes(value)
+	if err != nil {
+		return err
+	}
+	AddReporter(NewMemoryGrowthReporter(os.Stdout, threshold))
+	return nil
+}
+
+// MemoryGrowth is a test after which the heap grew and never shrank back.
+type MemoryGrowth struct {
+	Test string
+	// HeapBefore is the live heap when the test started and HeapAfter
+	// the lowest live heap at any point after it finished.
+	HeapBefore uint64
+	HeapAfter  uint64
+}
+
+// Growth is HeapAfter - HeapBefore.
+func (g MemoryGrowth) Growth() uint64 { return g.HeapAfter - g.HeapBefore }
+
+// MemoryGrowthReporter is a Reporter that finds tests that leak memory
+// across a long test run, for example fixtures that are never released.
+// It measures the live heap (after a garbage collection) when each test
+// run with RunTest starts and finishes. A test is reported if the heap
+// is larger than it was when the test started, by more than a threshold,
+// every time it is measured afterwards, up to the end of the run. When
+// tests run in parallel, a test can be blamed for memory retained by
+// the tests that ran alongside it.
+//
+// Register it in TestMain, with ntest.Main to print the report:
+//
+//	func TestMain(m *testing.M) {
+//		ntest.AddReporter(ntest.NewMemoryGrowthReporter(os.Stdout, 1<<20))
+//		os.Exit(ntest.Main(m))
+//	}
+//
+// Garbage collecting twice per test slows down large test runs, so it is
+// best enabled when looking for a leak, see $NTEST_MEMORY_GROWTH.
+type MemoryGrowthReporter struct {
+	w         io.Writer
+	threshold uint64
+	mu        sync.Mutex
+	started   map[string][]uint64 // heap at start, by test
+	samples   []memorySample
+}
+
+type memorySample struct {
+	test   string
+	before uint64
+	after  uint64
+}
+
+var _ Reporter = &MemoryGrowthReporter{}
+
+// NewMemoryGrowthReporter creates a MemoryGrowthReporter that writes
+// the tests whose memory growth is larger than threshold bytes to w. If
+// w is an io.Closer other than os.Stdout, it is closed by Close.
+func NewMemoryGrowthReporter(w io.Writer, threshold uint64) *MemoryGrowthReporter {
+	return &MemoryGrowthReporter{
+		w:         w,
+		threshold: threshold,
+		started:   make(map[string][]uint64),
+	}
+}
+
+func (r *MemoryGrowthReporter) TestStarted(name string, _ time.Time) {
+	heap := liveHeap()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started[name] = append(r.started[name], heap)
+}
+
+func (r *MemoryGrowthReporter) TestFinished(result TestResult) {
+	heap := liveHeap()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	starts := r.started[result.Name]
+	if len(starts) == 0 {
+		return
+	}
+	before := starts[len(starts)-1]
+	if len(starts) == 1 {
+		delete(r.started, result.Name)
+	} else {
+		r.started[result.Name] = starts[:len(starts)-1]
+	}
+	r.samples = append(r.samples, memorySample{test: result.Name, before: before, after: heap})
+}
+
+// Report returns the tests that grew the heap by more than the
+// threshold, largest growth first, measuring the heap now as the last
+// sample.
+func (r *MemoryGrowthReporter) Report() []MemoryGrowth {
+	heap := liveHeap()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var growths []MemoryGrowth
+	lowest := heap
+	for i := len(r.samples) - 1; i >= 0; i-- {
+		sample := r.samples[i]
+		if sample.after < lowest {
+			lowest = sample.after
+		}
+		if lowest > sample.before && lowest-sample.before > r.threshold {
+			growths = append(growths, MemoryGrowth{
+				Test:       sample.test,
+				HeapBefore: sample.before,
+				HeapAfter:  lowest,
+			})
+		}
+	}
+	sort.SliceStable(growths, func(i, j int) bool { return growths[i].Growth() > growths[j].Growth() })
+	return growths
+}
+
+func (r *MemoryGrowthReporter) Close() error {
+	growths := r.Report()
+	var err error
+	if len(growths) != 0 {
+		var b strings.Builder
+		fmt.Fprintf(&b, "Tests after which the heap grew by more than %s and did not shrink back:\n", formatBytes(r.threshold))
+		for _, g := range growths {
+			fmt.Fprintf(&b, "\t%s: +%s (%s -> %s)\n", g.Test, formatBytes(g.Growth()), formatBytes(g.HeapBefore), formatBytes(g.HeapAfter))
+		}
+		_, err = io.WriteString(r.w, b.String())
+	}
+	if closer, ok := r.w.(io.Closer); ok && r.w != os.Stdout {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// liveHeap returns the bytes of heap that are still reachable.
+func liveHeap() uint64 {
+	runtime.GC()
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// parseBytes parses a number of bytes with an optional K, M, or G
+// (binary) suffix.
+func parseBytes(value string) (uint64, error) {
+	s := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(value), "B"), "i")
+	shift := 0
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K', 'k':
+			shift = 10
+		case 'M', 'm':
+			shift = 20
+		case 'G', 'g':
+			shift = 30
+		}
+	}
+	if shift != 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number of bytes %q", value)
+	}
+	return n << shift, nil
+}
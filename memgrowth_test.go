@@ -0,0 +1,39 @@
+package ntest_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+var leaked [][]byte
+
+func TestMemoryGrowthReporter(t *testing.T) {
+	var buf bytes.Buffer
+	r := ntest.NewMemoryGrowthReporter(&buf, 1<<20)
+	run := func(name string, f func()) {
+		r.TestStarted(name, time.Now())
+		f()
+		r.TestFinished(ntest.TestResult{Name: name})
+	}
+	var temporary [][]byte
+	run("TestLeaks", func() { leaked = append(leaked, make([]byte, 8<<20)) })
+	run("TestTemporary", func() { temporary = append(temporary, make([]byte, 8<<20)) })
+	run("TestSmall", func() { leaked = append(leaked, make([]byte, 1<<10)) })
+	run("TestFreesTemporary", func() { temporary = nil })
+	defer func() { leaked = nil }()
+
+	growths := r.Report()
+	require.Len(t, growths, 1)
+	assert.Equal(t, "TestLeaks", growths[0].Test)
+	assert.Greater(t, growths[0].Growth(), uint64(7<<20))
+
+	require.NoError(t, r.Close())
+	assert.Contains(t, buf.String(), "Tests after which the heap grew by more than 1.0MiB and did not shrink back:\n\tTestLeaks: +")
+	assert.NotContains(t, buf.String(), "TestTemporary")
+}
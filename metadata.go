@@ -0,0 +1,207 @@
+package ntest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// CloudCredentials are the credentials handed out by a MetadataServer.
+type CloudCredentials struct {
+	// AWS
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	RoleName        string
+	// GCP
+	AccessToken         string
+	ServiceAccountEmail string
+	// Expiration applies to both.
+	Expiration time.Time
+}
+
+// InstanceIdentity describes the fake instance.
+type InstanceIdentity struct {
+	InstanceID       string
+	AccountID        string // AWS account or GCP numeric project
+	ProjectID        string // GCP
+	Region           string
+	AvailabilityZone string
+	InstanceType     string
+}
+
+// MetadataServer is a fake cloud instance metadata service. It implements
+// enough of the EC2 instance metadata service (IMDSv1 and IMDSv2) and the
+// GCE metadata server for SDK credential providers and region detection.
+// Fields may be changed while the server is running by calling Update.
+type MetadataServer struct {
+	URL string
+	mu  sync.Mutex
+	// guarded by mu
+	credentials CloudCredentials
+	identity    InstanceIdentity
+	requests    []string
+}
+
+// MetadataServerFixture provides a *MetadataServer with plausible defaults.
+// It does not redirect SDKs to it; add MetadataEnv for that.
+var MetadataServerFixture = nject.Provide("metadata-server", func(t T) *MetadataServer {
+	return NewMetadataServer(t)
+})
+
+// MetadataEnv points the AWS and Google Cloud SDKs at the injected
+// *MetadataServer by setting AWS_EC2_METADATA_SERVICE_ENDPOINT and
+// GCE_METADATA_HOST. It also clears the environment variables that would
+// otherwise take precedence over instance credentials.
+//
+// Since it uses t.Setenv, it cannot be used in parallel tests.
+var MetadataEnv = nject.Required(nject.Provide("metadata-env", func(t T, server *MetadataServer) {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("parse metadata server URL: %s", err)
+	}
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "false")
+	t.Setenv("GCE_METADATA_HOST", u.Host)
+	for _, key := range []string{
+		"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN",
+		"AWS_PROFILE", "AWS_WEB_IDENTITY_TOKEN_FILE",
+		"GOOGLE_APPLICATION_CREDENTIALS",
+	} {
+		t.Setenv(key, "")
+	}
+}))
+
+// NewMetadataServer starts a MetadataServer that is closed when the test
+// finishes.
+func NewMetadataServer(t T) *MetadataServer {
+	m := &MetadataServer{
+		credentials: CloudCredentials{
+			AccessKeyID:         "ASIATESTACCESSKEY",
+			SecretAccessKey:     "test-secret-access-key",
+			SessionToken:        "test-session-token",
+			RoleName:            "ntest-role",
+			AccessToken:         "ya29.test-access-token",
+			ServiceAccountEmail: "ntest@test-project.iam.gserviceaccount.com",
+			Expiration:          time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+		},
+		identity: InstanceIdentity{
+			InstanceID:       "i-0123456789abcdef0",
+			AccountID:        "123456789012",
+			ProjectID:        "test-project",
+			Region:           "us-east-1",
+			AvailabilityZone: "us-east-1a",
+			InstanceType:     "m5.large",
+		},
+	}
+	server := httptest.NewServer(http.HandlerFunc(m.serve))
+	t.Cleanup(server.Close)
+	m.URL = server.URL
+	return m
+}
+
+// Update changes the credentials and identity served.
+func (m *MetadataServer) Update(f func(*CloudCredentials, *InstanceIdentity)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	f(&m.credentials, &m.identity)
+}
+
+// Requests returns the method and path of every request received so far.
+func (m *MetadataServer) Requests() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.requests...)
+}
+
+const imdsToken = "ntest-imds-token"
+
+func (m *MetadataServer) serve(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.requests = append(m.requests, r.Method+" "+r.URL.Path)
+	creds, identity := m.credentials, m.identity
+	m.mu.Unlock()
+
+	text := func(s string) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(s))
+	}
+	jsonBody := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+
+	path := r.URL.Path
+	if path == "/" || strings.HasPrefix(path, "/computeMetadata/") {
+		w.Header().Set("Metadata-Flavor", "Google")
+		if path != "/" && r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor header", http.StatusForbidden)
+			return
+		}
+	}
+
+	switch path {
+	// EC2
+	case "/latest/api/token":
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", r.Header.Get("X-Aws-Ec2-Metadata-Token-Ttl-Seconds"))
+		text(imdsToken)
+	case "/latest/meta-data/iam/security-credentials/", "/latest/meta-data/iam/security-credentials":
+		text(creds.RoleName)
+	case "/latest/meta-data/iam/security-credentials/" + creds.RoleName:
+		jsonBody(map[string]string{
+			"Code":            "Success",
+			"LastUpdated":     time.Now().UTC().Format(time.RFC3339),
+			"Type":            "AWS-HMAC",
+			"AccessKeyId":     creds.AccessKeyID,
+			"SecretAccessKey": creds.SecretAccessKey,
+			"Token":           creds.SessionToken,
+			"Expiration":      creds.Expiration.Format(time.RFC3339),
+		})
+	case "/latest/dynamic/instance-identity/document":
+		jsonBody(map[string]string{
+			"instanceId":       identity.InstanceID,
+			"accountId":        identity.AccountID,
+			"region":           identity.Region,
+			"availabilityZone": identity.AvailabilityZone,
+			"instanceType":     identity.InstanceType,
+		})
+	case "/latest/meta-data/instance-id":
+		text(identity.InstanceID)
+	case "/latest/meta-data/placement/region":
+		text(identity.Region)
+	case "/latest/meta-data/placement/availability-zone":
+		text(identity.AvailabilityZone)
+
+	// GCE
+	case "/":
+		text("computeMetadata/")
+	case "/computeMetadata/v1/project/project-id":
+		text(identity.ProjectID)
+	case "/computeMetadata/v1/project/numeric-project-id":
+		text(identity.AccountID)
+	case "/computeMetadata/v1/instance/id":
+		text(identity.InstanceID)
+	case "/computeMetadata/v1/instance/zone":
+		text("projects/" + identity.AccountID + "/zones/" + identity.AvailabilityZone)
+	case "/computeMetadata/v1/instance/service-accounts/default/email":
+		text(creds.ServiceAccountEmail)
+	case "/computeMetadata/v1/instance/service-accounts/default/token":
+		jsonBody(map[string]interface{}{
+			"access_token": creds.AccessToken,
+			"expires_in":   int(time.Until(creds.Expiration).Seconds()),
+			"token_type":   "Bearer",
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
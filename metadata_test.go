@@ -0,0 +1,53 @@
+package ntest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestMetadataServer(t *testing.T) {
+	ntest.RunTest(t,
+		ntest.MetadataServerFixture,
+		ntest.MetadataEnv,
+		func(server *ntest.MetadataServer) {
+			endpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+			require.Equal(t, server.URL, endpoint)
+
+			get := func(path string, header ...string) (string, int) {
+				req, err := http.NewRequest(http.MethodGet, endpoint+path, nil)
+				require.NoError(t, err)
+				for i := 0; i+1 < len(header); i += 2 {
+					req.Header.Set(header[i], header[i+1])
+				}
+				resp, err := http.DefaultClient.Do(req)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				body, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				return string(body), resp.StatusCode
+			}
+
+			role, _ := get("/latest/meta-data/iam/security-credentials/")
+			body, _ := get("/latest/meta-data/iam/security-credentials/" + role)
+			var creds map[string]string
+			require.NoError(t, json.Unmarshal([]byte(body), &creds))
+			assert.Equal(t, "ASIATESTACCESSKEY", creds["AccessKeyId"])
+
+			server.Update(func(_ *ntest.CloudCredentials, identity *ntest.InstanceIdentity) {
+				identity.ProjectID = "other-project"
+			})
+			_, status := get("/computeMetadata/v1/project/project-id")
+			assert.Equal(t, http.StatusForbidden, status)
+			project, _ := get("/computeMetadata/v1/project/project-id", "Metadata-Flavor", "Google")
+			assert.Equal(t, "other-project", project)
+		},
+	)
+}
@@ -0,0 +1,73 @@
+package ntest
+
+import (
+	"sync"
+)
+
+// Middleware wraps a test: it does whatever it needs to before and after
+// calling next, which runs the test, with t or with a T that wraps it.
+// Not calling next skips the body of the test. If next is called with
+// a T other than a *testing.T, the *testing.T is not injected.
+type Middleware func(next func(T), t T)
+
+var (
+	middlewareLock sync.Mutex
+	middlewares    []*registeredMiddleware
+)
+
+// registeredMiddleware gives each Middleware passed to AddMiddleware an
+// identity, since functions are not comparable
+type registeredMiddleware struct {
+	m Middleware
+}
+
+// AddMiddleware registers middleware for all subsequent tests run with
+// RunTest, including the cells of matrix tests. It is meant for
+// concerns that apply to every test in a package, like metrics, leak
+// checks, or validating the environment, and is usually called from
+// TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		ntest.AddMiddleware(func(next func(ntest.T), t ntest.T) {
+//			ntest.CheckFDLeaks(t)
+//			next(t)
+//		})
+//		os.Exit(ntest.Main(m))
+//	}
+//
+// The middleware registered first is the outermost. AddMiddleware
+// returns a function that unregisters m, for example in t.Cleanup of a
+// test that only wants to wrap its own subtests.
+func AddMiddleware(m ...Middleware) (remove func()) {
+	middlewareLock.Lock()
+	defer middlewareLock.Unlock()
+	added := make(map[*registeredMiddleware]bool, len(m))
+	for _, mw := range m {
+		registered := &registeredMiddleware{m: mw}
+		added[registered] = true
+		middlewares = append(middlewares, registered)
+	}
+	return func() {
+		middlewareLock.Lock()
+		defer middlewareLock.Unlock()
+		kept := make([]*registeredMiddleware, 0, len(middlewares))
+		for _, other := range middlewares {
+			if !added[other] {
+				kept = append(kept, other)
+			}
+		}
+		middlewares = kept
+	}
+}
+
+// withMiddleware runs test wrapped in the registered middleware.
+func withMiddleware(t T, test func(T)) {
+	middlewareLock.Lock()
+	wrap := middlewares
+	middlewareLock.Unlock()
+	for i := len(wrap) - 1; i >= 0; i-- {
+		m, next := wrap[i].m, test
+		test = func(t T) { m(next, t) }
+	}
+	test(t)
+}
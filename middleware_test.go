@@ -0,0 +1,82 @@
+package ntest_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestAddMiddleware(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	record := func(s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, s)
+	}
+	// middleware is process-wide, so only record this test
+	mine := func(t ntest.T) bool { return strings.HasPrefix(t.Name(), "TestAddMiddleware/") }
+	remove := ntest.AddMiddleware(
+		func(next func(ntest.T), t ntest.T) {
+			if mine(t) {
+				record("outer before " + t.Name())
+				defer record("outer after " + t.Name())
+			}
+			next(t)
+		},
+		func(next func(ntest.T), t ntest.T) {
+			if mine(t) && strings.HasSuffix(t.Name(), "skipped") {
+				record("skipped")
+				return
+			}
+			next(t)
+		},
+	)
+
+	t.Run("cell", func(t *testing.T) {
+		ntest.RunTest(t, func() { record("test") })
+	})
+	t.Run("skipped", func(t *testing.T) {
+		ntest.RunTest(t, func() { record("test") })
+	})
+	assert.Equal(t, []string{
+		"outer before TestAddMiddleware/cell",
+		"test",
+		"outer after TestAddMiddleware/cell",
+		"outer before TestAddMiddleware/skipped",
+		"skipped",
+		"outer after TestAddMiddleware/skipped",
+	}, calls)
+
+	remove()
+	calls = nil
+	t.Run("removed", func(t *testing.T) {
+		ntest.RunTest(t, func() { record("test") })
+	})
+	assert.Equal(t, []string{"test"}, calls)
+}
+
+func TestAddMiddlewareMatrix(t *testing.T) {
+	var mu sync.Mutex
+	var wrapped []string
+	t.Cleanup(ntest.AddMiddleware(func(next func(ntest.T), t ntest.T) {
+		if strings.HasPrefix(t.Name(), "TestAddMiddlewareMatrix/") {
+			mu.Lock()
+			wrapped = append(wrapped, t.Name())
+			mu.Unlock()
+		}
+		next(t)
+	}))
+	ntest.RunMatrix(t,
+		map[string]nject.Provider{
+			"a": nject.Provide("a", func() int { return 1 }),
+			"b": nject.Provide("b", func() int { return 2 }),
+		},
+		func(int) {})
+	assert.ElementsMatch(t, []string{"TestAddMiddlewareMatrix/a", "TestAddMiddlewareMatrix/b"}, wrapped)
+}
@@ -0,0 +1,212 @@
+package ntest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// Migrations is a directory of versioned SQL migrations in the layout
+// used by goose and atlas: files named <version>_<description>.sql that
+// are applied in order of version. Statements end with a semicolon at
+// the end of a line. Goose annotations are understood: only the
+// "-- +goose Up" section of a file is applied and statements between
+// "-- +goose StatementBegin" and "-- +goose StatementEnd" are not split.
+type Migrations struct {
+	Dir string
+	// FS, if set, is what Dir is read from. Otherwise Dir is read from
+	// the filesystem.
+	FS fs.FS
+	// Template, if set, is called once, the first time the migrations
+	// are needed, with a function that applies them to a database. It
+	// should create a template database, migrate it, and return a
+	// function that copies the template into the database of a test
+	// (for example with CREATE TABLE ... LIKE), which is usually much
+	// faster than migrating each database from scratch.
+	Template func(ctx context.Context, migrate func(*sql.DB) error) (clone func(ctx context.Context, db *sql.DB) error, err error)
+}
+
+// MigrationVersion is the version of the last migration applied by
+// MigrationsFixture.
+type MigrationVersion int64
+
+type migration struct {
+	version    int64
+	file       string
+	statements []string
+}
+
+var migrationFileRE = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+
+// MigrationsFixture migrates the injected *sql.DB, which should be the
+// empty schema of the test, and provides the MigrationVersion it was
+// migrated to. The version is logged and, if the test fails, logged
+// again with the failure.
+//
+// The migrations are read, and the Template is made, only once for
+// each fixture, so create the fixture once, for example as a package
+// variable:
+//
+//	var Migrated = ntest.MigrationsFixture(ntest.Migrations{Dir: "../migrations"})
+func MigrationsFixture(m Migrations) nject.Provider {
+	var once sync.Once
+	var migrations []migration
+	var clone func(context.Context, *sql.DB) error
+	var err error
+	prepare := func() {
+		migrations, err = m.read()
+		if err != nil || m.Template == nil {
+			return
+		}
+		clone, err = m.Template(context.Background(), func(db *sql.DB) error {
+			return applyMigrations(context.Background(), db, migrations)
+		})
+		if err != nil {
+			err = fmt.Errorf("template: %w", err)
+		}
+	}
+	return nject.Provide("migrations-"+filepath.Base(m.Dir), func(t T, db *sql.DB) MigrationVersion {
+		t.Helper()
+		once.Do(prepare)
+		if err != nil {
+			t.Fatalf("migrate %s: %s", m.Dir, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("migrate %s: no migrations found", m.Dir)
+		}
+		ctx := context.Background()
+		if clone != nil {
+			if err := clone(ctx, db); err != nil {
+				t.Fatalf("migrate %s: clone template: %s", m.Dir, err)
+			}
+		} else if err := applyMigrations(ctx, db, migrations); err != nil {
+			t.Fatalf("migrate %s: %s", m.Dir, err)
+		}
+		last := migrations[len(migrations)-1]
+		t.Logf("schema migrated to version %d (%s)", last.version, last.file)
+		OnFailure(t, func(fc FailureContext) {
+			fc.T.Logf("schema was migrated to version %d (%s) from %s", last.version, last.file, m.Dir)
+		})
+		return MigrationVersion(last.version)
+	})
+}
+
+// Apply applies all of the migrations to db and returns the version of
+// the last one. Template is not used.
+func (m Migrations) Apply(ctx context.Context, db *sql.DB) (MigrationVersion, error) {
+	migrations, err := m.read()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, fmt.Errorf("no migrations found in %s", m.Dir)
+	}
+	if err := applyMigrations(ctx, db, migrations); err != nil {
+		return 0, err
+	}
+	return MigrationVersion(migrations[len(migrations)-1].version), nil
+}
+
+func applyMigrations(ctx context.Context, db *sql.DB, migrations []migration) error {
+	for _, mig := range migrations {
+		for _, statement := range mig.statements {
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("%s: %s: %w", mig.file, statement, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (m Migrations) read() ([]migration, error) {
+	fsys, dir := m.FS, m.Dir
+	if fsys == nil {
+		fsys, dir = os.DirFS(m.Dir), "."
+	}
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []migration
+	versions := make(map[int64]string)
+	for _, entry := range entries {
+		match := migrationFileRE.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		if other, ok := versions[version]; ok {
+			return nil, fmt.Errorf("%s and %s have the same version", other, entry.Name())
+		}
+		versions[version] = entry.Name()
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration{
+			version:    version,
+			file:       entry.Name(),
+			statements: splitMigration(string(data)),
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// splitMigration returns the statements of the up migration in a file.
+func splitMigration(text string) []string {
+	goose := strings.Contains(text, "-- +goose Up")
+	up := !goose
+	var statements []string
+	var current []string
+	var block bool
+	flush := func() {
+		statement := strings.TrimSpace(strings.Join(current, "\n"))
+		current = nil
+		if statement != "" {
+			statements = append(statements, strings.TrimSuffix(statement, ";"))
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "-- +goose Up"):
+			up = true
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose Down"):
+			up = false
+			continue
+		case !up:
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose StatementBegin"):
+			block = true
+			continue
+		case strings.HasPrefix(trimmed, "-- +goose StatementEnd"):
+			block = false
+			flush()
+			continue
+		case strings.HasPrefix(trimmed, "--") && len(current) == 0:
+			continue
+		}
+		current = append(current, line)
+		if !block && strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return statements
+}
@@ -0,0 +1,109 @@
+package ntest_test
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+var testMigrations = fstest.MapFS{
+	"migrations/002_orders.sql": {Data: []byte(`-- +goose Up
+CREATE TABLE orders (id INT);
+-- +goose StatementBegin
+CREATE PROCEDURE p() AS BEGIN
+  DELETE FROM orders;
+END
+-- +goose StatementEnd
+
+-- +goose Down
+DROP TABLE orders;
+`)},
+	"migrations/001_users.sql": {Data: []byte(`-- users
+CREATE TABLE users (
+  id INT
+);
+INSERT INTO users VALUES (1);
+`)},
+	"migrations/atlas.sum": {Data: []byte("ignored")},
+}
+
+var queryRE = regexp.MustCompile(`query=("(?:[^"\\]|\\.)*")`)
+
+// migratedDB opens a fake database that records the statements executed
+func migratedDB(t ntest.T, executed *[]string) *sql.DB {
+	log := ntest.NewSQLLog(ntest.ReplaceLogger(t, func(s string) {
+		if m := queryRE.FindStringSubmatch(s); m != nil {
+			query, err := strconv.Unquote(m[1])
+			require.NoError(t, err)
+			*executed = append(*executed, query)
+		}
+	}))
+	db, err := log.Open("ntest-fake", "")
+	require.NoError(t, err)
+	return db
+}
+
+func TestMigrationsFixture(t *testing.T) {
+	var executed []string
+	ft := &failingT{cleanupT: &cleanupT{T: t}}
+	var logged []string
+	lt := ntest.ReplaceLogger(ft, func(s string) { logged = append(logged, s) })
+	ntest.RunTest(lt,
+		func(t ntest.T) *sql.DB { return migratedDB(t, &executed) },
+		ntest.MigrationsFixture(ntest.Migrations{FS: testMigrations, Dir: "migrations"}),
+		func(v ntest.MigrationVersion) {
+			assert.Equal(t, ntest.MigrationVersion(2), v)
+		},
+	)
+	assert.Equal(t, []string{
+		"CREATE TABLE users (\n  id INT\n)",
+		"INSERT INTO users VALUES (1)",
+		"CREATE TABLE orders (id INT)",
+		"CREATE PROCEDURE p() AS BEGIN\n  DELETE FROM orders;\nEND",
+	}, executed)
+	ft.errors = append(ft.errors, "failed")
+	ft.runCleanups()
+	assert.Contains(t, strings.Join(logged, "\n"), "schema was migrated to version 2 (002_orders.sql) from migrations")
+}
+
+func TestMigrationsTemplate(t *testing.T) {
+	var templates, clones int
+	var executed []string
+	fixture := ntest.MigrationsFixture(ntest.Migrations{
+		FS:  testMigrations,
+		Dir: "migrations",
+		Template: func(ctx context.Context, migrate func(*sql.DB) error) (func(context.Context, *sql.DB) error, error) {
+			templates++
+			if err := migrate(migratedDB(t, &executed)); err != nil {
+				return nil, err
+			}
+			return func(context.Context, *sql.DB) error {
+				clones++
+				return nil
+			}, nil
+		},
+	})
+	for i := 0; i < 3; i++ {
+		ntest.RunTest(t, func() (*sql.DB, error) { return sql.Open("ntest-fake", "") }, fixture, func(v ntest.MigrationVersion) {
+			assert.Equal(t, ntest.MigrationVersion(2), v)
+		})
+	}
+	assert.Equal(t, 1, templates)
+	assert.Equal(t, 3, clones)
+	assert.Len(t, executed, 4)
+
+	v, err := ntest.Migrations{FS: testMigrations, Dir: "migrations"}.Apply(context.Background(), migratedDB(t, &executed))
+	require.NoError(t, err)
+	assert.Equal(t, ntest.MigrationVersion(2), v)
+	_, err = ntest.Migrations{Dir: t.TempDir()}.Apply(context.Background(), migratedDB(t, &executed))
+	assert.ErrorContains(t, err, "no migrations found")
+}
@@ -0,0 +1,61 @@
+package ntest
+
+import (
+	"reflect"
+
+	"github.com/muir/nject"
+)
+
+// AssertMocks makes RunTest check, when the test finishes, the injected
+// values that have an AssertExpectations(TestingT) bool method, like
+// testify mocks (types that embed mock.Mock). Each is called with the
+// T of the test and, if its expectations were not met, the failure names
+// the test and the type of the mock. As with WaitForReadiness, only
+// mocks that are provided before the last element of the chain and used
+// by the final function (directly or through other injectors after
+// them) are found.
+var AssertMocks = true
+
+// mockAsserter returns a provider, to be placed just before the last
+// element of chain, that registers the mocks provided by the rest of
+// chain to be checked at cleanup. It returns nil if there are none.
+func mockAsserter(t T, chain []interface{}) nject.Provider {
+	if !AssertMocks || len(chain) < 2 {
+		return nil
+	}
+	types := chainOutputs(chain[:len(chain)-1], hasAssertExpectations)
+	if len(types) == 0 {
+		return nil
+	}
+	injectors := make([]interface{}, len(types))
+	for i, typ := range types {
+		typ := typ
+		injectors[i] = passThrough("assert-"+typ.String(), typ, func(v reflect.Value) {
+			t.Cleanup(func() {
+				t.Helper()
+				out := v.MethodByName("AssertExpectations").Call([]reflect.Value{reflect.ValueOf(t)})
+				if !out[0].Bool() {
+					t.Errorf("%s: mock %s did not get the calls that it expected", t.Name(), typ)
+				}
+			})
+		})
+	}
+	return nject.Sequence("assert-mocks", injectors...)
+}
+
+func hasAssertExpectations(typ reflect.Type) bool {
+	method, ok := typ.MethodByName("AssertExpectations")
+	if !ok {
+		return false
+	}
+	ft := method.Type
+	in := 1 // the receiver
+	if typ.Kind() == reflect.Interface {
+		in = 0
+	}
+	return ft.NumIn() == in+1 &&
+		ft.In(in).Kind() == reflect.Interface &&
+		tType.Implements(ft.In(in)) &&
+		ft.NumOut() == 1 &&
+		ft.Out(0).Kind() == reflect.Bool
+}
@@ -0,0 +1,66 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// testingT is mock.TestingT from testify.
+type testingT interface {
+	Logf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+	FailNow()
+}
+
+// expectingMock has the AssertExpectations method of testify mocks.
+type expectingMock struct {
+	expected, got int
+	checked       int
+}
+
+func (m *expectingMock) AssertExpectations(t testingT) bool {
+	m.checked++
+	if m.got < m.expected {
+		t.Errorf("FAIL: %d out of %d expectation(s) were met", m.got, m.expected)
+		return false
+	}
+	return true
+}
+
+func TestAssertMocks(t *testing.T) {
+	met := &expectingMock{expected: 1}
+	unmet := &expectingMock{expected: 2}
+	unused := &expectingMock{expected: 1}
+	type unusedMock struct{ *expectingMock }
+
+	ct := &cleanupT{T: &errorCapturingT{T: t}}
+	ntest.RunTest(ct,
+		func() *expectingMock { return met },
+		func() unusedMock { return unusedMock{unused} },
+		func(m *expectingMock) {
+			m.got++
+		},
+	)
+	assert.Equal(t, 0, met.checked, "checked when the test finishes")
+	ct.runCleanups()
+	assert.Equal(t, 1, met.checked)
+	assert.Equal(t, 0, unused.checked, "not injected")
+	require.Empty(t, ct.T.(*errorCapturingT).errors)
+
+	ct = &cleanupT{T: &errorCapturingT{T: t}}
+	ntest.RunTest(ct,
+		func() *expectingMock { return unmet },
+		func(m *expectingMock) {
+			m.got++
+		},
+	)
+	ct.runCleanups()
+	errors := ct.T.(*errorCapturingT).errors
+	require.Len(t, errors, 2)
+	assert.Equal(t, "FAIL: 1 out of 2 expectation(s) were met", errors[0])
+	assert.Equal(t, "TestAssertMocks: mock *ntest_test.expectingMock did not get the calls that it expected", errors[1])
+}
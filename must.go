@@ -0,0 +1,41 @@
+package ntest
+
+// Must returns a function that accepts the results of a call that returns
+// a value and an error. If the error is not nil, the test fails immediately;
+// otherwise the value is returned. Since Go cannot infer the type from the
+// later call, it must be given explicitly:
+//
+//	db := ntest.Must[*sql.DB](t)(sql.Open("mysql", dsn))
+//
+// The failure is reported at the line of the caller.
+func Must[X any](t T) func(X, error) X {
+	return func(x X, err error) X {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return x
+	}
+}
+
+// Must2 is like Must for calls that return two values and an error.
+func Must2[X, Y any](t T) func(X, Y, error) (X, Y) {
+	return func(x X, y Y, err error) (X, Y) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return x, y
+	}
+}
+
+// Must3 is like Must for calls that return three values and an error.
+func Must3[X, Y, Z any](t T) func(X, Y, Z, error) (X, Y, Z) {
+	return func(x X, y Y, z Z, err error) (X, Y, Z) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		return x, y, z
+	}
+}
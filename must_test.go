@@ -0,0 +1,27 @@
+package ntest_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestMust(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, 42, ntest.Must[int](t)(strconv.Atoi("42")))
+	a, b := ntest.Must2[string, int](t)("x", 2, nil)
+	assert.Equal(t, "x", a)
+	assert.Equal(t, 2, b)
+	_, _, c := ntest.Must3[int, int, bool](t)(1, 2, true, nil)
+	assert.True(t, c)
+
+	capture := &fatalCapturingT{T: t}
+	assert.True(t, catchFatal(func() {
+		ntest.Must[int](capture)(0, errors.New("boom"))
+	}))
+	assert.Equal(t, []string{"unexpected error: boom"}, capture.fatals)
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package ntest
+
+const raceEnabled = false
@@ -0,0 +1,118 @@
+// Package ntestafero provides afero filesystems for tests, along with
+// adapters that let ntest seed them and load SingleStore pipelines from
+// them.
+//
+// It is a separate module so that ntest itself does not depend on afero.
+package ntestafero
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/memsql/ntest"
+	"github.com/muir/nject"
+	"github.com/spf13/afero"
+)
+
+// Fixture provides an afero.Fs that is private to the test. By default
+// it is an in-memory filesystem. Use OSFilesystem to switch to a real
+// directory.
+//
+// If the test fails, the contents of the filesystem are logged.
+var Fixture = nject.Provide("afero-fs", func(t ntest.T) afero.Fs {
+	return dumpOnFailure(t, afero.NewMemMapFs())
+})
+
+// OSFilesystem replaces the in-memory filesystem from Fixture with one
+// that is backed by a temporary directory on disk. The directory is
+// removed when the test finishes.
+func OSFilesystem() nject.Provider {
+	return nject.ReplaceNamed("afero-fs", func(t ntest.T) afero.Fs {
+		t.Helper()
+		dir, err := os.MkdirTemp("", "ntest-fs-")
+		if err != nil {
+			t.Fatalf("create temp dir: %s", err)
+		}
+		t.Cleanup(func() {
+			_ = os.RemoveAll(dir)
+		})
+		return dumpOnFailure(t, afero.NewBasePathFs(afero.NewOsFs(), dir))
+	})
+}
+
+func dumpOnFailure(t ntest.T, fs afero.Fs) afero.Fs {
+	ntest.OnFailure(t, func(ntest.FailureContext) {
+		DumpFS(t, fs)
+	})
+	return fs
+}
+
+// DumpFS logs a listing of every file and directory in fs with modes
+// and sizes.
+func DumpFS(t ntest.T, fs afero.Fs) {
+	var lines []string
+	err := afero.Walk(fs, "/", func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			lines = append(lines, path+": "+err.Error())
+			return nil
+		}
+		depth := strings.Count(filepath.ToSlash(filepath.Clean(path)), "/")
+		if path == "/" {
+			depth = 0
+		}
+		name := info.Name()
+		if info.IsDir() {
+			name += "/"
+		}
+		lines = append(lines, strings.Repeat("  ", depth)+info.Mode().String()+" "+
+			formatSize(info.Size(), info.IsDir())+" "+name)
+		return nil
+	})
+	if err != nil {
+		lines = append(lines, "walk failed: "+err.Error())
+	}
+	t.Logf("filesystem contents:\n%s", strings.Join(lines, "\n"))
+}
+
+func formatSize(size int64, isDir bool) string {
+	if isDir {
+		return "-"
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// SeedStore adapts an afero.Fs (see Fixture) to ntest.SeedStore.
+// Keys are paths.
+type SeedStore struct {
+	Fs afero.Fs
+}
+
+func (s SeedStore) Put(_ context.Context, key string, data []byte) error {
+	if err := s.Fs.MkdirAll(filepath.Dir(key), 0o755); err != nil {
+		return err
+	}
+	return afero.WriteFile(s.Fs, key, data, 0o644)
+}
+
+func (s SeedStore) Get(_ context.Context, key string) ([]byte, error) {
+	return afero.ReadFile(s.Fs, key)
+}
+
+// PipelineSource is ntest.FSPipelineSource for files in the afero.Fs
+// from Fixture. It only works with OSFilesystem since the files must be
+// on disk.
+func PipelineSource(t ntest.T, fs afero.Fs, glob string) ntest.PipelineSource {
+	t.Helper()
+	base, ok := fs.(*afero.BasePathFs)
+	if !ok {
+		t.Fatalf("pipeline source must be on disk (use OSFilesystem), not %T", fs)
+	}
+	path, err := base.RealPath(glob)
+	if err != nil {
+		t.Fatalf("pipeline source %s: %s", glob, err)
+	}
+	return ntest.FSPipelineSource(path)
+}
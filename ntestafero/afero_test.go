@@ -0,0 +1,89 @@
+package ntestafero_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+	"github.com/memsql/ntest/ntestafero"
+)
+
+func TestFixture(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		name  string
+		chain []interface{}
+	}{
+		{name: "memory", chain: []interface{}{ntestafero.Fixture}},
+		{name: "os", chain: []interface{}{ntestafero.Fixture, ntestafero.OSFilesystem()}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			ntest.RunTest(t, append(tc.chain, func(fs afero.Fs) {
+				require.NoError(t, fs.MkdirAll("/a/b", 0o755))
+				require.NoError(t, afero.WriteFile(fs, "/a/b/c.txt", []byte("hello"), 0o644))
+				var caught []string
+				ntestafero.DumpFS(ntest.ReplaceLogger(t, func(s string) {
+					caught = append(caught, s)
+				}), fs)
+				require.Equal(t, 1, len(caught))
+				assert.Regexp(t, `(?m)^      -rw-r--r-- 5 c\.txt$`, caught[0])
+			})...)
+		})
+	}
+}
+
+func TestSeedStore(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), []byte("from a file"), 0o600))
+	manifest := filepath.Join(dir, "manifest.yaml")
+	require.NoError(t, os.WriteFile(manifest, []byte(`
+seed:
+  - store: files
+    key: /in/inline.txt
+    content: hello
+  - store: files
+    key: /in/big.txt
+    file: big.txt
+expect:
+  - store: files
+    key: /out/result.json
+    json:
+      count: 2
+      names: [big.txt, inline.txt]
+  - store: files
+    key: /in/inline.txt
+    absent: true
+`), 0o600))
+
+	ntest.RunTest(t,
+		ntestafero.Fixture,
+		func(fs afero.Fs) ntest.SeedStores {
+			return ntest.SeedStores{"files": ntestafero.SeedStore{Fs: fs}}
+		},
+		ntest.SeedFromManifest(manifest),
+		func(fs afero.Fs) {
+			data, err := afero.ReadFile(fs, "/in/big.txt")
+			require.NoError(t, err)
+			require.Equal(t, "from a file", string(data))
+			require.NoError(t, fs.Remove("/in/inline.txt"))
+			require.NoError(t, fs.MkdirAll("/out", 0o755))
+			require.NoError(t, afero.WriteFile(fs, "/out/result.json",
+				[]byte(`{"names":["big.txt","inline.txt"],"count":2}`), 0o644))
+		},
+	)
+}
+
+func TestPipelineSource(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	source := ntestafero.PipelineSource(t, afero.NewBasePathFs(afero.NewOsFs(), dir), "/in/*.csv")
+	assert.Equal(t, ntest.PipelineSource{Kind: "FS", Path: filepath.Join(dir, "in", "*.csv")}, source)
+}
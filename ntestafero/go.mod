@@ -0,0 +1,22 @@
+module github.com/memsql/ntest/ntestafero
+
+go 1.18
+
+require (
+	github.com/memsql/ntest v0.0.0
+	github.com/muir/nject v1.8.0
+	github.com/spf13/afero v1.10.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/go-cmp v0.6.0 // indirect
+	github.com/muir/reflectutils v0.7.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/text v0.3.7 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/memsql/ntest => ../
@@ -0,0 +1,117 @@
+// Package ntestginkgo adapts ntest to Ginkgo (v2) suites.
+//
+// ntest.RunTest can be given ginkgo.GinkgoT() directly, but matrix testing
+// uses testing.T.Run which does not exist in Ginkgo. This package provides
+// RunTest and RunMatrix that map onto Ginkgo containers instead.
+//
+// ntest has no ReWrap method or ReWrapper interface for wrappers of T to
+// implement, so there is nothing of that kind here: the T from this
+// package is used as-is, and ntest's own wrappers (like ReplaceLogger)
+// can be applied on top of it.
+package ntestginkgo
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/memsql/ntest"
+	"github.com/muir/nject"
+	"github.com/onsi/ginkgo/v2"
+)
+
+// T returns the ntest.T for the current spec. Skip and Fatal are
+// forwarded to ginkgo.Skip and ginkgo.Fail so that specs are marked as
+// skipped or failed (rather than panicking with an unexpected value) and
+// failures are reported at the line of the caller.
+func T() ntest.T {
+	return ginkgoT{FullGinkgoTInterface: ginkgo.GinkgoT(1)}
+}
+
+type ginkgoT struct {
+	ginkgo.FullGinkgoTInterface
+}
+
+func (t ginkgoT) Skip(args ...interface{}) {
+	ginkgo.Skip(sprintln(args...), 1)
+}
+
+func (t ginkgoT) Skipf(format string, args ...interface{}) {
+	ginkgo.Skip(fmt.Sprintf(format, args...), 1)
+}
+
+func (t ginkgoT) Fatal(args ...interface{}) {
+	ginkgo.Fail(sprintln(args...), 1)
+}
+
+func (t ginkgoT) Fatalf(format string, args ...interface{}) {
+	ginkgo.Fail(fmt.Sprintf(format, args...), 1)
+}
+
+// RunTest runs an injection chain for the current Ginkgo spec. Call it
+// from inside an It.
+func RunTest(chain ...interface{}) {
+	ntest.RunTest(T(), chain...)
+}
+
+// RunMatrix is the Ginkgo equivalent of ntest.RunMatrix. It must be called
+// while building the spec tree (inside Describe or Context, not It). Each
+// cell of the matrix becomes an It; when there are several matrices, the
+// outer ones become Contexts. Cells are added in sorted order so that the
+// spec tree is the same on every Ginkgo process.
+//
+// Matrix values must be direct arguments to RunMatrix -- they will not be
+// extracted from nject.Sequences.
+func RunMatrix(chain ...interface{}) {
+	matrix, before, after := breakChain(chain)
+	if matrix == nil {
+		ginkgo.Fail("No matrix found in matrix testing, perhaps the specifier is in a Sequence? (not allowed)", 1)
+		return
+	}
+	addCells(matrix, before, after)
+}
+
+func addCells(matrix map[string]nject.Provider, before []interface{}, after []interface{}) {
+	names := make([]string, 0, len(matrix))
+	for name := range matrix {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subChain := matrix[name]
+		nextMatrix, newBefore, newAfter := breakChain(after)
+		if nextMatrix == nil {
+			chain := combine(before, []interface{}{subChain}, after)
+			ginkgo.It(name, func() {
+				RunTest(chain...)
+			})
+			continue
+		}
+		ginkgo.Context(name, func() {
+			addCells(nextMatrix, combine(before, newBefore, []interface{}{subChain}), newAfter)
+		})
+	}
+}
+
+func breakChain(chain []interface{}) (matrix map[string]nject.Provider, before []interface{}, after []interface{}) {
+	for i, injector := range chain {
+		matrix, ok := injector.(map[string]nject.Provider)
+		if ok {
+			return matrix, chain[:i], chain[i+1:]
+		}
+	}
+	return nil, nil, chain
+}
+
+func combine(slices ...[]interface{}) []interface{} {
+	var combined []interface{}
+	for _, s := range slices {
+		combined = append(combined, s...)
+	}
+	return combined
+}
+
+// sprintln formats like fmt.Sprintln but without the trailing newline.
+func sprintln(args ...interface{}) string {
+	line := fmt.Sprintln(args...)
+	return line[:len(line)-1]
+}
@@ -0,0 +1,67 @@
+package ntestginkgo_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+
+	"github.com/memsql/ntest"
+	"github.com/memsql/ntest/ntestginkgo"
+)
+
+func TestNtestGinkgo(t *testing.T) {
+	gomega.RegisterFailHandler(ginkgo.Fail)
+	ginkgo.RunSpecs(t, "ntestginkgo")
+}
+
+type color string
+type size int
+
+var (
+	mu   sync.Mutex
+	seen = make(map[string]bool)
+)
+
+var _ = ginkgo.Describe("RunMatrix", func() {
+	ntestginkgo.RunMatrix(
+		map[string]nject.Provider{
+			"red":  nject.Provide("red", func() color { return "red" }),
+			"blue": nject.Provide("blue", func() color { return "blue" }),
+		},
+		map[string]nject.Provider{
+			"small": nject.Provide("small", func() size { return 1 }),
+			"large": nject.Provide("large", func() size { return 10 }),
+		},
+		func(t ntest.T, c color, s size) {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[string(c)+"/"+t.Name()] = true
+			gomega.Expect(s).To(gomega.BeNumerically(">", 0))
+		},
+	)
+
+	ginkgo.It("runs every cell", func() {
+		mu.Lock()
+		defer mu.Unlock()
+		gomega.Expect(seen).To(gomega.HaveLen(4))
+	})
+})
+
+var _ = ginkgo.Describe("RunTest", func() {
+	ginkgo.It("injects T", func() {
+		ntestginkgo.RunTest(func(t ntest.T) {
+			t.Setenv("NTEST_GINKGO", "yes")
+			t.Logf("running %s", t.Name())
+		})
+	})
+
+	ginkgo.It("skips", func() {
+		ntestginkgo.RunTest(func(t ntest.T) {
+			t.Skip("skipping from ntest")
+		})
+		ginkgo.Fail("not reached")
+	})
+})
@@ -0,0 +1,64 @@
+// Package ntestgomock provides injectors for gomock (go.uber.org/mock).
+//
+// It is a separate module so that ntest itself does not depend on gomock.
+package ntestgomock
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/memsql/ntest"
+	"github.com/muir/nject"
+	"go.uber.org/mock/gomock"
+)
+
+// Controller creates a *gomock.Controller that reports to t. Failures
+// go through the same T as the rest of the test, so wrappers like
+// ntest.ReplaceLogger see them and line numbers point at the caller.
+// Finish is called when the test finishes; there is no need to call it.
+func Controller(t ntest.T) *gomock.Controller {
+	// NewController registers Finish with t.Cleanup
+	return gomock.NewController(t)
+}
+
+// ControllerInjector provides a *gomock.Controller, see Controller.
+var ControllerInjector = nject.Provide("gomock-controller", Controller)
+
+// Mock provides the mock created by newMock, which is usually a
+// constructor generated by mockgen. The *gomock.Controller is provided
+// by ControllerInjector, which is included.
+//
+//	ntest.RunTest(t,
+//		ntestgomock.Mock(NewMockMailer),
+//		func(m *MockMailer) { m.EXPECT().Send("a") ... },
+//	)
+func Mock[M any](newMock func(*gomock.Controller) M) nject.Provider {
+	return nject.Sequence("gomock-"+typeName[M](),
+		ControllerInjector,
+		nject.Provide("mock-"+typeName[M](), newMock),
+	)
+}
+
+// MockAs is like Mock but the mock is also provided as an I, typically
+// the interface that it mocks, so that injectors that want an I get the
+// mock. MockAs panics if M does not implement I.
+//
+//	ntestgomock.MockAs[Mailer](NewMockMailer)
+func MockAs[I any, M any](newMock func(*gomock.Controller) M) nject.Provider {
+	iType := reflect.TypeOf((*I)(nil)).Elem()
+	mType := reflect.TypeOf((*M)(nil)).Elem()
+	if !mType.AssignableTo(iType) {
+		panic(fmt.Sprintf("ntestgomock.MockAs: %s does not implement %s", mType, iType))
+	}
+	return nject.Sequence("gomock-"+typeName[M](),
+		ControllerInjector,
+		nject.Provide("mock-"+typeName[M](), func(ctrl *gomock.Controller) (M, I) {
+			m := newMock(ctrl)
+			return m, any(m).(I)
+		}),
+	)
+}
+
+func typeName[X any]() string {
+	return reflect.TypeOf((*X)(nil)).Elem().String()
+}
@@ -0,0 +1,108 @@
+package ntestgomock_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	"github.com/memsql/ntest"
+	"github.com/memsql/ntest/ntestgomock"
+)
+
+type Mailer interface {
+	Send(to string) error
+}
+
+// MockMailer is what mockgen generates for Mailer.
+type MockMailer struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailerMockRecorder
+}
+
+type MockMailerMockRecorder struct {
+	mock *MockMailer
+}
+
+func NewMockMailer(ctrl *gomock.Controller) *MockMailer {
+	mock := &MockMailer{ctrl: ctrl}
+	mock.recorder = &MockMailerMockRecorder{mock}
+	return mock
+}
+
+func (m *MockMailer) EXPECT() *MockMailerMockRecorder {
+	return m.recorder
+}
+
+func (m *MockMailer) Send(to string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Send", to)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+func (mr *MockMailerMockRecorder) Send(to interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Send", reflect.TypeOf((*MockMailer)(nil).Send), to)
+}
+
+// reportingT records failures and cleanups instead of acting on them.
+type reportingT struct {
+	ntest.T
+	failures []string
+	cleanups []func()
+}
+
+func (t *reportingT) Errorf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *reportingT) Fatalf(format string, args ...interface{}) {
+	t.failures = append(t.failures, fmt.Sprintf(format, args...))
+}
+
+func (t *reportingT) Cleanup(f func()) { t.cleanups = append(t.cleanups, f) }
+
+func (t *reportingT) finish() {
+	for i := len(t.cleanups) - 1; i >= 0; i-- {
+		t.cleanups[i]()
+	}
+}
+
+func TestMockAs(t *testing.T) {
+	var called bool
+	ntest.RunTest(t,
+		ntestgomock.MockAs[Mailer](NewMockMailer),
+		func(m *MockMailer) {
+			m.EXPECT().Send("a").Return(nil)
+		},
+		func(mailer Mailer) {
+			called = true
+			assert.NoError(t, mailer.Send("a"))
+		},
+	)
+	assert.True(t, called)
+}
+
+func TestFinishOnCleanup(t *testing.T) {
+	rt := &reportingT{T: t}
+	ntest.RunTest(rt,
+		ntestgomock.Mock(NewMockMailer),
+		func(m *MockMailer) {
+			m.EXPECT().Send("b").Return(nil)
+		},
+	)
+	assert.Empty(t, rt.failures)
+	rt.finish()
+	require.NotEmpty(t, rt.failures, "Finish reports the missing call")
+	assert.Contains(t, rt.failures[0], "missing call(s) to *ntestgomock_test.MockMailer.Send(is equal to b (string))")
+}
+
+func TestMockAsMismatch(t *testing.T) {
+	assert.PanicsWithValue(t, "ntestgomock.MockAs: *ntestgomock_test.MockMailer does not implement fmt.Stringer", func() {
+		ntestgomock.MockAs[fmt.Stringer](NewMockMailer)
+	})
+}
@@ -0,0 +1,384 @@
+// Package ntestmemcache provides a memcached client for tests, backed by
+// an in-process fake or a real memcached.
+//
+// It is a separate module so that ntest itself does not depend on
+// gomemcache.
+package ntestmemcache
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/memsql/ntest"
+	"github.com/muir/nject"
+)
+
+// Service is used by Real.
+var Service = ntest.DockerService{
+	Name:   "memcached",
+	EnvVar: "NTEST_MEMCACHED_ADDR",
+	Image:  "memcached:1.6-alpine",
+	Port:   "11211/tcp",
+}
+
+// Addr is the host:port of the memcached server used by Fixture.
+type Addr string
+
+// Fixture provides a *memcache.Client. By default the client talks to an
+// in-process Fake that is private to the test. Add Real to the chain to
+// use a real memcached instead.
+var Fixture = nject.Sequence("memcached",
+	nject.Provide("memcached-addr", func(t ntest.T) Addr {
+		return Addr(NewFake(t).Addr())
+	}),
+	func(t ntest.T, addr Addr) *memcache.Client {
+		t.Helper()
+		client := memcache.New(string(addr))
+		if !ntest.Eventually(t, client.Ping, ntest.EventuallyOptions{
+			Timeout:     30 * time.Second,
+			Description: "memcached at " + string(addr) + " ready",
+		}) {
+			t.FailNow()
+		}
+		t.Cleanup(func() { _ = client.Close() })
+		return client
+	},
+)
+
+// Real replaces the in-process fake used by Fixture with a real
+// memcached, following the ntest.DockerService conventions: the address
+// comes from NTEST_MEMCACHED_ADDR, or a container is started, or the test
+// is skipped. A real memcached is shared state: use key prefixes (see
+// ntest.TestID) to keep tests separate.
+func Real() nject.Provider {
+	return nject.ReplaceNamed("memcached-addr", func(t ntest.T) Addr {
+		return Addr(Service.Address(t))
+	})
+}
+
+// Fake is an in-process server that implements the memcached
+// text protocol well enough for typical client libraries. It supports
+// get, gets, gat, gats, set, add, replace, append, prepend, cas, delete,
+// incr, decr, touch, flush_all, version, and quit.
+type Fake struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	items    map[string]*memcachedItem
+	casID    uint64
+	conns    map[net.Conn]struct{}
+}
+
+type memcachedItem struct {
+	value   []byte
+	flags   uint32
+	cas     uint64
+	expires time.Time
+}
+
+// NewFake starts a Fake on a random local port. It is stopped when the
+// test finishes.
+func NewFake(t ntest.T) *Fake {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("fake memcached listen: %s", err)
+	}
+	m := &Fake{
+		listener: listener,
+		items:    make(map[string]*memcachedItem),
+		conns:    make(map[net.Conn]struct{}),
+	}
+	m.wg.Add(1)
+	go m.accept()
+	t.Cleanup(m.close)
+	return m
+}
+
+// Addr is the address clients should connect to.
+func (m *Fake) Addr() string {
+	return m.listener.Addr().String()
+}
+
+// Keys returns the number of unexpired items.
+func (m *Fake) Keys() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int
+	for key := range m.items {
+		if m.lookup(key) != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *Fake) close() {
+	_ = m.listener.Close()
+	m.mu.Lock()
+	for conn := range m.conns {
+		_ = conn.Close()
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+func (m *Fake) accept() {
+	defer m.wg.Done()
+	for {
+		conn, err := m.listener.Accept()
+		if err != nil {
+			return
+		}
+		m.mu.Lock()
+		m.conns[conn] = struct{}{}
+		m.mu.Unlock()
+		m.wg.Add(1)
+		go m.serve(conn)
+	}
+}
+
+func (m *Fake) serve(conn net.Conn) {
+	defer m.wg.Done()
+	defer func() {
+		m.mu.Lock()
+		delete(m.conns, conn)
+		m.mu.Unlock()
+		_ = conn.Close()
+	}()
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+	for {
+		line, err := rw.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" {
+			return
+		}
+		if !m.command(rw, fields) {
+			return
+		}
+		if rw.Flush() != nil {
+			return
+		}
+	}
+}
+
+// command handles one command. It returns false if the connection
+// should be closed.
+func (m *Fake) command(rw *bufio.ReadWriter, fields []string) bool {
+	noreply := fields[len(fields)-1] == "noreply"
+	if noreply {
+		fields = fields[:len(fields)-1]
+	}
+	reply := func(s string) {
+		if !noreply {
+			_, _ = rw.WriteString(s + "\r\n")
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch cmd := fields[0]; cmd {
+	case "get", "gets", "gat", "gats":
+		keys := fields[1:]
+		var exptime int64
+		if cmd == "gat" || cmd == "gats" {
+			if len(keys) < 2 {
+				reply("ERROR")
+				return true
+			}
+			exptime, _ = strconv.ParseInt(keys[0], 10, 64)
+			keys = keys[1:]
+		}
+		for _, key := range keys {
+			item := m.lookup(key)
+			if item == nil {
+				continue
+			}
+			if cmd == "gat" || cmd == "gats" {
+				item.expires = memcachedExpiration(exptime)
+			}
+			header := "VALUE " + key + " " + strconv.FormatUint(uint64(item.flags), 10) + " " + strconv.Itoa(len(item.value))
+			if cmd == "gets" || cmd == "gats" {
+				header += " " + strconv.FormatUint(item.cas, 10)
+			}
+			_, _ = rw.WriteString(header + "\r\n")
+			_, _ = rw.Write(item.value)
+			_, _ = rw.WriteString("\r\n")
+		}
+		_, _ = rw.WriteString("END\r\n")
+	case "set", "add", "replace", "append", "prepend", "cas":
+		want := 5
+		if cmd == "cas" {
+			want = 6
+		}
+		if len(fields) != want {
+			reply("ERROR")
+			return true
+		}
+		flags, err1 := strconv.ParseUint(fields[2], 10, 32)
+		exptime, err2 := strconv.ParseInt(fields[3], 10, 64)
+		size, err3 := strconv.Atoi(fields[4])
+		if err1 != nil || err2 != nil || err3 != nil || size < 0 {
+			reply("CLIENT_ERROR bad command line format")
+			return false
+		}
+		data := make([]byte, size+2)
+		m.mu.Unlock()
+		_, err := io.ReadFull(rw, data)
+		m.mu.Lock()
+		if err != nil {
+			return false
+		}
+		if string(data[size:]) != "\r\n" {
+			reply("CLIENT_ERROR bad data chunk")
+			return true
+		}
+		reply(m.store(cmd, fields, data[:size], uint32(flags), exptime))
+	case "delete":
+		if len(fields) < 2 {
+			reply("ERROR")
+		} else if m.lookup(fields[1]) == nil {
+			reply("NOT_FOUND")
+		} else {
+			delete(m.items, fields[1])
+			reply("DELETED")
+		}
+	case "incr", "decr":
+		if len(fields) != 3 {
+			reply("ERROR")
+			return true
+		}
+		item := m.lookup(fields[1])
+		if item == nil {
+			reply("NOT_FOUND")
+			return true
+		}
+		delta, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			reply("CLIENT_ERROR invalid numeric delta argument")
+			return true
+		}
+		value, err := strconv.ParseUint(string(item.value), 10, 64)
+		if err != nil {
+			reply("CLIENT_ERROR cannot increment or decrement non-numeric value")
+			return true
+		}
+		switch {
+		case cmd == "incr":
+			value += delta
+		case delta > value:
+			value = 0
+		default:
+			value -= delta
+		}
+		item.value = []byte(strconv.FormatUint(value, 10))
+		m.casID++
+		item.cas = m.casID
+		reply(string(item.value))
+	case "touch":
+		if len(fields) != 3 {
+			reply("ERROR")
+			return true
+		}
+		item := m.lookup(fields[1])
+		if item == nil {
+			reply("NOT_FOUND")
+			return true
+		}
+		exptime, _ := strconv.ParseInt(fields[2], 10, 64)
+		item.expires = memcachedExpiration(exptime)
+		reply("TOUCHED")
+	case "flush_all":
+		m.items = make(map[string]*memcachedItem)
+		reply("OK")
+	case "version":
+		reply("VERSION 1.6.0-ntest-fake")
+	default:
+		reply("ERROR")
+	}
+	return true
+}
+
+// store must be called with m.mu held
+func (m *Fake) store(cmd string, fields []string, data []byte, flags uint32, exptime int64) string {
+	key := fields[1]
+	existing := m.lookup(key)
+	switch cmd {
+	case "add":
+		if existing != nil {
+			return "NOT_STORED"
+		}
+	case "replace", "append", "prepend":
+		if existing == nil {
+			return "NOT_STORED"
+		}
+	case "cas":
+		if existing == nil {
+			return "NOT_FOUND"
+		}
+		unique, err := strconv.ParseUint(fields[5], 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR bad command line format"
+		}
+		if unique != existing.cas {
+			return "EXISTS"
+		}
+	}
+	m.casID++
+	switch cmd {
+	case "append":
+		existing.value = append(existing.value, data...)
+		existing.cas = m.casID
+	case "prepend":
+		existing.value = append(append([]byte{}, data...), existing.value...)
+		existing.cas = m.casID
+	default:
+		m.items[key] = &memcachedItem{
+			value:   data,
+			flags:   flags,
+			cas:     m.casID,
+			expires: memcachedExpiration(exptime),
+		}
+	}
+	return "STORED"
+}
+
+// lookup must be called with m.mu held
+func (m *Fake) lookup(key string) *memcachedItem {
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if !item.expires.IsZero() && !time.Now().Before(item.expires) {
+		delete(m.items, key)
+		return nil
+	}
+	return item
+}
+
+// memcachedExpiration interprets an expiration time the way memcached
+// does: zero is never, up to 30 days is relative, larger is a unix time,
+// and negative is already expired.
+func memcachedExpiration(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return time.Unix(1, 0)
+	case exptime <= 30*24*60*60:
+		return time.Now().Add(time.Duration(exptime) * time.Second)
+	default:
+		return time.Unix(exptime, 0)
+	}
+}
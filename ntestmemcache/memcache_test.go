@@ -0,0 +1,41 @@
+package ntestmemcache_test
+
+import (
+	"testing"
+
+	"github.com/bradfitz/gomemcache/memcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+	"github.com/memsql/ntest/ntestmemcache"
+)
+
+func TestFixture(t *testing.T) {
+	t.Parallel()
+	ntest.RunTest(t,
+		ntestmemcache.Fixture,
+		func(client *memcache.Client) {
+			require.NoError(t, client.Set(&memcache.Item{Key: "a", Value: []byte("1"), Flags: 7}))
+			item, err := client.Get("a")
+			require.NoError(t, err)
+			assert.Equal(t, "1", string(item.Value))
+			assert.Equal(t, uint32(7), item.Flags)
+
+			assert.Equal(t, memcache.ErrNotStored, client.Add(&memcache.Item{Key: "a", Value: []byte("2")}))
+			n, err := client.Increment("a", 41)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(42), n)
+
+			item, err = client.Get("a")
+			require.NoError(t, err)
+			item.Value = []byte("cas")
+			require.NoError(t, client.CompareAndSwap(item))
+			assert.Equal(t, memcache.ErrCASConflict, client.CompareAndSwap(item))
+
+			require.NoError(t, client.Delete("a"))
+			_, err = client.Get("a")
+			assert.Equal(t, memcache.ErrCacheMiss, err)
+		},
+	)
+}
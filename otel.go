@@ -0,0 +1,271 @@
+package ntest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OTLPEndpointEnv names the environment variable that, when set to the
+// base URL of an OTLP/HTTP collector (for example http://localhost:4318),
+// enables an OTLPReporter.
+const OTLPEndpointEnv = "NTEST_OTLP_ENDPOINT"
+
+// OTLPBatchSize is the number of finished spans that are buffered before
+// they are exported.
+var OTLPBatchSize = 256
+
+func enableOTLP(endpoint string) error {
+	AddReporter(NewOTLPReporter(endpoint))
+	return nil
+}
+
+// OTLPReporter is a Reporter that exports a trace of the test run to an
+// OpenTelemetry collector using OTLP/HTTP with JSON encoding. There is one
+// trace per test process with a span for each test, each matrix cell, and
+// each injector wrapped with TimeInjector. Spans are nested by test name.
+type OTLPReporter struct {
+	url     string
+	service string
+	client  *http.Client
+	traceID string
+	mu      sync.Mutex
+	open    map[string][]otlpOpenSpan // by test name
+	pending []otlpSpan
+}
+
+type otlpOpenSpan struct {
+	id    string
+	start time.Time
+}
+
+var (
+	_ Reporter      = &OTLPReporter{}
+	_ EventListener = &OTLPReporter{}
+)
+
+// NewOTLPReporter creates an OTLPReporter that exports to endpoint (the
+// collector base URL; "/v1/traces" is added). The service name is the name
+// of the test binary.
+func NewOTLPReporter(endpoint string) *OTLPReporter {
+	return &OTLPReporter{
+		url:     strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		service: testBinary(),
+		client:  &http.Client{Timeout: 10 * time.Second},
+		traceID: randomHex(16),
+		open:    make(map[string][]otlpOpenSpan),
+	}
+}
+
+func (r *OTLPReporter) TestStarted(name string, start time.Time) {
+	r.startSpan(name, start)
+}
+
+func (r *OTLPReporter) TestFinished(result TestResult) {
+	r.endSpan(result.Name, "test "+result.Name, result.Start.Add(result.Duration),
+		outcome(result.Failed, result.Skipped), firstLine(result.Messages))
+}
+
+func (r *OTLPReporter) Event(e Event) {
+	switch e.Type {
+	case EventCellStart:
+		r.startSpan(e.Test, e.Time)
+	case EventCellEnd:
+		r.endSpan(e.Test, "cell "+e.Test, e.Time, e.Outcome, "")
+	case EventInjector:
+		r.mu.Lock()
+		parent := r.parent(e.Test)
+		r.pending = append(r.pending, otlpSpan{
+			TraceID:      r.traceID,
+			SpanID:       randomHex(8),
+			ParentSpanID: parent,
+			Name:         "injector " + e.Name,
+			Kind:         1,
+			Start:        unixNano(e.Time.Add(-e.Elapsed)),
+			End:          unixNano(e.Time),
+			Attributes:   []otlpAttribute{stringAttribute("ntest.test", e.Test)},
+		})
+		r.mu.Unlock()
+		r.maybeExport()
+	}
+}
+
+func (r *OTLPReporter) Close() error {
+	r.mu.Lock()
+	spans := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	return r.export(spans)
+}
+
+func (r *OTLPReporter) startSpan(name string, start time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.open[name] = append(r.open[name], otlpOpenSpan{id: randomHex(8), start: start})
+}
+
+func (r *OTLPReporter) endSpan(name, spanName string, end time.Time, result string, message string) {
+	r.mu.Lock()
+	stack := r.open[name]
+	if len(stack) == 0 {
+		r.mu.Unlock()
+		return
+	}
+	span := stack[len(stack)-1]
+	if len(stack) == 1 {
+		delete(r.open, name)
+	} else {
+		r.open[name] = stack[:len(stack)-1]
+	}
+	status := otlpStatus{Code: 1}
+	if result == "fail" {
+		status = otlpStatus{Code: 2, Message: message}
+	}
+	r.pending = append(r.pending, otlpSpan{
+		TraceID:      r.traceID,
+		SpanID:       span.id,
+		ParentSpanID: r.parent(name),
+		Name:         spanName,
+		Kind:         1,
+		Start:        unixNano(span.start),
+		End:          unixNano(end),
+		Attributes: []otlpAttribute{
+			stringAttribute("ntest.test", name),
+			stringAttribute("ntest.outcome", result),
+		},
+		Status: status,
+	})
+	r.mu.Unlock()
+	r.maybeExport()
+}
+
+// parent finds the innermost open span for name or, failing that, for
+// the closest parent test. It must be called with the lock held.
+func (r *OTLPReporter) parent(name string) string {
+	for {
+		if stack := r.open[name]; len(stack) != 0 {
+			return stack[len(stack)-1].id
+		}
+		i := strings.LastIndexByte(name, '/')
+		if i == -1 {
+			return ""
+		}
+		name = name[:i]
+	}
+}
+
+func (r *OTLPReporter) maybeExport() {
+	r.mu.Lock()
+	if len(r.pending) < OTLPBatchSize {
+		r.mu.Unlock()
+		return
+	}
+	spans := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if err := r.export(spans); err != nil {
+		fmt.Fprintf(os.Stderr, "ntest: export spans: %s\n", err)
+	}
+}
+
+func (r *OTLPReporter) export(spans []otlpSpan) error {
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: otlpResource{
+				Attributes: []otlpAttribute{stringAttribute("service.name", r.service)},
+			},
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/memsql/ntest"},
+				Spans: spans,
+			}},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("post %s: status %d", r.url, resp.StatusCode)
+	}
+	return nil
+}
+
+// OTLP JSON encoding, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID      string          `json:"traceId"`
+	SpanID       string          `json:"spanId"`
+	ParentSpanID string          `json:"parentSpanId,omitempty"`
+	Name         string          `json:"name"`
+	Kind         int             `json:"kind"`
+	Start        string          `json:"startTimeUnixNano"`
+	End          string          `json:"endTimeUnixNano"`
+	Attributes   []otlpAttribute `json:"attributes,omitempty"`
+	Status       otlpStatus      `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: otlpValue{StringValue: value}}
+}
+
+func unixNano(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
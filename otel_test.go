@@ -0,0 +1,72 @@
+package ntest_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestOTLPReporter(t *testing.T) {
+	t.Parallel()
+	type span struct {
+		TraceID      string `json:"traceId"`
+		SpanID       string `json:"spanId"`
+		ParentSpanID string `json:"parentSpanId"`
+		Name         string `json:"name"`
+		Status       struct {
+			Code int `json:"code"`
+		} `json:"status"`
+	}
+	var mu sync.Mutex
+	var spans []span
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		var body struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []span `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, rs := range body.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	reporter := ntest.NewOTLPReporter(server.URL)
+	start := time.Now()
+	reporter.Event(ntest.Event{Time: start, Type: ntest.EventCellStart, Test: "TestM/a"})
+	reporter.TestStarted("TestM/a", start)
+	reporter.Event(ntest.Event{Time: start.Add(time.Millisecond), Type: ntest.EventInjector, Test: "TestM/a", Name: "db", Elapsed: time.Millisecond})
+	reporter.TestFinished(ntest.TestResult{Name: "TestM/a", Start: start, Duration: time.Second, Failed: true})
+	reporter.Event(ntest.Event{Time: start.Add(time.Second), Type: ntest.EventCellEnd, Test: "TestM/a", Outcome: "fail"})
+	require.NoError(t, reporter.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, 3, len(spans))
+	byName := make(map[string]span)
+	for _, s := range spans {
+		byName[s.Name] = s
+		assert.Equal(t, spans[0].TraceID, s.TraceID)
+	}
+	cell, test, injector := byName["cell TestM/a"], byName["test TestM/a"], byName["injector db"]
+	assert.Equal(t, "", cell.ParentSpanID)
+	assert.Equal(t, cell.SpanID, test.ParentSpanID)
+	assert.Equal(t, test.SpanID, injector.ParentSpanID)
+	assert.Equal(t, 2, test.Status.Code)
+}
@@ -0,0 +1,49 @@
+package ntest_test
+
+import (
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestOnPanic(t *testing.T) {
+	defer func(saved ntest.PanicPolicy) { ntest.OnPanic = saved }(ntest.OnPanic)
+
+	ntest.OnPanic = ntest.PanicFail
+	capture := &errorCapturingT{T: t}
+	ntest.RunTest(capture, func() {
+		panic("oops")
+	})
+	require.Equal(t, 1, len(capture.errors))
+	assert.Contains(t, capture.errors[0], "panic in TestOnPanic: oops")
+	assert.Contains(t, capture.errors[0], "panic_test.go")
+
+	ntest.OnPanic = ntest.PanicFailAndRepanic
+	capture = &errorCapturingT{T: t}
+	assert.PanicsWithValue(t, "again", func() {
+		ntest.RunTest(capture, func() {
+			panic("again")
+		})
+	})
+	assert.Equal(t, 1, len(capture.errors))
+}
+
+func TestPanicPropagatesUnchanged(t *testing.T) {
+	var stack string
+	func() {
+		defer func() {
+			r := recover()
+			assert.Equal(t, "unchanged", r)
+			stack = string(debug.Stack())
+		}()
+		ntest.RunTest(t, func() {
+			panic("unchanged")
+		})
+	}()
+	assert.Contains(t, stack, "panic_test.go")
+	assert.NotRegexp(t, `panic\.go:\d+ \+0x[0-9a-f]+\ngithub\.com/memsql/ntest\.runTest`, stack, "not recovered and panicked again by RunTest")
+}
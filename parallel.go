@@ -0,0 +1,101 @@
+package ntest
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Parallel calls Parallel on the *testing.T (or other T with a Parallel
+// method) underneath any wrappers from this package, such as ReplaceLogger
+// and ErrorBudget. If the underlying T does not support Parallel, nothing
+// happens. If the test has already used Setenv (which the testing package
+// does not allow in parallel tests), the test fails with a message naming
+// the wrappers rather than panicking from deep inside them.
+func Parallel(t T) {
+	t.Helper()
+	base := unwrapAll(t)
+	p, ok := base.(interface{ Parallel() })
+	if !ok {
+		return
+	}
+	if err := catchTestingPanic(p.Parallel); err != nil {
+		t.Fatalf("cannot make %s parallel through %s: %s (use Setenv only in tests that do not call Parallel)", t.Name(), wrapperChain(t), err)
+	}
+}
+
+// Setenv calls Setenv on the T underneath any wrappers from this package.
+// If the test is parallel (which the testing package does not allow with
+// Setenv), the test fails with a message naming the wrappers rather than
+// panicking from deep inside them. The wrappers in this package use it
+// for their Setenv method.
+//
+// The previous value is restored when the test finishes, even if the
+// underlying T is not a *testing.T and does not do that itself.
+//
+// Variables set with Setenv are tracked, see EnvIsolation.
+func Setenv(t T, key, value string) {
+	t.Helper()
+	base := unwrapAll(t)
+	previous, wasSet := os.LookupEnv(key)
+	if err := catchTestingPanic(func() { base.Setenv(key, value) }); err != nil {
+		t.Fatalf("cannot Setenv(%s) in %s through %s: %s (parallel tests cannot change the environment)", key, t.Name(), wrapperChain(t), err)
+	}
+	if _, ok := base.(testing.TB); !ok {
+		// Other implementations of T (mocks, for example) may not put the
+		// environment back. If they do, restoring it twice is harmless.
+		t.Cleanup(func() {
+			if wasSet {
+				_ = os.Setenv(key, previous)
+			} else {
+				_ = os.Unsetenv(key)
+			}
+		})
+	}
+	claimEnv(t, key, value)
+}
+
+func (t logWrappedT) Setenv(key, value string)   { Setenv(t, key, value) }
+func (t *reportingT) Setenv(key, value string)   { Setenv(t, key, value) }
+func (t *errorBudgetT) Setenv(key, value string) { Setenv(t, key, value) }
+
+func unwrapAll(t T) T {
+	for {
+		w, ok := t.(wrappedT)
+		if !ok {
+			return t
+		}
+		t = w.unwrap()
+	}
+}
+
+// wrapperChain describes the wrappers around a T, for example
+// "*ntest.reportingT -> ntest.logWrappedT -> *testing.T".
+func wrapperChain(t T) string {
+	var types []string
+	for {
+		types = append(types, fmt.Sprintf("%T", t))
+		w, ok := t.(wrappedT)
+		if !ok {
+			return strings.Join(types, " -> ")
+		}
+		t = w.unwrap()
+	}
+}
+
+// catchTestingPanic converts a panic with a string value (which is how the
+// testing package reports misuse) into an error.
+func catchTestingPanic(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			err = fmt.Errorf("%s", msg)
+		}
+	}()
+	f()
+	return nil
+}
@@ -0,0 +1,54 @@
+package ntest_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestSetenvThenParallel(t *testing.T) {
+	capture := &testingFatalT{T: t}
+	wrapped := ntest.ReplaceLogger(capture, func(string) {})
+	wrapped.Setenv("NTEST_PARALLEL_TEST", "yes")
+	assert.Equal(t, "yes", os.Getenv("NTEST_PARALLEL_TEST"))
+	assert.True(t, catchFatal(func() {
+		ntest.Parallel(wrapped)
+	}))
+	require.Equal(t, 1, len(capture.fatals))
+	assert.Contains(t, capture.fatals[0], "ntest.logWrappedT -> *ntest_test.testingFatalT")
+}
+
+func TestParallelThenSetenv(t *testing.T) {
+	capture := &testingFatalT{T: t}
+	wrapped := ntest.ErrorBudget(capture, 1)
+	ntest.Parallel(wrapped)
+	assert.True(t, catchFatal(func() {
+		wrapped.Setenv("NTEST_PARALLEL_TEST", "no")
+	}))
+	require.Equal(t, 1, len(capture.fatals))
+	assert.Contains(t, capture.fatals[0], "cannot Setenv(NTEST_PARALLEL_TEST)")
+}
+
+func TestSetenvRestores(t *testing.T) {
+	const set, unset = "NTEST_SETENV_RESTORE", "NTEST_SETENV_RESTORE_UNSET"
+	t.Setenv(set, "before")
+	t.Setenv(unset, "")
+	require.NoError(t, os.Unsetenv(unset))
+
+	ct := &cleanupT{T: bareSetenvT{T: t}}
+	wrapped := ntest.ReplaceLogger(ct, func(string) {})
+	wrapped.Setenv(set, "during")
+	wrapped.Setenv(set, "again")
+	wrapped.Setenv(unset, "during")
+	assert.Equal(t, "again", os.Getenv(set))
+	assert.Equal(t, "during", os.Getenv(unset))
+
+	ct.runCleanups()
+	assert.Equal(t, "before", os.Getenv(set))
+	_, ok := os.LookupEnv(unset)
+	assert.False(t, ok, "variables that were not set are unset again")
+}
@@ -0,0 +1,108 @@
+package ntest
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// FixtureModeEnv names the environment variable that selects between
+// the implementations of fixtures registered with RegisterFixture. It
+// is a list of modes in order of preference, like "docker,memory".
+const FixtureModeEnv = "NTEST_FIXTURE_MODE"
+
+var fixturePlugins struct {
+	mu     sync.Mutex
+	byType map[reflect.Type]map[string]reflect.Value
+}
+
+// RegisterFixture registers fixture as the implementation of I for
+// mode, like "memory", "docker", or "cluster". Register implementations
+// from init functions, in files with build tags if they have
+// dependencies that not every build should have:
+//
+//	//go:build docker
+//
+//	func init() {
+//		ntest.RegisterFixture[Store]("docker", func(t ntest.T) Store {
+//			return startStoreContainer(t)
+//		})
+//	}
+//
+// Chains then ask for an I with PluginFixture. Registering a mode twice
+// replaces the earlier implementation.
+func RegisterFixture[I any](mode string, fixture func(T) I) {
+	typ := reflect.TypeOf((*I)(nil)).Elem()
+	fixturePlugins.mu.Lock()
+	defer fixturePlugins.mu.Unlock()
+	if fixturePlugins.byType == nil {
+		fixturePlugins.byType = make(map[reflect.Type]map[string]reflect.Value)
+	}
+	if fixturePlugins.byType[typ] == nil {
+		fixturePlugins.byType[typ] = make(map[string]reflect.Value)
+	}
+	fixturePlugins.byType[typ][mode] = reflect.ValueOf(fixture)
+}
+
+// PluginFixture returns an injector that provides an I using one of the
+// implementations registered with RegisterFixture: the first of the
+// modes listed in $NTEST_FIXTURE_MODE that has one, or the only one if
+// just one is registered (because build tags left out the others). The
+// test fails if there is no such implementation. The same test can then
+// run against, for example, in-memory fakes in unit test runs and real
+// services in CI.
+//
+// The implementation is chosen when the test runs, so PluginFixture can
+// be used to build chains before the implementations register
+// themselves.
+func PluginFixture[I any]() nject.Provider {
+	typ := reflect.TypeOf((*I)(nil)).Elem()
+	return nject.Provide("plugin-"+typ.String(), func(t T) I {
+		t.Helper()
+		mode, fixture, err := selectFixture(typ, fixtureModes())
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		t.Logf("using the %s %s fixture", mode, typ)
+		return fixture.Interface().(func(T) I)(t)
+	})
+}
+
+func fixtureModes() []string {
+	var modes []string
+	for _, mode := range strings.Split(os.Getenv(FixtureModeEnv), ",") {
+		if mode = strings.TrimSpace(mode); mode != "" {
+			modes = append(modes, mode)
+		}
+	}
+	return modes
+}
+
+func selectFixture(typ reflect.Type, modes []string) (string, reflect.Value, error) {
+	fixturePlugins.mu.Lock()
+	defer fixturePlugins.mu.Unlock()
+	registered := fixturePlugins.byType[typ]
+	for _, mode := range modes {
+		if fixture, ok := registered[mode]; ok {
+			return mode, fixture, nil
+		}
+	}
+	available := make([]string, 0, len(registered))
+	for mode, fixture := range registered {
+		if len(registered) == 1 {
+			return mode, fixture, nil
+		}
+		available = append(available, mode)
+	}
+	sort.Strings(available)
+	if len(available) == 0 {
+		return "", reflect.Value{}, fmt.Errorf("no %s fixture is registered (missing build tags?)", typ)
+	}
+	return "", reflect.Value{}, fmt.Errorf("no %s fixture for $%s=%q, choose from: %s",
+		typ, FixtureModeEnv, os.Getenv(FixtureModeEnv), strings.Join(available, ", "))
+}
@@ -0,0 +1,46 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type pluginStore interface{ Mode() string }
+
+type modeStore string
+
+func (s modeStore) Mode() string { return string(s) }
+
+type unregisteredStore interface{ Unregistered() }
+
+func TestPluginFixture(t *testing.T) {
+	ntest.RegisterFixture[pluginStore]("memory", func(ntest.T) pluginStore { return modeStore("memory") })
+	ntest.RegisterFixture[pluginStore]("docker", func(ntest.T) pluginStore { return modeStore("docker") })
+
+	mode := func(t ntest.T, env string) string {
+		t.Setenv(ntest.FixtureModeEnv, env)
+		var got string
+		ntest.RunTest(t, ntest.PluginFixture[pluginStore](), func(s pluginStore) {
+			got = s.Mode()
+		})
+		return got
+	}
+	assert.Equal(t, "docker", mode(t, "cluster,docker,memory"))
+	assert.Equal(t, "memory", mode(t, "memory"))
+
+	fatal := &testingFatalT{T: t}
+	assert.True(t, catchFatal(func() { mode(fatal, "cluster") }))
+	require.Len(t, fatal.fatals, 1)
+	assert.Equal(t, `no ntest_test.pluginStore fixture for $NTEST_FIXTURE_MODE="cluster", choose from: docker, memory`, fatal.fatals[0])
+
+	fatal = &testingFatalT{T: t}
+	assert.True(t, catchFatal(func() {
+		ntest.RunTest(fatal, ntest.PluginFixture[unregisteredStore](), func(unregisteredStore) {})
+	}))
+	require.Len(t, fatal.fatals, 1)
+	assert.Equal(t, "no ntest_test.unregisteredStore fixture is registered (missing build tags?)", fatal.fatals[0])
+}
@@ -0,0 +1,216 @@
+package ntest
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// ProcessOptions controls StartProcess.
+type ProcessOptions struct {
+	// Name is used to prefix output lines. Defaults to the base name
+	// of the command.
+	Name string
+	// ReadyPattern, if set, must match a line of output (stdout or
+	// stderr) before StartProcess returns.
+	ReadyPattern *regexp.Regexp
+	// Ready, if set, is polled with Eventually until it returns nil
+	// before StartProcess returns.
+	Ready func() error
+	// ReadyTimeout limits how long to wait for readiness. Defaults to
+	// DefaultEventuallyTimeout.
+	ReadyTimeout time.Duration
+	// GracePeriod is how long to wait after sending an interrupt before
+	// killing the process during cleanup. If zero, the process is killed
+	// immediately.
+	GracePeriod time.Duration
+	// WaitDelay is how long to keep reading output after the process
+	// exits. Output can stay open when the process has started children
+	// of its own that inherited it; it is closed, and logged, once
+	// WaitDelay has passed. Defaults to DefaultProcessWaitDelay.
+	WaitDelay time.Duration
+}
+
+// DefaultProcessWaitDelay is the default ProcessOptions.WaitDelay.
+const DefaultProcessWaitDelay = 5 * time.Second
+
+// Process is a subprocess started with StartProcess.
+type Process struct {
+	Cmd       *exec.Cmd
+	name      string
+	done      chan struct{}
+	stdout    *processOutput
+	stderr    *processOutput
+	waitDelay time.Duration
+	output    processPipes
+	waitErr   error
+}
+
+// ProcessInjector returns an injector that starts a subprocess and provides
+// it as a *Process. Use Extra if more than one is needed.
+func ProcessInjector(opts ProcessOptions, command string, args ...string) nject.Provider {
+	return nject.Provide("process-"+filepath.Base(command), func(t T) *Process {
+		return StartProcess(t, exec.Command(command, args...), opts)
+	})
+}
+
+// StartProcess starts cmd with its stdout and stderr sent to the test log,
+// one line at a time. It waits for the process to be ready as described by
+// opts. When the test finishes, the process is stopped and waited for.
+//
+// cmd.Stdout and cmd.Stderr must not be set.
+func StartProcess(t T, cmd *exec.Cmd, opts ProcessOptions) *Process {
+	t.Helper()
+	name := opts.Name
+	if name == "" {
+		name = filepath.Base(cmd.Path)
+	}
+	p := &Process{
+		Cmd:  cmd,
+		name: name,
+		done: make(chan struct{}),
+	}
+	ready := make(chan struct{})
+	readyOnce := onceFunc(func() { close(ready) })
+	if opts.ReadyPattern == nil {
+		readyOnce()
+	}
+	p.stdout = &processOutput{t: t, prefix: name + "[stdout]: ", ready: opts.ReadyPattern, readyOnce: readyOnce}
+	p.stderr = &processOutput{t: t, prefix: name + "[stderr]: ", ready: opts.ReadyPattern, readyOnce: readyOnce}
+	p.waitDelay = opts.WaitDelay
+	if p.waitDelay == 0 {
+		p.waitDelay = DefaultProcessWaitDelay
+	}
+	if err := p.connectOutput(); err != nil {
+		t.Fatalf("%s output: %s", name, err)
+	}
+	if err := cmd.Start(); err != nil {
+		p.closeOutput()
+		t.Fatalf("start %s: %s", name, err)
+	}
+	t.Logf("started %s (pid %d)", name, cmd.Process.Pid)
+	p.outputStarted()
+	go func() {
+		err := p.outputDone(t, cmd.Wait())
+		p.stdout.flush()
+		p.stderr.flush()
+		p.waitErr = err
+		close(p.done)
+	}()
+	t.Cleanup(func() {
+		p.stop(t, opts.GracePeriod)
+	})
+
+	readyTimeout := opts.ReadyTimeout
+	if readyTimeout == 0 {
+		readyTimeout = DefaultEventuallyTimeout
+	}
+	timer := time.NewTimer(ScaledTimeout(readyTimeout))
+	defer timer.Stop()
+	select {
+	case <-ready:
+	case <-p.done:
+		t.Fatalf("%s exited before it was ready: %v", name, p.waitErr)
+	case <-timer.C:
+		t.Fatalf("%s did not print a line matching %s within %s", name, opts.ReadyPattern, ScaledTimeout(readyTimeout))
+	}
+	if opts.Ready != nil {
+		if !Eventually(t, func() error {
+			select {
+			case <-p.done:
+				return nil
+			default:
+				return opts.Ready()
+			}
+		}, EventuallyOptions{Timeout: readyTimeout, Description: name + " ready"}) {
+			t.FailNow()
+		}
+		select {
+		case <-p.done:
+			t.Fatalf("%s exited before it was ready: %v", name, p.waitErr)
+		default:
+		}
+	}
+	return p
+}
+
+// processOutput logs what is written to it one line at a time.
+type processOutput struct {
+	t         T
+	prefix    string
+	ready     *regexp.Regexp
+	readyOnce func()
+	partial   []byte
+}
+
+// maxProcessLine is the longest line logged as it is; longer output
+// without a newline is logged in pieces
+const maxProcessLine = 1024 * 1024
+
+func (o *processOutput) Write(b []byte) (int, error) {
+	o.partial = append(o.partial, b...)
+	for {
+		i := bytes.IndexByte(o.partial, '\n')
+		if i == -1 {
+			if len(o.partial) >= maxProcessLine {
+				o.flush()
+			}
+			return len(b), nil
+		}
+		o.line(string(bytes.TrimSuffix(o.partial[:i], []byte{'\r'})))
+		o.partial = o.partial[i+1:]
+	}
+}
+
+// flush logs a last line that does not end with a newline
+func (o *processOutput) flush() {
+	if len(o.partial) != 0 {
+		o.line(string(o.partial))
+		o.partial = nil
+	}
+}
+
+func (o *processOutput) line(line string) {
+	o.t.Logf("%s%s", o.prefix, line)
+	if o.ready != nil && o.ready.MatchString(line) {
+		o.readyOnce()
+	}
+}
+
+// Done is closed when the process has exited.
+func (p *Process) Done() <-chan struct{} {
+	return p.done
+}
+
+// Wait waits for the process to exit and returns the result of
+// exec.Cmd.Wait.
+func (p *Process) Wait() error {
+	<-p.done
+	return p.waitErr
+}
+
+func (p *Process) stop(t T, grace time.Duration) {
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	if grace > 0 {
+		if err := p.Cmd.Process.Signal(os.Interrupt); err == nil {
+			select {
+			case <-p.done:
+				t.Logf("%s stopped", p.name)
+				return
+			case <-time.After(grace):
+			}
+		}
+	}
+	_ = p.Cmd.Process.Kill()
+	<-p.done
+	t.Logf("%s killed", p.name)
+}
@@ -0,0 +1,116 @@
+package ntest_test
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestProcess(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	var mu sync.Mutex
+	var caught []string
+	var process *ntest.Process
+	t.Run("run", func(t *testing.T) {
+		captureT := ntest.ReplaceLogger(t, func(s string) {
+			t.Log("captured:", s)
+			mu.Lock()
+			defer mu.Unlock()
+			caught = append(caught, s)
+		})
+		ntest.RunTest(captureT,
+			ntest.ProcessInjector(ntest.ProcessOptions{
+				Name:         "helper",
+				ReadyPattern: regexp.MustCompile(`^listening$`),
+			}, "sh", "-c", "echo starting; echo oops >&2; echo listening; exec sleep 30"),
+			func(p *ntest.Process) {
+				process = p
+			},
+		)
+	})
+	select {
+	case <-process.Done():
+	default:
+		t.Fatal("process not stopped by cleanup")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	all := strings.Join(caught, "\n")
+	assert.Contains(t, all, "helper[stdout]: starting")
+	assert.Contains(t, all, "helper[stderr]: oops")
+	assert.Contains(t, all, "helper killed")
+}
+
+func TestProcessOutputHeldByChild(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	var mu sync.Mutex
+	var caught []string
+	var process *ntest.Process
+	start := time.Now()
+	t.Run("run", func(t *testing.T) {
+		captureT := ntest.ReplaceLogger(t, func(s string) {
+			t.Log("captured:", s)
+			mu.Lock()
+			defer mu.Unlock()
+			caught = append(caught, s)
+		})
+		ntest.RunTest(captureT,
+			ntest.ProcessInjector(ntest.ProcessOptions{
+				Name:         "helper",
+				ReadyPattern: regexp.MustCompile(`^listening$`),
+				WaitDelay:    100 * time.Millisecond,
+			}, "sh", "-c", "sleep 30 & echo listening; exec sleep 30"),
+			func(p *ntest.Process) {
+				process = p
+			},
+		)
+	})
+	assert.Less(t, time.Since(start), 20*time.Second, "cleanup waited for the child")
+	select {
+	case <-process.Done():
+	default:
+		t.Fatal("process not stopped by cleanup")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	all := strings.Join(caught, "\n")
+	assert.Contains(t, all, "helper killed")
+}
+
+func TestProcessExitedOutputHeldByChild(t *testing.T) {
+	t.Parallel()
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	var mu sync.Mutex
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		mu.Lock()
+		defer mu.Unlock()
+		caught = append(caught, s)
+	})
+	p := ntest.StartProcess(captureT, exec.Command("sh", "-c", "sleep 30 & echo done"), ntest.ProcessOptions{
+		Name:      "helper",
+		WaitDelay: 100 * time.Millisecond,
+	})
+	assert.NoError(t, p.Wait())
+	mu.Lock()
+	defer mu.Unlock()
+	all := strings.Join(caught, "\n")
+	assert.Contains(t, all, "helper[stdout]: done")
+	assert.Contains(t, all, "helper output was still open")
+}
@@ -0,0 +1,33 @@
+//go:build go1.20
+
+package ntest
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// processPipes is not needed when exec.Cmd has WaitDelay
+type processPipes struct{}
+
+func (p *Process) connectOutput() error {
+	p.Cmd.Stdout = p.stdout
+	p.Cmd.Stderr = p.stderr
+	p.Cmd.WaitDelay = ScaledTimeout(p.waitDelay)
+	return nil
+}
+
+func (p *Process) closeOutput() {}
+
+func (p *Process) outputStarted() {}
+
+// outputDone is called after Wait. exec.Cmd has already closed output
+// that was still open after WaitDelay; that is only reported (as
+// ErrWaitDelay) when the process itself succeeded.
+func (p *Process) outputDone(t T, waitErr error) error {
+	if errors.Is(waitErr, exec.ErrWaitDelay) {
+		t.Logf("%s output was still open %s after it exited (perhaps inherited by a child process), closed it", p.name, ScaledTimeout(p.waitDelay))
+		return nil
+	}
+	return waitErr
+}
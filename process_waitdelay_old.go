@@ -0,0 +1,76 @@
+//go:build !go1.20
+
+package ntest
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// processPipes are the pipes for a process's output. Before Go 1.20,
+// exec.Cmd cannot stop waiting for output that a child process has kept
+// open, so the pipes are managed here instead.
+type processPipes struct {
+	readers []*os.File
+	writers []*os.File
+	copying sync.WaitGroup
+}
+
+func (p *Process) connectOutput() error {
+	for i := 0; i < 2; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			p.closeOutput()
+			return err
+		}
+		p.output.readers = append(p.output.readers, r)
+		p.output.writers = append(p.output.writers, w)
+	}
+	p.Cmd.Stdout = p.output.writers[0]
+	p.Cmd.Stderr = p.output.writers[1]
+	return nil
+}
+
+func (p *Process) closeOutput() {
+	for _, f := range append(p.output.readers, p.output.writers...) {
+		_ = f.Close()
+	}
+}
+
+func (p *Process) outputStarted() {
+	// the process has its own copies of the write ends
+	for _, f := range p.output.writers {
+		_ = f.Close()
+	}
+	for i, w := range []*processOutput{p.stdout, p.stderr} {
+		p.output.copying.Add(1)
+		go func(r io.Reader, w io.Writer) {
+			defer p.output.copying.Done()
+			_, _ = io.Copy(w, r)
+		}(p.output.readers[i], w)
+	}
+}
+
+// outputDone is called after Wait. It waits up to WaitDelay for the
+// output to be copied and then closes the pipes.
+func (p *Process) outputDone(t T, waitErr error) error {
+	copied := make(chan struct{})
+	go func() {
+		p.output.copying.Wait()
+		close(copied)
+	}()
+	timer := time.NewTimer(ScaledTimeout(p.waitDelay))
+	defer timer.Stop()
+	select {
+	case <-copied:
+	case <-timer.C:
+		t.Logf("%s output was still open %s after it exited (perhaps inherited by a child process), closed it", p.name, ScaledTimeout(p.waitDelay))
+	}
+	for _, r := range p.output.readers {
+		_ = r.Close()
+	}
+	<-copied
+	return waitErr
+}
@@ -0,0 +1,160 @@
+package ntest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Environment variables for profiles: ProfileOnFailureEnv sets
+// ProfileOnFailure, for example NTEST_PROFILE_ON_FAILURE=heap,allocs, and
+// ProfileSlowEnv sets ProfileSlowTests, for example
+// NTEST_PROFILE_SLOW=30s.
+const (
+	ProfileOnFailureEnv = "NTEST_PROFILE_ON_FAILURE"
+	ProfileSlowEnv      = "NTEST_PROFILE_SLOW"
+)
+
+// BlockProfileRate and MutexProfileFraction are passed to
+// runtime.SetBlockProfileRate and runtime.SetMutexProfileFraction by
+// ProfileSlowTests.
+var (
+	BlockProfileRate     = int(10 * time.Microsecond)
+	MutexProfileFraction = 10
+)
+
+var profiles struct {
+	mu    sync.Mutex
+	names []string
+	slow  time.Duration
+}
+
+// ProfileOnFailure makes RunTest write the named runtime/pprof profiles,
+// like "heap" and "allocs", to the ArtifactDir of tests that fail. The
+// profiles are written after the cleanup functions of the test have run
+// and cover the whole process, so a test that fails because it (or a
+// fixture) ran out of memory can be diagnosed afterwards with go tool
+// pprof. Call it with no names to turn it off.
+func ProfileOnFailure(names ...string) error {
+	for _, name := range names {
+		if pprof.Lookup(name) == nil {
+			return fmt.Errorf("unknown profile %q", name)
+		}
+	}
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	profiles.names = names
+	return nil
+}
+
+func enableProfileOnFailure(value string) error {
+	return ProfileOnFailure(strings.Split(value, ",")...)
+}
+
+// ProfileSlowTests turns on block and mutex profiling (see
+// BlockProfileRate and MutexProfileFraction) and makes RunTest write the
+// block and mutex profiles to the ArtifactDir of tests that take longer
+// than threshold, including their cleanup functions. Tests that are
+// slow because of contention then leave behind the evidence. The
+// profiles cover the whole process since ProfileSlowTests was called,
+// not only the slow test. A threshold of zero turns it off.
+func ProfileSlowTests(threshold time.Duration) {
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+	profiles.slow = threshold
+	if threshold > 0 {
+		runtime.SetBlockProfileRate(BlockProfileRate)
+		runtime.SetMutexProfileFraction(MutexProfileFraction)
+	} else {
+		runtime.SetBlockProfileRate(0)
+		runtime.SetMutexProfileFraction(0)
+	}
+}
+
+func enableProfileSlow(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	ProfileSlowTests(d)
+	return nil
+}
+
+// watchProfiles arranges for the ProfileOnFailure profiles to be
+// written if t fails, and the ProfileSlowTests profiles if it is slow.
+// It must be called before anything registers cleanup functions with t.
+func watchProfiles(t T) {
+	profiles.mu.Lock()
+	names := profiles.names
+	slow := profiles.slow
+	profiles.mu.Unlock()
+	if len(names) == 0 && slow <= 0 {
+		return
+	}
+	start := time.Now()
+	t.Cleanup(func() {
+		var write []string
+		if t.Failed() {
+			write = append(write, names...)
+		}
+		if slow > 0 && time.Since(start) > slow {
+			t.Logf("%s took longer than %s", t.Name(), slow)
+			for _, name := range []string{"block", "mutex"} {
+				if !containsString(write, name) {
+					write = append(write, name)
+				}
+			}
+		}
+		if len(write) != 0 {
+			writeProfiles(t, write)
+		}
+	})
+}
+
+// writeProfiles writes the named profiles to the ArtifactDir of t as
+// name.pprof.
+func writeProfiles(t T, names []string) {
+	t.Helper()
+	// the heap profile is as of the most recent garbage collection
+	runtime.GC()
+	// Fatalf would abort the test for the sake of a diagnostic
+	dir, err := artifactDir(t)
+	if err != nil {
+		t.Logf("could not write profiles: %s", err)
+		return
+	}
+	for _, name := range names {
+		path := filepath.Join(dir, name+".pprof")
+		if err := writeProfile(name, path); err != nil {
+			t.Logf("could not write %s profile: %s", name, err)
+			continue
+		}
+		t.Logf("%s profile written to %s", name, path)
+	}
+}
+
+func writeProfile(name, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(name).WriteTo(f, 0); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
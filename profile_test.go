@@ -0,0 +1,60 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestProfileOnFailure(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	assert.EqualError(t, ntest.ProfileOnFailure("heap", "nope"), `unknown profile "nope"`)
+	require.NoError(t, ntest.ProfileOnFailure("heap", "allocs"))
+	defer func() { _ = ntest.ProfileOnFailure() }()
+
+	ct := &cleanupT{T: t}
+	ntest.RunTest(failedT{ct}, func() {})
+	ct.runCleanups()
+	for _, name := range []string{"heap.pprof", "allocs.pprof"} {
+		info, err := os.Stat(filepath.Join(root, "TestProfileOnFailure", name))
+		if assert.NoError(t, err, name) {
+			assert.NotZero(t, info.Size(), name)
+		}
+	}
+
+	require.NoError(t, os.RemoveAll(filepath.Join(root, "TestProfileOnFailure")))
+	ct = &cleanupT{T: t}
+	ntest.RunTest(ct, func() {})
+	ct.runCleanups()
+	_, err := os.Stat(filepath.Join(root, "TestProfileOnFailure", "heap.pprof"))
+	assert.True(t, os.IsNotExist(err), "not written for tests that pass")
+}
+
+func TestProfileSlowTests(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	ntest.ProfileSlowTests(5 * time.Millisecond)
+	defer ntest.ProfileSlowTests(0)
+
+	for name, sleep := range map[string]time.Duration{"slow": 20 * time.Millisecond, "fast": 0} {
+		sleep := sleep
+		t.Run(name, func(t *testing.T) {
+			ntest.RunTest(t, func() {
+				time.Sleep(sleep)
+			})
+		})
+	}
+	for _, name := range []string{"block.pprof", "mutex.pprof"} {
+		_, err := os.Stat(filepath.Join(root, "TestProfileSlowTests", "slow", name))
+		assert.NoError(t, err, name)
+		_, err = os.Stat(filepath.Join(root, "TestProfileSlowTests", "fast", name))
+		assert.True(t, os.IsNotExist(err), name)
+	}
+}
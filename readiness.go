@@ -0,0 +1,106 @@
+package ntest
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// Readiness can be implemented by fixtures that take time to become
+// usable after they are created, like a container that is still
+// starting. Ready should return nil once the fixture can be used.
+type Readiness interface {
+	Ready(ctx context.Context) error
+}
+
+// WaitForReadiness makes RunTest wait, with WaitForReady, for the
+// injected fixtures that implement Readiness before calling the final
+// function. Only fixtures that are used by the final function (directly
+// or through other injectors after them) are waited for.
+//
+// Only fixtures provided before the last element of the chain are
+// found, so a Sequence that ends with the final function should not
+// also provide the fixtures.
+var WaitForReadiness = true
+
+var readinessType = reflect.TypeOf((*Readiness)(nil)).Elem()
+
+// readinessWaiter returns a provider, to be placed just before the last
+// element of chain, that waits for the Readiness fixtures provided by the
+// rest of chain. It returns nil if there are none.
+func readinessWaiter(t T, chain []interface{}) nject.Provider {
+	if !WaitForReadiness || len(chain) < 2 {
+		return nil
+	}
+	types := chainOutputs(chain[:len(chain)-1], func(typ reflect.Type) bool {
+		return typ.Implements(readinessType)
+	})
+	if len(types) == 0 {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var probes []Probe
+	injectors := make([]interface{}, 0, len(types)+1)
+	for _, typ := range types {
+		typ := typ
+		injectors = append(injectors, passThrough("ready-"+typ.String(), typ, func(v reflect.Value) {
+			mu.Lock()
+			probes = append(probes, Probe{
+				Name:  typ.String(),
+				Check: v.Interface().(Readiness).Ready,
+			})
+			mu.Unlock()
+		}))
+	}
+	injectors = append(injectors, nject.Provide("wait-for-readiness", func() {
+		mu.Lock()
+		waitFor := probes
+		mu.Unlock()
+		if len(waitFor) != 0 {
+			WaitForReady(t, waitFor...)
+		}
+	}))
+	return nject.Sequence("readiness", injectors...)
+}
+
+// chainOutputs returns the types, provided by chain, that match.
+func chainOutputs(chain []interface{}, match func(reflect.Type) bool) []reflect.Type {
+	var types []reflect.Type
+	seen := make(map[reflect.Type]bool)
+	nject.Sequence("outputs", chain...).ForEachProvider(func(p nject.Provider) {
+		_, outputs := p.DownFlows()
+		for _, out := range outputs {
+			if !seen[out] && match(out) {
+				seen[out] = true
+				types = append(types, out)
+			}
+		}
+	})
+	return types
+}
+
+// passThrough returns a provider that takes a typ and provides it
+// unchanged, calling f with it first unless it is nil. Passing the value
+// through means that it is only included if something after it uses
+// the value.
+func passThrough(name string, typ reflect.Type, f func(reflect.Value)) nject.Provider {
+	fn := reflect.MakeFunc(reflect.FuncOf([]reflect.Type{typ}, []reflect.Type{typ}, false),
+		func(args []reflect.Value) []reflect.Value {
+			if v := args[0]; !isNilValue(v) {
+				f(v)
+			}
+			return args
+		})
+	return nject.Provide(name, fn.Interface())
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
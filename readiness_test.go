@@ -0,0 +1,53 @@
+package ntest_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+type startingFixture struct {
+	checks int32
+}
+
+func (f *startingFixture) Ready(context.Context) error {
+	if atomic.AddInt32(&f.checks, 1) < 3 {
+		return errors.New("still starting")
+	}
+	return nil
+}
+
+type neverReadyFixture struct{}
+
+func (neverReadyFixture) Ready(context.Context) error { return errors.New("never ready") }
+
+func TestWaitForReadiness(t *testing.T) {
+	var fixture *startingFixture
+	ntest.RunTest(t,
+		func() *startingFixture {
+			fixture = &startingFixture{}
+			return fixture
+		},
+		func() neverReadyFixture { return neverReadyFixture{} },
+		func(f *startingFixture) {
+			assert.Equal(t, int32(3), atomic.LoadInt32(&f.checks), "ready checks before the test")
+		},
+	)
+	assert.NotNil(t, fixture)
+}
+
+func TestWaitForReadinessDisabled(t *testing.T) {
+	ntest.WaitForReadiness = false
+	defer func() { ntest.WaitForReadiness = true }()
+	ntest.RunTest(t,
+		func() *startingFixture { return &startingFixture{} },
+		func(f *startingFixture) {
+			assert.Equal(t, int32(0), atomic.LoadInt32(&f.checks))
+		},
+	)
+}
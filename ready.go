@@ -0,0 +1,136 @@
+package ntest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Probe checks whether a service is ready.
+type Probe struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// Timing for WaitForReady
+var (
+	DefaultReadyTimeout    = 2 * time.Minute
+	ReadyInitialBackoff    = 50 * time.Millisecond
+	ReadyMaxBackoff        = 2 * time.Second
+	ReadyPerAttemptTimeout = 5 * time.Second
+)
+
+// TCPProbe is ready when a TCP connection to addr can be established.
+func TCPProbe(addr string) Probe {
+	return Probe{
+		Name: "tcp " + addr,
+		Check: func(ctx context.Context) error {
+			var dialer net.Dialer
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		},
+	}
+}
+
+// HTTPProbe is ready when a GET of url returns a status below 400.
+func HTTPProbe(url string) Probe {
+	return Probe{
+		Name: "http " + url,
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return err
+			}
+			_ = resp.Body.Close()
+			if resp.StatusCode >= 400 {
+				return fmt.Errorf("status %d", resp.StatusCode)
+			}
+			return nil
+		},
+	}
+}
+
+// SQLProbe is ready when db can be pinged.
+func SQLProbe(name string, db *sql.DB) Probe {
+	return Probe{
+		Name:  "sql " + name,
+		Check: db.PingContext,
+	}
+}
+
+// WaitForReady waits until every probe succeeds. Probes are checked
+// concurrently with exponential backoff between attempts. Progress is
+// logged as probes become ready or their errors change. If the probes are
+// not all ready by DefaultReadyTimeout (or shortly before the test's
+// deadline, whichever is sooner), the test fails immediately with the last
+// error from each probe that is not ready.
+func WaitForReady(t T, probes ...Probe) {
+	t.Helper()
+	start := time.Now()
+	deadline := eventuallyDeadline(t, start, EventuallyOptions{Timeout: DefaultReadyTimeout})
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+
+	var mu sync.Mutex
+	lastErrors := make(map[string]error)
+	var wg sync.WaitGroup
+	for _, probe := range probes {
+		probe := probe
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			backoff := ReadyInitialBackoff
+			var last string
+			for attempt := 1; ; attempt++ {
+				attemptCtx, attemptCancel := context.WithTimeout(ctx, ScaledTimeout(ReadyPerAttemptTimeout))
+				err := probe.Check(attemptCtx)
+				attemptCancel()
+				mu.Lock()
+				lastErrors[probe.Name] = err
+				mu.Unlock()
+				if err == nil {
+					t.Logf("%s is ready after %d attempts (%s)", probe.Name, attempt, time.Since(start).Round(time.Millisecond))
+					return
+				}
+				if err.Error() != last {
+					last = err.Error()
+					t.Logf("waiting for %s (attempt %d): %s", probe.Name, attempt, last)
+				}
+				timer := time.NewTimer(backoff)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+				backoff *= 2
+				if backoff > ReadyMaxBackoff {
+					backoff = ReadyMaxBackoff
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	var notReady []string
+	for _, probe := range probes {
+		if err := lastErrors[probe.Name]; err != nil {
+			notReady = append(notReady, probe.Name+": "+err.Error())
+		}
+	}
+	if len(notReady) != 0 {
+		t.Fatalf("services not ready after %s:\n\t%s", time.Since(start).Round(time.Millisecond), strings.Join(notReady, "\n\t"))
+	}
+}
@@ -0,0 +1,41 @@
+package ntest_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestWaitForReady(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(server.Close)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = listener.Close() })
+
+	becomesReady := time.Now().Add(100 * time.Millisecond)
+	ntest.WaitForReady(t,
+		ntest.HTTPProbe(server.URL),
+		ntest.TCPProbe(listener.Addr().String()),
+		ntest.Probe{
+			Name: "slow",
+			Check: func(context.Context) error {
+				if time.Now().Before(becomesReady) {
+					return errors.New("warming up")
+				}
+				return nil
+			},
+		},
+	)
+	assert.False(t, time.Now().Before(becomesReady))
+}
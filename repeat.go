@@ -0,0 +1,104 @@
+package ntest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/muir/nject"
+)
+
+// RepeatResults is the outcome of RunRepeated.
+type RepeatResults struct {
+	Runs int
+	// Failed lists the runs that failed, counting from 1.
+	Failed []int
+}
+
+// FailureRate is the fraction of the runs that failed.
+func (r RepeatResults) FailureRate() float64 {
+	if r.Runs == 0 {
+		return 0
+	}
+	return float64(len(r.Failed)) / float64(r.Runs)
+}
+
+func (r RepeatResults) String() string {
+	s := fmt.Sprintf("%d of %d runs failed (%.1f%%)", len(r.Failed), r.Runs, 100*r.FailureRate())
+	if len(r.Failed) != 0 {
+		runs := make([]string, len(r.Failed))
+		for i, run := range r.Failed {
+			runs[i] = strconv.Itoa(run)
+		}
+		s += ": " + strings.Join(runs, ", ")
+	}
+	return s
+}
+
+// RunRepeated runs chain n times, one after the other, each in its own
+// subtest (named "run-001" and so on) with RunTest, so that every run
+// gets fresh fixtures. It is a way to shake out flaky tests that, unlike
+// go test -count, reports how often the test failed and which runs did.
+// Lines logged through the injected T by runs that pass are dropped,
+// so that the output of a hundred runs is the output of the runs that
+// failed.
+//
+//	func TestFlaky(t *testing.T) {
+//		ntest.RunRepeated(t, 100, dbFixture, func(t ntest.T, db *sql.DB) { ... })
+//	}
+func RunRepeated(t *testing.T, n int, chain ...interface{}) RepeatResults {
+	results := RepeatResults{Runs: n}
+	width := len(strconv.Itoa(n))
+	for i := 1; i <= n; i++ {
+		passed := t.Run(fmt.Sprintf("run-%0*d", width, i), func(t *testing.T) {
+			RunTest(bufferLogs(t), combineSlices(
+				[]interface{}{nject.Provide("testing.T", func() *testing.T { return t })},
+				chain)...)
+		})
+		if !passed {
+			results.Failed = append(results.Failed, i)
+		}
+	}
+	t.Logf("%s: %s", t.Name(), results)
+	return results
+}
+
+// bufferedLogT keeps the lines that are logged to it and only passes
+// them on if the test fails.
+type bufferedLogT struct {
+	T
+	mu    sync.Mutex
+	lines []string
+}
+
+func bufferLogs(t T) T {
+	bt := &bufferedLogT{T: t}
+	t.Cleanup(func() {
+		bt.mu.Lock()
+		lines := bt.lines
+		bt.lines = nil
+		bt.mu.Unlock()
+		if t.Failed() && len(lines) != 0 {
+			t.Logf("log of %s:\n%s", t.Name(), strings.Join(lines, "\n"))
+		}
+	})
+	return bt
+}
+
+func (t *bufferedLogT) unwrap() T { return t.T }
+
+func (t *bufferedLogT) Log(args ...interface{}) {
+	t.log(sprintln(args...))
+}
+
+func (t *bufferedLogT) Logf(format string, args ...interface{}) {
+	t.log(fmt.Sprintf(format, args...))
+}
+
+func (t *bufferedLogT) log(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+}
@@ -0,0 +1,64 @@
+package ntest_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestRunRepeated(t *testing.T) {
+	var runs int
+	var logged []string
+	results := ntest.RunRepeated(t, 3, func(t ntest.T, tt *testing.T) {
+		runs++
+		t.Logf("run %d", runs)
+		assert.True(t, strings.HasPrefix(tt.Name(), "TestRunRepeated/run-"), tt.Name())
+		logged = append(logged, tt.Name())
+	})
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, []string{"TestRunRepeated/run-1", "TestRunRepeated/run-2", "TestRunRepeated/run-3"}, logged)
+	assert.Equal(t, ntest.RepeatResults{Runs: 3}, results)
+	assert.Equal(t, "0 of 3 runs failed (0.0%)", results.String())
+}
+
+func TestRunRepeatedFailures(t *testing.T) {
+	if os.Getenv("NTEST_TEST_REPEATED_FAILURES") != "" {
+		// the flaky test, run by the test binary below
+		var runs int
+		ntest.RunRepeated(t, 10, func(t ntest.T) {
+			runs++
+			t.Logf("attempt %d", runs)
+			if runs%4 == 0 {
+				t.Errorf("flaked")
+			}
+		})
+		return
+	}
+	// run in a process of its own, whose failures do not fail this
+	// test, and once however -test.count is set
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunRepeatedFailures$", "-test.count=1", "-test.v")
+	cmd.Env = append(os.Environ(), "NTEST_TEST_REPEATED_FAILURES=true")
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	require.True(t, errors.As(err, &exitErr), "%v\n%s", err, out)
+	output := string(out)
+	assert.Contains(t, output, "TestRunRepeatedFailures: 2 of 10 runs failed (20.0%): 4, 8")
+	assert.Contains(t, output, "--- FAIL: TestRunRepeatedFailures/run-04")
+	assert.Contains(t, output, "attempt 8")
+	assert.NotContains(t, output, "attempt 7", "logs of runs that passed are dropped")
+}
+
+func TestRepeatResults(t *testing.T) {
+	t.Parallel()
+	results := ntest.RepeatResults{Runs: 10, Failed: []int{4, 8}}
+	assert.InDelta(t, 0.2, results.FailureRate(), 0.001)
+	assert.Equal(t, "2 of 10 runs failed (20.0%): 4, 8", results.String())
+	assert.Equal(t, 0.0, ntest.RepeatResults{}.FailureRate())
+}
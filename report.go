@@ -0,0 +1,299 @@
+package ntest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Reporter receives the results of every RunTest, including each cell
+// of a matrix test. Reporters are registered with AddReporter. Methods
+// may be called concurrently.
+type Reporter interface {
+	TestStarted(name string, start time.Time)
+	TestFinished(result TestResult)
+	// Close is called by Main after all tests have run.
+	Close() error
+}
+
+// FailureAnnotator can be implemented by a Reporter to add a note to the
+// log of a failed test. AnnotateFailure is called before TestFinished;
+// an empty note is ignored.
+type FailureAnnotator interface {
+	AnnotateFailure(name string) string
+}
+
+// TestResult describes the outcome of a test run with RunTest.
+//
+// Messages and Log only include what was written through the T that
+// RunTest injects (ntest.T), not writes directly to a *testing.T.
+type TestResult struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Failed   bool
+	Skipped  bool
+	// Messages are from Error, Errorf, Fatal, Fatalf, Skip, and Skipf.
+	Messages []string
+	// Failures are the messages from Error, Errorf, Fatal, and Fatalf
+	// along with where they were reported.
+	Failures []TestFailure
+	// Log holds the most recent log lines, up to MaxReportLogLines.
+	Log []string
+	// ArtifactDir is set if the test used ArtifactDir.
+	ArtifactDir string
+}
+
+// TestFailure is a failure message and the source location of the test
+// code that reported it. Frames in the testing, testify, and ntest
+// packages are skipped when finding the location.
+type TestFailure struct {
+	Message string
+	File    string
+	Line    int
+}
+
+// MaxReportLogLines limits how many log lines are kept for each TestResult.
+var MaxReportLogLines = 200
+
+var (
+	reportersLock sync.Mutex
+	reporters     []*registeredReporter
+)
+
+// registeredReporter gives each call to AddReporter an identity, since
+// a Reporter may not be comparable
+type registeredReporter struct {
+	Reporter
+}
+
+// AddReporter registers a Reporter for all subsequent tests. It returns
+// a function that unregisters it (without closing it), for example in
+// t.Cleanup of a test that only wants to report on itself.
+func AddReporter(r Reporter) (remove func()) {
+	reportersLock.Lock()
+	defer reportersLock.Unlock()
+	registered := &registeredReporter{Reporter: r}
+	reporters = append(reporters, registered)
+	return func() {
+		reportersLock.Lock()
+		defer reportersLock.Unlock()
+		kept := make([]*registeredReporter, 0, len(reporters))
+		for _, other := range reporters {
+			if other != registered {
+				kept = append(kept, other)
+			}
+		}
+		reporters = kept
+	}
+}
+
+func currentReporters() []Reporter {
+	reportersLock.Lock()
+	defer reportersLock.Unlock()
+	if len(reporters) == 0 {
+		return nil
+	}
+	active := make([]Reporter, len(reporters))
+	for i, r := range reporters {
+		active[i] = r.Reporter
+	}
+	return active
+}
+
+var mainRunning int32
+
+// usingMain reports whether the tests are being run by Main, which
+// closes the reporters when they are done.
+func usingMain() bool {
+	return atomic.LoadInt32(&mainRunning) != 0
+}
+
+// Main runs the tests and then closes all reporters so that they can
+// write their final output. Use it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(ntest.Main(m))
+//	}
+func Main(m *testing.M) int {
+	atomic.StoreInt32(&mainRunning, 1)
+	code := m.Run()
+	reportersLock.Lock()
+	closing := reporters
+	reporters = nil
+	reportersLock.Unlock()
+	for _, r := range closing {
+		if err := r.Close(); err != nil {
+			fmt.Printf("ntest: close reporter: %s\n", err)
+			if code == 0 {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+type reportingT struct {
+	T
+	mu     sync.Mutex
+	result TestResult
+}
+
+// startReport begins recording a test for the registered reporters. If
+// there are no reporters, t is returned unchanged. Otherwise a wrapped T
+// that records messages and log lines is returned.
+func startReport(t T) T {
+	active := currentReporters()
+	if len(active) == 0 {
+		return t
+	}
+	rt := &reportingT{
+		T: t,
+		result: TestResult{
+			Name:  t.Name(),
+			Start: time.Now(),
+		},
+	}
+	for _, r := range active {
+		r.TestStarted(rt.result.Name, rt.result.Start)
+	}
+	t.Cleanup(func() {
+		rt.mu.Lock()
+		result := rt.result
+		rt.mu.Unlock()
+		result.Duration = time.Since(result.Start)
+		result.Failed = t.Failed()
+		result.Skipped = t.Skipped()
+		result.ArtifactDir = lookupArtifactDir(result.Name)
+		if result.Failed {
+			for _, r := range active {
+				if annotator, ok := r.(FailureAnnotator); ok {
+					if note := annotator.AnnotateFailure(result.Name); note != "" {
+						t.Log(note)
+						result.Log = append(result.Log, note)
+					}
+				}
+			}
+		}
+		for _, r := range active {
+			r.TestFinished(result)
+		}
+	})
+	return rt
+}
+
+func (t *reportingT) log(line string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Log = append(t.result.Log, line)
+	if over := len(t.result.Log) - MaxReportLogLines; over > 0 {
+		t.result.Log = t.result.Log[over:]
+	}
+}
+
+func (t *reportingT) message(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Messages = append(t.result.Messages, msg)
+}
+
+func (t *reportingT) failure(msg string) {
+	file, line := failureLocation()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result.Messages = append(t.result.Messages, msg)
+	t.result.Failures = append(t.result.Failures, TestFailure{
+		Message: msg,
+		File:    file,
+		Line:    line,
+	})
+}
+
+// failureLocation returns the first frame on the stack that is not in
+// the testing, testify, or ntest packages.
+func failureLocation() (string, int) {
+	frame, _ := callerFrame()
+	return frame.File, frame.Line
+}
+
+// callerFrame returns the first frame on the stack that is not in the
+// testing, testify, nject, or ntest packages.
+func callerFrame() (runtime.Frame, bool) {
+	pcs := make([]uintptr, 50)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, "testing.") &&
+			!strings.HasPrefix(frame.Function, "github.com/stretchr/testify/") &&
+			!strings.HasPrefix(frame.Function, "github.com/memsql/ntest.") &&
+			!strings.HasPrefix(frame.Function, "github.com/muir/nject") &&
+			!strings.HasPrefix(frame.Function, "reflect.") &&
+			!strings.HasPrefix(frame.Function, "runtime.") {
+			return frame, true
+		}
+		if !more {
+			return runtime.Frame{}, false
+		}
+	}
+}
+
+func (t *reportingT) Log(args ...interface{}) {
+	t.T.Helper()
+	t.log(sprintln(args...))
+	t.T.Log(args...)
+}
+
+func (t *reportingT) Logf(format string, args ...interface{}) {
+	t.T.Helper()
+	t.log(fmt.Sprintf(format, args...))
+	t.T.Logf(format, args...)
+}
+
+func (t *reportingT) Error(args ...interface{}) {
+	t.T.Helper()
+	t.failure(sprintln(args...))
+	t.T.Error(args...)
+}
+
+func (t *reportingT) Errorf(format string, args ...interface{}) {
+	t.T.Helper()
+	t.failure(fmt.Sprintf(format, args...))
+	t.T.Errorf(format, args...)
+}
+
+func (t *reportingT) Fatal(args ...interface{}) {
+	t.T.Helper()
+	t.failure(sprintln(args...))
+	t.T.Fatal(args...)
+}
+
+func (t *reportingT) Fatalf(format string, args ...interface{}) {
+	t.T.Helper()
+	t.failure(fmt.Sprintf(format, args...))
+	t.T.Fatalf(format, args...)
+}
+
+func (t *reportingT) Skip(args ...interface{}) {
+	t.T.Helper()
+	t.message(sprintln(args...))
+	recordCellSkip(t.Name(), sprintln(args...))
+	t.T.Skip(args...)
+}
+
+func (t *reportingT) Skipf(format string, args ...interface{}) {
+	t.T.Helper()
+	t.message(fmt.Sprintf(format, args...))
+	recordCellSkip(t.Name(), fmt.Sprintf(format, args...))
+	t.T.Skipf(format, args...)
+}
+
+// sprintln formats like fmt.Sprintln (which is how testing.T formats
+// Log and Error) but without the trailing newline.
+func sprintln(args ...interface{}) string {
+	line := fmt.Sprintln(args...)
+	return line[:len(line)-1]
+}
@@ -0,0 +1,162 @@
+package ntest_test
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// recordingReporter keeps results for tests whose names start with prefix.
+// Reporters are process-wide so other tests' results are ignored.
+type recordingReporter struct {
+	prefix  string
+	mu      sync.Mutex
+	started []string
+	results []ntest.TestResult
+}
+
+func (r *recordingReporter) TestStarted(name string, _ time.Time) {
+	if !strings.HasPrefix(name, r.prefix) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.started = append(r.started, name)
+}
+
+func (r *recordingReporter) TestFinished(result ntest.TestResult) {
+	if !strings.HasPrefix(result.Name, r.prefix) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.results = append(r.results, result)
+}
+
+func (r *recordingReporter) Close() error { return nil }
+
+func TestReporter(t *testing.T) {
+	t.Parallel()
+	reporter := &recordingReporter{prefix: t.Name() + "/"}
+	t.Cleanup(ntest.AddReporter(reporter))
+	t.Run("pass", func(t *testing.T) {
+		ntest.RunTest(t, func(t ntest.T) {
+			t.Log("hello", 7)
+		})
+	})
+	t.Run("skip", func(t *testing.T) {
+		ntest.RunTest(t, func(t ntest.T) {
+			t.Skip("not today")
+		})
+	})
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	assert.Equal(t, []string{"TestReporter/pass", "TestReporter/skip"}, reporter.started)
+	require.Equal(t, 2, len(reporter.results))
+	assert.Equal(t, []string{"hello 7"}, reporter.results[0].Log)
+	assert.False(t, reporter.results[0].Skipped)
+	assert.True(t, reporter.results[1].Skipped)
+	assert.Equal(t, []string{"not today"}, reporter.results[1].Messages)
+}
+
+func TestJUnitReporter(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "report.xml")
+	reporter := ntest.NewJUnitReporter(path)
+	start := time.Now()
+	reporter.TestFinished(ntest.TestResult{Name: "TestA/cell", Start: start, Duration: time.Second})
+	reporter.TestFinished(ntest.TestResult{
+		Name:     "TestB",
+		Start:    start.Add(time.Millisecond),
+		Failed:   true,
+		Messages: []string{"expected 1\ngot 2"},
+		Log:      []string{"connecting", "connected"},
+	})
+	require.NoError(t, reporter.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var parsed struct {
+		Suites []struct {
+			Tests    int `xml:"tests,attr"`
+			Failures int `xml:"failures,attr"`
+			Cases    []struct {
+				Name      string `xml:"name,attr"`
+				Classname string `xml:"classname,attr"`
+				Failure   *struct {
+					Message string `xml:"message,attr"`
+				} `xml:"failure"`
+				SystemOut string `xml:"system-out"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	require.NoError(t, xml.Unmarshal(data, &parsed))
+	require.Equal(t, 1, len(parsed.Suites))
+	suite := parsed.Suites[0]
+	assert.Equal(t, 2, suite.Tests)
+	assert.Equal(t, 1, suite.Failures)
+	assert.True(t, strings.HasSuffix(suite.Cases[0].Classname, ".TestA"), suite.Cases[0].Classname)
+	assert.Nil(t, suite.Cases[0].Failure)
+	if assert.NotNil(t, suite.Cases[1].Failure) {
+		assert.Equal(t, "expected 1", suite.Cases[1].Failure.Message)
+	}
+	assert.Equal(t, "connecting\nconnected", suite.Cases[1].SystemOut)
+}
+
+func TestReporterFailureLocation(t *testing.T) {
+	t.Parallel()
+	reporter := &recordingReporter{prefix: t.Name() + "/"}
+	t.Cleanup(ntest.AddReporter(reporter))
+	var line int
+	t.Run("fail", func(t *testing.T) {
+		ntest.RunTest(&errorCapturingT{T: t}, func(t ntest.T) {
+			_, _, line, _ = runtime.Caller(0)
+			assert.Equal(t, 1, 2)
+		})
+	})
+	reporter.mu.Lock()
+	defer reporter.mu.Unlock()
+	require.Equal(t, 1, len(reporter.results))
+	require.Equal(t, 1, len(reporter.results[0].Failures))
+	failure := reporter.results[0].Failures[0]
+	assert.Equal(t, "report_test.go", filepath.Base(failure.File))
+	assert.Equal(t, line+1, failure.Line)
+}
+
+func TestJUnitReporterWritesPeriodically(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "report.xml")
+	reporter := ntest.NewJUnitReporter(path)
+	countTests := func() int {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var parsed struct {
+			Suites []struct {
+				Tests int `xml:"tests,attr"`
+			} `xml:"testsuite"`
+		}
+		require.NoError(t, xml.Unmarshal(data, &parsed))
+		require.Equal(t, 1, len(parsed.Suites))
+		return parsed.Suites[0].Tests
+	}
+	// this test binary does not use ntest.Main so the first result is
+	// written right away and later ones are batched
+	reporter.TestFinished(ntest.TestResult{Name: "TestA", Start: time.Now()})
+	assert.Equal(t, 1, countTests())
+	reporter.TestFinished(ntest.TestResult{Name: "TestB", Start: time.Now()})
+	assert.Equal(t, 1, countTests())
+	assert.Eventually(t, func() bool { return countTests() == 2 }, 10*time.Second, 100*time.Millisecond)
+	reporter.TestFinished(ntest.TestResult{Name: "TestC", Start: time.Now()})
+	require.NoError(t, reporter.Close())
+	assert.Equal(t, 3, countTests())
+}
@@ -0,0 +1,125 @@
+package ntest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// ResourceBudget sets limits on what a single test may consume. Zero
+// values are not checked.
+//
+// Allocation counts come from runtime.MemStats and peak RSS from the
+// operating system; both are process-wide, so measurements of tests
+// that run in parallel with other tests include the other tests' usage.
+type ResourceBudget struct {
+	WallTime   time.Duration
+	Allocs     uint64 // number of heap allocations
+	AllocBytes uint64 // cumulative bytes allocated
+	// PeakRSSGrowth limits how much the process's peak resident set size
+	// may grow during the test. It is not measured on all platforms.
+	PeakRSSGrowth uint64
+	// WarnOnly logs budget violations instead of failing the test.
+	WarnOnly bool
+	// HeapProfile writes a heap profile to the ArtifactDir of the test
+	// when a memory limit (Allocs, AllocBytes, or PeakRSSGrowth) is
+	// exceeded.
+	HeapProfile bool
+}
+
+// ResourceUsage is what a test consumed.
+type ResourceUsage struct {
+	WallTime      time.Duration
+	Allocs        uint64
+	AllocBytes    uint64
+	PeakRSSGrowth uint64
+}
+
+// ResourceGuard returns an injector that applies GuardResources.
+func ResourceGuard(budget ResourceBudget) nject.Provider {
+	return nject.Required(nject.Provide("resource-guard", func(t T) {
+		GuardResources(t, budget)
+	}))
+}
+
+// GuardResources measures the resources used from now until the end of
+// the test and fails the test (or warns if budget.WarnOnly) if any of
+// the budget's limits is exceeded.
+func GuardResources(t T, budget ResourceBudget) {
+	start := sampleResources()
+	t.Cleanup(func() {
+		usage := sampleResources().since(start)
+		var exceeded []string
+		check := func(name string, limit, used uint64, format func(uint64) string) bool {
+			if limit != 0 && used > limit {
+				exceeded = append(exceeded, fmt.Sprintf("%s %s > %s", name, format(used), format(limit)))
+				return true
+			}
+			return false
+		}
+		count := func(n uint64) string { return fmt.Sprint(n) }
+		duration := func(n uint64) string { return time.Duration(n).Round(time.Millisecond).String() }
+		check("wall time", uint64(budget.WallTime), uint64(usage.WallTime), duration)
+		memory := check("allocations", budget.Allocs, usage.Allocs, count)
+		memory = check("allocated bytes", budget.AllocBytes, usage.AllocBytes, formatBytes) || memory
+		memory = check("peak RSS growth", budget.PeakRSSGrowth, usage.PeakRSSGrowth, formatBytes) || memory
+		if len(exceeded) == 0 {
+			return
+		}
+		msg := "resource budget exceeded: " + strings.Join(exceeded, ", ")
+		if budget.WarnOnly {
+			t.Log("warning: " + msg)
+		} else {
+			t.Error(msg)
+		}
+		if memory && budget.HeapProfile {
+			writeProfiles(t, []string{"heap"})
+		}
+	})
+}
+
+type resourceSample struct {
+	time       time.Time
+	allocs     uint64
+	allocBytes uint64
+	peakRSS    uint64
+}
+
+func sampleResources() resourceSample {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return resourceSample{
+		time:       time.Now(),
+		allocs:     ms.Mallocs,
+		allocBytes: ms.TotalAlloc,
+		peakRSS:    peakRSS(),
+	}
+}
+
+func (end resourceSample) since(start resourceSample) ResourceUsage {
+	usage := ResourceUsage{
+		WallTime:   end.time.Sub(start.time),
+		Allocs:     end.allocs - start.allocs,
+		AllocBytes: end.allocBytes - start.allocBytes,
+	}
+	if end.peakRSS > start.peakRSS {
+		usage.PeakRSSGrowth = end.peakRSS - start.peakRSS
+	}
+	return usage
+}
+
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,62 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestResourceGuard(t *testing.T) {
+	t.Parallel()
+	var caught []string
+	t.Run("warn", func(t *testing.T) {
+		captureT := ntest.ReplaceLogger(t, func(s string) {
+			t.Log("captured:", s)
+			caught = append(caught, s)
+		})
+		ntest.RunTest(captureT,
+			ntest.ResourceGuard(ntest.ResourceBudget{
+				WallTime: time.Millisecond,
+				WarnOnly: true,
+			}),
+			func() {
+				time.Sleep(5 * time.Millisecond)
+			},
+		)
+	})
+	if assert.Equal(t, 1, len(caught)) {
+		assert.Contains(t, caught[0], "warning: resource budget exceeded: wall time")
+	}
+}
+
+func TestResourceGuardHeapProfile(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		caught = append(caught, s)
+	})
+	ct := &cleanupT{T: captureT}
+	ntest.RunTest(ct,
+		ntest.ResourceGuard(ntest.ResourceBudget{
+			Allocs:      1,
+			WarnOnly:    true,
+			HeapProfile: true,
+		}),
+		func() {
+			_ = make([]byte, 1<<20)
+		},
+	)
+	ct.runCleanups()
+	_, err := os.Stat(filepath.Join(root, "TestResourceGuardHeapProfile", "heap.pprof"))
+	assert.NoError(t, err)
+	if assert.Equal(t, 2, len(caught)) {
+		assert.Contains(t, caught[0], "warning: resource budget exceeded: allocations")
+		assert.Contains(t, caught[1], "heap profile written to ")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package ntest
+
+// peakRSS is not available on this platform
+func peakRSS() uint64 {
+	return 0
+}
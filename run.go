@@ -1,25 +1,119 @@
 package ntest
 
 import (
+	"reflect"
+	"runtime/debug"
 	"testing"
 
 	"github.com/muir/nject"
 	"github.com/stretchr/testify/require"
 )
 
+// PanicPolicy controls what RunTest does when the injection chain panics.
+type PanicPolicy int
+
+const (
+	// PanicPropagate lets panics propagate unchanged (the default).
+	PanicPropagate PanicPolicy = iota
+	// PanicFail recovers the panic and fails the test with the panic
+	// value and stack. RunTest then returns normally so the rest of the
+	// test function and the cleanup functions run.
+	PanicFail
+	// PanicFailAndRepanic is like PanicFail but panics again with the
+	// same value after the failure has been recorded.
+	PanicFailAndRepanic
+)
+
+// OnPanic is the PanicPolicy used by RunTest.
+var OnPanic = PanicPropagate
+
 // RunTest provides the basic framework for running a test.
 //
 // If running a testing.T test, pass that. If running a Ginkgo test, pass ginkgo.GinkgoT().
+//
+// What happens if the chain panics is controlled by OnPanic.
+//
+// Fakes registered with Fake replace the providers of their type.
+//
+// Fixtures that implement Readiness are waited for before the final
+// function is called, see WaitForReadiness. Injected mocks are checked
+// when the test finishes, see AssertMocks.
+//
+// Cleanup functions registered while the chain runs are watched, see
+// CleanupHangTimeout.
+//
+// Profiles can be saved when the test fails or is slow, see
+// ProfileOnFailure and ProfileSlowTests. Tests selected with
+// $NTEST_CPU_PROFILE are profiled, see ProfileCPU, and tests selected
+// with $NTEST_TRACE are traced, see RecordTrace.
+//
+// Hooks registered with OnFailure run even if the test only fails in
+// a cleanup function that runs after them.
+//
+// If $NTEST_CHAIN_GRAPH is set, a graph of the providers that the chain
+// uses is written to the ArtifactDir of the test.
+//
+// The test is wrapped in the middleware registered with AddMiddleware.
 func RunTest(t T, chain ...interface{}) {
+	withMiddleware(t, func(t T) {
+		runTest(t, chain)
+	})
+}
+
+func runTest(t T, chain []interface{}) {
+	testingT, isTestingT := t.(*testing.T)
+	t = startReport(t)
+	watchProfiles(t)
+	watchFailureHooks(t)
+	if cpuProfileSelected(t.Name()) {
+		ProfileCPU(t)
+	}
+	if traceSelected(t.Name()) {
+		RecordTrace(t, TraceSlow)
+	}
+	startWatchdog(t)
+	defer watchCleanups(t)()
+	checkChain(t, chain)
+	if policy := OnPanic; policy != PanicPropagate {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			t.Errorf("panic in %s: %v\n%s", t.Name(), r, debug.Stack())
+			if policy == PanicFailAndRepanic {
+				panic(r)
+			}
+		}()
+	}
+	defer func() {
+		// recover only if the chain may have been stopped by FailNow on
+		// a T from XFail so that other panics propagate unchanged
+		if _, ok := xfailTests.LoadAndDelete(t.Name()); !ok {
+			return
+		}
+		if r := recover(); r != nil {
+			if _, ok := r.(expectedFailureStop); !ok {
+				panic(r)
+			}
+		}
+	}()
 	tseq := nject.Sequence("T",
 		func() T { return t },
 	)
-	if testingT, ok := t.(*testing.T); ok {
+	if isTestingT {
 		tseq = tseq.Append("realT",
 			func() *testing.T { return testingT },
 		)
 	}
-	err := nject.Run(t.Name(),
+	chain = substituteFakes(t, chain)
+	if asserter := mockAsserter(t, chain); asserter != nil {
+		chain = combineSlices(chain[:len(chain)-1], []interface{}{asserter}, chain[len(chain)-1:])
+	}
+	if waiter := readinessWaiter(t, chain); waiter != nil {
+		chain = combineSlices(chain[:len(chain)-1], []interface{}{waiter}, chain[len(chain)-1:])
+	}
+	fullChain := nject.Sequence(t.Name(),
 		tseq,
 		func(inner func() error, t *testing.T) {
 			err := inner()
@@ -28,8 +122,30 @@ func RunTest(t T, chain ...interface{}) {
 		nject.Sequence("user-chain", chain...),
 		nject.NonFinal(nject.Shun(func(inner func()) error { inner(); return nil })),
 	)
+	if formats := chainGraphFormats(); len(formats) != 0 {
+		fullChain = nject.Sequence(t.Name(), chainGrapher(t, formats, fullChain), fullChain)
+	}
+	err := nject.Run(t.Name(), fullChain)
 	if err != nil && err.Error() != nject.DetailedError(err) {
 		t.Logf("nject detailed error: %s", nject.DetailedError(err))
 	}
 	require.NoErrorf(t, err, "invalid injection chain for %s", t.Name())
 }
+
+var tType = reflect.TypeOf((*T)(nil)).Elem()
+
+// RunTestT is RunTest for a particular implementation of T, like
+// *testing.B or a T wrapper: t is also injected as an ET so that
+// injectors and the final function can take the concrete type without
+// type assertions.
+//
+//	ntest.RunTestT(b, dbInjector, func(b *testing.B, db *sql.DB) { ... })
+func RunTestT[ET T](t ET, chain ...interface{}) {
+	switch reflect.TypeOf((*ET)(nil)).Elem() {
+	case tType, reflect.TypeOf((*testing.T)(nil)):
+		// RunTest already provides these
+		RunTest(t, chain...)
+	default:
+		RunTest(t, combineSlices([]interface{}{nject.Provide("typed-T", func() ET { return t })}, chain)...)
+	}
+}
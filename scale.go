@@ -0,0 +1,39 @@
+package ntest
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// TimeoutScaleEnv names the environment variable that sets the factor
+// used by ScaledTimeout, for example "2.5" on a slow CI runner.
+const TimeoutScaleEnv = "NTEST_TIMEOUT_SCALE"
+
+// RaceTimeoutScale is the factor used by ScaledTimeout when the race
+// detector is enabled and NTEST_TIMEOUT_SCALE is not set.
+var RaceTimeoutScale = 3.0
+
+// TimeoutScale returns the factor used by ScaledTimeout: the value of
+// NTEST_TIMEOUT_SCALE if it is set, otherwise RaceTimeoutScale if the race
+// detector is enabled, otherwise 1.
+func TimeoutScale() float64 {
+	if s := os.Getenv(TimeoutScaleEnv); s != "" {
+		if scale, err := strconv.ParseFloat(s, 64); err == nil && scale > 0 {
+			return scale
+		}
+	}
+	if raceEnabled {
+		return RaceTimeoutScale
+	}
+	return 1
+}
+
+// ScaledTimeout multiplies d by TimeoutScale so that timeouts can be
+// tuned for fast laptops and still pass under the race detector or on
+// slow machines. The timeouts built into ntest (Eventually, WaitForReady,
+// StartProcess, BusCapture.WaitFor, and the margin before the test
+// deadline) are scaled already.
+func ScaledTimeout(d time.Duration) time.Duration {
+	return time.Duration(float64(d) * TimeoutScale())
+}
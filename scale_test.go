@@ -0,0 +1,22 @@
+package ntest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestScaledTimeout(t *testing.T) {
+	t.Setenv(ntest.TimeoutScaleEnv, "2.5")
+	assert.Equal(t, 5*time.Second, ntest.ScaledTimeout(2*time.Second))
+
+	t.Setenv(ntest.TimeoutScaleEnv, "")
+	want := time.Second
+	if raceEnabled {
+		want = time.Duration(ntest.RaceTimeoutScale * float64(time.Second))
+	}
+	assert.Equal(t, want, ntest.ScaledTimeout(time.Second))
+}
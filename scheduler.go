@@ -0,0 +1,125 @@
+package ntest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// FakeScheduler captures jobs that code under test registers so that the
+// test can run them deterministically instead of waiting for real time
+// to pass. Code under test should depend on a small interface that
+// FakeScheduler satisfies (for example, one with just Every) so that
+// the fake can be substituted for the real scheduler.
+type FakeScheduler struct {
+	mu    sync.Mutex
+	start time.Time
+	jobs  []*ScheduledJob
+}
+
+// ScheduledJob is a job registered with a FakeScheduler.
+type ScheduledJob struct {
+	Name string
+	// Next is when the job is next due.
+	Next time.Time
+	// Runs counts how many times the job has been run.
+	Runs     int
+	schedule func(after time.Time) time.Time
+	job      func()
+}
+
+// SchedulerFixture provides a *FakeScheduler whose clock starts at the
+// current time.
+var SchedulerFixture = nject.Provide("fake-scheduler", func() *FakeScheduler {
+	return NewFakeScheduler(time.Now())
+})
+
+// NewFakeScheduler creates a FakeScheduler. Jobs registered with Every are
+// first due one interval after start.
+func NewFakeScheduler(start time.Time) *FakeScheduler {
+	return &FakeScheduler{start: start}
+}
+
+// Every registers a job that is due every interval.
+func (s *FakeScheduler) Every(name string, interval time.Duration, job func()) {
+	s.Schedule(name, func(after time.Time) time.Time {
+		return after.Add(interval)
+	}, job)
+}
+
+// Schedule registers a job with an arbitrary schedule: next returns the
+// first time after its argument that the job is due. This matches the
+// Next method of common cron schedule implementations.
+func (s *FakeScheduler) Schedule(name string, next func(after time.Time) time.Time, job func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, &ScheduledJob{
+		Name:     name,
+		Next:     next(s.start),
+		schedule: next,
+		job:      job,
+	})
+}
+
+// RunDue runs, in order of due time (and then name), each job that is due
+// at or before now. Each job is run at most once per call and then
+// rescheduled relative to now. The names of the jobs run are returned.
+// Jobs are run without holding any locks so they may register more jobs.
+func (s *FakeScheduler) RunDue(now time.Time) []string {
+	s.mu.Lock()
+	var due []*ScheduledJob
+	for _, job := range s.jobs {
+		if !job.Next.After(now) {
+			due = append(due, job)
+		}
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].Next.Equal(due[j].Next) {
+			return due[i].Name < due[j].Name
+		}
+		return due[i].Next.Before(due[j].Next)
+	})
+	for _, job := range due {
+		job.Next = job.schedule(now)
+		job.Runs++
+	}
+	s.mu.Unlock()
+	names := make([]string, len(due))
+	for i, job := range due {
+		names[i] = job.Name
+		job.job()
+	}
+	return names
+}
+
+// Run runs all jobs with the given name immediately, regardless of when they
+// are due, without changing their schedules. It returns the number of
+// jobs run.
+func (s *FakeScheduler) Run(name string) int {
+	s.mu.Lock()
+	var matched []*ScheduledJob
+	for _, job := range s.jobs {
+		if job.Name == name {
+			job.Runs++
+			matched = append(matched, job)
+		}
+	}
+	s.mu.Unlock()
+	for _, job := range matched {
+		job.job()
+	}
+	return len(matched)
+}
+
+// Jobs returns a snapshot of the registered jobs.
+func (s *FakeScheduler) Jobs() []ScheduledJob {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]ScheduledJob, len(s.jobs))
+	for i, job := range s.jobs {
+		jobs[i] = ScheduledJob{Name: job.Name, Next: job.Next, Runs: job.Runs}
+	}
+	return jobs
+}
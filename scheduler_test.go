@@ -0,0 +1,33 @@
+package ntest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestFakeScheduler(t *testing.T) {
+	t.Parallel()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ntest.RunTest(t,
+		func() *ntest.FakeScheduler { return ntest.NewFakeScheduler(start) },
+		func(s *ntest.FakeScheduler) {
+			var ran []string
+			s.Every("minutely", time.Minute, func() { ran = append(ran, "minutely") })
+			s.Every("hourly", time.Hour, func() { ran = append(ran, "hourly") })
+
+			assert.Empty(t, s.RunDue(start.Add(30*time.Second)))
+			assert.Equal(t, []string{"minutely"}, s.RunDue(start.Add(time.Minute)))
+			assert.Equal(t, []string{"minutely", "hourly"}, s.RunDue(start.Add(2*time.Hour)))
+			assert.Equal(t, 1, s.Run("hourly"))
+			assert.Equal(t, []string{"minutely", "minutely", "hourly", "hourly"}, ran)
+
+			jobs := s.Jobs()
+			assert.Equal(t, start.Add(2*time.Hour+time.Minute), jobs[0].Next)
+			assert.Equal(t, 2, jobs[1].Runs)
+		},
+	)
+}
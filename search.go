@@ -0,0 +1,189 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// ElasticsearchService is used by SearchClusterFixture. The image works
+// for both Elasticsearch and OpenSearch style clients. To use OpenSearch,
+// set NTEST_ELASTICSEARCH_URL or replace the image.
+var ElasticsearchService = DockerService{
+	Name:   "elasticsearch",
+	EnvVar: "NTEST_ELASTICSEARCH_URL",
+	Image:  "docker.elastic.co/elasticsearch/elasticsearch:8.13.4",
+	Port:   "9200/tcp",
+	Env: map[string]string{
+		"discovery.type":         "single-node",
+		"xpack.security.enabled": "false",
+		"ES_JAVA_OPTS":           "-Xms512m -Xmx512m",
+	},
+}
+
+// SearchCluster is an Elasticsearch or OpenSearch cluster.
+type SearchCluster struct {
+	URL    string
+	Client *http.Client
+}
+
+// SearchIndexMapping is the body used to create the per-test index
+// (settings and mappings). The default, provided by a named injector
+// "search-index-mapping", is empty. Override with SearchMapping.
+type SearchIndexMapping string
+
+// SearchIndex is an index created for a single test.
+type SearchIndex struct {
+	Cluster *SearchCluster
+	Name    string
+}
+
+// SearchResult is the part of a search response that tests usually need.
+type SearchResult struct {
+	Total int
+	IDs   []string
+	// Sources are the _source documents of the hits.
+	Sources []json.RawMessage
+}
+
+// SearchClusterFixture provides a *SearchCluster following the
+// DockerService conventions. It waits for the cluster to be healthy.
+var SearchClusterFixture = nject.Provide("search-cluster", func(t T) *SearchCluster {
+	t.Helper()
+	addr := ElasticsearchService.Address(t)
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	cluster := &SearchCluster{
+		URL:    strings.TrimRight(addr, "/"),
+		Client: &http.Client{Timeout: 30 * time.Second},
+	}
+	if !Eventually(t, func() error {
+		_, err := cluster.request(http.MethodGet, "/_cluster/health?wait_for_status=yellow&timeout=1s", nil)
+		return err
+	}, EventuallyOptions{Timeout: 2 * time.Minute, Interval: time.Second, Description: "search cluster healthy"}) {
+		t.FailNow()
+	}
+	return cluster
+})
+
+// SearchIndexFixture creates an index named after the test (see
+// TestID) with the injected SearchIndexMapping. The index is deleted when
+// the test finishes.
+var SearchIndexFixture = nject.Sequence("search-index",
+	nject.Provide("search-index-mapping", func() SearchIndexMapping { return "" }),
+	func(t T, cluster *SearchCluster, mapping SearchIndexMapping) *SearchIndex {
+		t.Helper()
+		index := &SearchIndex{
+			Cluster: cluster,
+			Name:    string(NewTestID(t)),
+		}
+		var body []byte
+		if mapping != "" {
+			body = []byte(mapping)
+		}
+		if _, err := cluster.request(http.MethodPut, "/"+index.Name, body); err != nil {
+			t.Fatalf("create search index %s: %s", index.Name, err)
+		}
+		t.Cleanup(func() {
+			if _, err := cluster.request(http.MethodDelete, "/"+index.Name, nil); err != nil {
+				t.Logf("delete search index %s: %s", index.Name, err)
+			}
+		})
+		return index
+	},
+)
+
+// SearchMapping overrides the settings and mappings used to create the
+// index provided by SearchIndexFixture.
+func SearchMapping(mapping string) nject.Provider {
+	return nject.ReplaceNamed("search-index-mapping", func() SearchIndexMapping {
+		return SearchIndexMapping(mapping)
+	})
+}
+
+// Index adds or replaces a document.
+func (i *SearchIndex) Index(t T, id string, doc interface{}) {
+	t.Helper()
+	body, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("encode document %s: %s", id, err)
+	}
+	if _, err := i.Cluster.request(http.MethodPut, "/"+i.Name+"/_doc/"+id, body); err != nil {
+		t.Fatalf("index document %s: %s", id, err)
+	}
+}
+
+// RefreshAndSearch makes all indexed documents visible and then runs
+// query (the body of a _search request; nil matches everything).
+func (i *SearchIndex) RefreshAndSearch(t T, query interface{}) SearchResult {
+	t.Helper()
+	if _, err := i.Cluster.request(http.MethodPost, "/"+i.Name+"/_refresh", nil); err != nil {
+		t.Fatalf("refresh search index %s: %s", i.Name, err)
+	}
+	var body []byte
+	if query != nil {
+		var err error
+		body, err = json.Marshal(query)
+		if err != nil {
+			t.Fatalf("encode search query: %s", err)
+		}
+	}
+	respBody, err := i.Cluster.request(http.MethodPost, "/"+i.Name+"/_search", body)
+	if err != nil {
+		t.Fatalf("search %s: %s", i.Name, err)
+	}
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID     string          `json:"_id"`
+				Source json.RawMessage `json:"_source"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		t.Fatalf("decode search response: %s", err)
+	}
+	result := SearchResult{Total: resp.Hits.Total.Value}
+	for _, hit := range resp.Hits.Hits {
+		result.IDs = append(result.IDs, hit.ID)
+		result.Sources = append(result.Sources, hit.Source)
+	}
+	return result
+}
+
+func (c *SearchCluster) request(method, path string, body []byte) ([]byte, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.URL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("%s %s: status %d: %s", method, path, resp.StatusCode, respBody)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,97 @@
+package ntest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+// fakeSearchServer implements just enough of the Elasticsearch API for
+// the fixture to be exercised without a real cluster.
+func fakeSearchServer(t *testing.T) (*httptest.Server, map[string]bool) {
+	var mu sync.Mutex
+	indexes := make(map[string]bool)
+	docs := make(map[string]map[string]json.RawMessage)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+		switch {
+		case parts[0] == "_cluster":
+			_, _ = w.Write([]byte(`{"status":"green"}`))
+		case len(parts) == 1 && r.Method == http.MethodPut:
+			indexes[parts[0]] = true
+			docs[parts[0]] = make(map[string]json.RawMessage)
+		case len(parts) == 1 && r.Method == http.MethodDelete:
+			delete(indexes, parts[0])
+		case len(parts) == 3 && parts[1] == "_doc":
+			body, _ := io.ReadAll(r.Body)
+			docs[parts[0]][parts[2]] = body
+		case len(parts) == 2 && parts[1] == "_refresh":
+		case len(parts) == 2 && parts[1] == "_search":
+			type hit struct {
+				ID     string          `json:"_id"`
+				Source json.RawMessage `json:"_source"`
+			}
+			var hits []hit
+			for id, doc := range docs[parts[0]] {
+				hits = append(hits, hit{ID: id, Source: doc})
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"hits": map[string]interface{}{
+					"total": map[string]int{"value": len(hits)},
+					"hits":  hits,
+				},
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, indexes
+}
+
+func TestSearchIndexFixture(t *testing.T) {
+	server, indexes := fakeSearchServer(t)
+	t.Setenv("NTEST_ELASTICSEARCH_URL", server.URL)
+	var indexName string
+	t.Run("index", func(t *testing.T) {
+		ntest.RunTest(t,
+			ntest.SearchClusterFixture,
+			ntest.SearchIndexFixture,
+			ntest.SearchMapping(`{"mappings":{"properties":{"title":{"type":"text"}}}}`),
+			func(index *ntest.SearchIndex) {
+				indexName = index.Name
+				assert.True(t, indexes[index.Name], "created")
+				index.Index(t, "1", map[string]string{"title": "hello"})
+				result := index.RefreshAndSearch(t, nil)
+				assert.Equal(t, 1, result.Total)
+				assert.Equal(t, []string{"1"}, result.IDs)
+				assert.JSONEq(t, `{"title":"hello"}`, string(result.Sources[0]))
+			},
+		)
+	})
+	assert.NotEmpty(t, indexName)
+	assert.False(t, indexes[indexName], "deleted")
+}
+
+func TestSearchClusterSkip(t *testing.T) {
+	t.Setenv("NTEST_ELASTICSEARCH_URL", "")
+	t.Setenv(ntest.DockerEnabledEnv, "false")
+	var inner *testing.T
+	t.Run("skipped", func(t *testing.T) {
+		inner = t
+		ntest.RunTest(t, ntest.SearchClusterFixture, func(*ntest.SearchCluster) {
+			t.Error("should have been skipped")
+		})
+	})
+	assert.True(t, inner.Skipped())
+}
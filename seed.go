@@ -0,0 +1,270 @@
+package ntest
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/muir/nject"
+	"gopkg.in/yaml.v3"
+)
+
+// SeedStore is implemented by storage fixtures that can be seeded from
+// a manifest and checked afterwards. Object stores (S3, GCS) and
+// filesystems map onto it directly. Get must return an error that
+// matches fs.ErrNotExist (with errors.Is) when the key does not exist.
+type SeedStore interface {
+	Put(ctx context.Context, key string, data []byte) error
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// SeedStores names the stores that a manifest can refer to. Provide
+// one in the injection chain before SeedFromManifest.
+type SeedStores map[string]SeedStore
+
+// SeedManifest describes data to load before a test runs and the
+// state that is expected after the test runs. Manifests can be written
+// in JSON or YAML (chosen by file extension).
+type SeedManifest struct {
+	Seed   []SeedObject `json:"seed" yaml:"seed"`
+	Expect []SeedObject `json:"expect" yaml:"expect"`
+}
+
+// SeedObject is one entry in a SeedManifest. Exactly one of Content,
+// File, or JSON should be set, except for expectations with Absent.
+type SeedObject struct {
+	Store string `json:"store" yaml:"store"`
+	Key   string `json:"key" yaml:"key"`
+	// Content is used as-is.
+	Content string `json:"content,omitempty" yaml:"content,omitempty"`
+	// File is read relative to the directory of the manifest.
+	File string `json:"file,omitempty" yaml:"file,omitempty"`
+	// JSON is encoded as JSON. When used in an expectation, the stored
+	// value is compared as JSON rather than byte-for-byte.
+	JSON interface{} `json:"json,omitempty" yaml:"json,omitempty"`
+	// Absent, for expectations only, asserts that the key does not exist.
+	Absent bool `json:"absent,omitempty" yaml:"absent,omitempty"`
+}
+
+// SeedFromManifest returns a wrapper injector that loads the manifest at
+// path, seeds the injected SeedStores before the rest of the chain runs,
+// and checks the expectations after the rest of the chain (including the
+// final function) returns.
+func SeedFromManifest(path string) nject.Provider {
+	return nject.Provide("seed-"+filepath.Base(path), func(inner func(), t T, stores SeedStores) {
+		t.Helper()
+		manifest, err := LoadSeedManifest(path)
+		if err != nil {
+			t.Fatalf("load seed manifest: %s", err)
+		}
+		dir := filepath.Dir(path)
+		ctx := context.Background()
+		for _, obj := range manifest.Seed {
+			store := seedStore(t, stores, obj)
+			data, _, err := obj.data(dir)
+			if err != nil {
+				t.Fatalf("seed %s/%s: %s", obj.Store, obj.Key, err)
+			}
+			if err := store.Put(ctx, obj.Key, data); err != nil {
+				t.Fatalf("seed %s/%s: %s", obj.Store, obj.Key, err)
+			}
+		}
+		t.Logf("seeded %d objects from %s", len(manifest.Seed), path)
+		inner()
+		for _, obj := range manifest.Expect {
+			checkSeedExpectation(ctx, t, seedStore(t, stores, obj), dir, obj)
+		}
+	})
+}
+
+// LoadSeedManifest reads and parses a manifest.
+func LoadSeedManifest(path string) (*SeedManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest SeedManifest
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &manifest)
+	default:
+		err = json.Unmarshal(data, &manifest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+func seedStore(t T, stores SeedStores, obj SeedObject) SeedStore {
+	t.Helper()
+	store, ok := stores[obj.Store]
+	if !ok {
+		t.Fatalf("seed manifest refers to store '%s' which is not in SeedStores", obj.Store)
+	}
+	return store
+}
+
+func (obj SeedObject) data(dir string) (data []byte, isJSON bool, err error) {
+	switch {
+	case obj.File != "":
+		data, err = os.ReadFile(filepath.Join(dir, obj.File))
+		return data, false, err
+	case obj.JSON != nil:
+		data, err = json.Marshal(normalizeYAML(obj.JSON))
+		return data, true, err
+	default:
+		return []byte(obj.Content), false, nil
+	}
+}
+
+func checkSeedExpectation(ctx context.Context, t T, store SeedStore, dir string, obj SeedObject) {
+	t.Helper()
+	got, err := store.Get(ctx, obj.Key)
+	if obj.Absent {
+		if err == nil {
+			t.Errorf("expected %s/%s to be absent, but it exists", obj.Store, obj.Key)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("check %s/%s: %s", obj.Store, obj.Key, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("expected %s/%s: %s", obj.Store, obj.Key, err)
+		return
+	}
+	want, isJSON, err := obj.data(dir)
+	if err != nil {
+		t.Errorf("expected %s/%s: %s", obj.Store, obj.Key, err)
+		return
+	}
+	if isJSON {
+		var wantValue, gotValue interface{}
+		_ = json.Unmarshal(want, &wantValue)
+		if err := json.Unmarshal(got, &gotValue); err != nil || !reflect.DeepEqual(wantValue, gotValue) {
+			t.Errorf("%s/%s does not match expected JSON\nwant: %s\n got: %s", obj.Store, obj.Key, want, got)
+		}
+		return
+	}
+	if !bytes.Equal(want, got) {
+		t.Errorf("%s/%s does not match expected content\nwant: %q\n got: %q", obj.Store, obj.Key, want, got)
+	}
+}
+
+// normalizeYAML converts map[string]interface{} trees decoded from YAML
+// into something encoding/json can marshal.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(val)
+		}
+		return m
+	case map[string]interface{}:
+		for k, val := range v {
+			v[k] = normalizeYAML(val)
+		}
+		return v
+	case []interface{}:
+		for i, val := range v {
+			v[i] = normalizeYAML(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// SQLSeedStore adapts a database to SeedStore. Keys are table names.
+// Data must be a JSON array of objects, each of which is inserted as a
+// row. Get returns all rows of the table, as a JSON array of objects,
+// ordered by the first column.
+type SQLSeedStore struct {
+	DB *sql.DB
+	// Placeholder generates the placeholder for the nth (starting at 1)
+	// parameter. If nil, "?" is used.
+	Placeholder func(n int) string
+}
+
+func (s SQLSeedStore) Put(ctx context.Context, table string, data []byte) error {
+	var rows []map[string]interface{}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("rows for %s must be a JSON array of objects: %w", table, err)
+	}
+	for _, row := range rows {
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		placeholders := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if s.Placeholder != nil {
+				placeholders[i] = s.Placeholder(i + 1)
+			} else {
+				placeholders[i] = "?"
+			}
+			args[i] = row[column]
+		}
+		query := "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+		if _, err := s.DB.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("%s: %w", query, err)
+		}
+	}
+	return nil
+}
+
+func (s SQLSeedStore) Get(ctx context.Context, table string) ([]byte, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT * FROM "+table+" ORDER BY 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result, err := scanRows(rows, -1)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// scanRows reads up to limit rows (all of them if limit is negative) as
+// objects keyed by column. Text that the driver returns as []byte is
+// converted to string so that it is readable as JSON.
+func scanRows(rows *sql.Rows, limit int) ([]map[string]interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := []map[string]interface{}{}
+	for (limit < 0 || len(result) < limit) && rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				row[column] = string(b)
+			} else {
+				row[column] = values[i]
+			}
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
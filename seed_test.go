@@ -0,0 +1,87 @@
+package ntest_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type memorySeedStore struct {
+	mu   sync.Mutex
+	data map[string][]byte
+}
+
+func (s *memorySeedStore) Put(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = make(map[string][]byte)
+	}
+	s.data[key] = data
+	return nil
+}
+
+func (s *memorySeedStore) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.data[key]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (s *memorySeedStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+func TestSeedFromManifest(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), []byte("from a file"), 0o600))
+	manifest := filepath.Join(dir, "manifest.yaml")
+	require.NoError(t, os.WriteFile(manifest, []byte(`
+seed:
+  - store: files
+    key: /in/inline.txt
+    content: hello
+  - store: files
+    key: /in/big.txt
+    file: big.txt
+expect:
+  - store: files
+    key: /out/result.json
+    json:
+      count: 2
+      names: [big.txt, inline.txt]
+  - store: files
+    key: /in/inline.txt
+    absent: true
+`), 0o600))
+
+	store := &memorySeedStore{}
+	ntest.RunTest(t,
+		func() ntest.SeedStores {
+			return ntest.SeedStores{"files": store}
+		},
+		ntest.SeedFromManifest(manifest),
+		func() {
+			ctx := context.Background()
+			data, err := store.Get(ctx, "/in/big.txt")
+			require.NoError(t, err)
+			require.Equal(t, "from a file", string(data))
+			store.Delete("/in/inline.txt")
+			require.NoError(t, store.Put(ctx, "/out/result.json",
+				[]byte(`{"names":["big.txt","inline.txt"],"count":2}`)))
+		},
+	)
+}
@@ -0,0 +1,127 @@
+package ntest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DockerService describes an external service that a fixture needs.
+//
+// The convention for service fixtures is:
+//
+//  1. If the environment variable named by EnvVar is set, its value is
+//     the address of an already-running service.
+//  2. Otherwise, if docker is available and its daemon is running (and
+//     NTEST_DOCKER is not "false"), Image is started in a container for
+//     the duration of the test.
+//  3. Otherwise the test is skipped.
+type DockerService struct {
+	// Name is used in log messages and skip messages.
+	Name string
+	// EnvVar holds the address of an existing service.
+	EnvVar string
+	// Image is the docker image to run.
+	Image string
+	// Port is the container port to publish, eg: "9200/tcp".
+	Port string
+	// Env is passed to the container.
+	Env map[string]string
+	// Args are passed to the container after the image name.
+	Args []string
+}
+
+// DockerEnabledEnv can be set to "false" to prevent service fixtures from
+// starting containers.
+const DockerEnabledEnv = "NTEST_DOCKER"
+
+// Address returns the address of the service: either the value of EnvVar or
+// host:port of a newly started container. The container is removed when
+// the test finishes. Address skips the test if the service is unavailable.
+func (s DockerService) Address(t T) string {
+	t.Helper()
+	if addr := os.Getenv(s.EnvVar); addr != "" {
+		return addr
+	}
+	docker, err := exec.LookPath("docker")
+	if err != nil || os.Getenv(DockerEnabledEnv) == "false" {
+		t.Skipf("skipping %s: %s is not available (set %s or make docker available)", t.Name(), s.Name, s.EnvVar)
+	}
+	if err := dockerRunning(docker); err != nil {
+		t.Skipf("skipping %s: %s is not available (set %s or start docker): docker info: %s", t.Name(), s.Name, s.EnvVar, err)
+	}
+	args := []string{"run", "--detach", "--rm", "--publish", "127.0.0.1::" + s.Port}
+	keys := make([]string, 0, len(s.Env))
+	for k := range s.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--env", k+"="+s.Env[k])
+	}
+	args = append(args, s.Image)
+	args = append(args, s.Args...)
+	out, err := exec.Command(docker, args...).Output()
+	if err != nil {
+		t.Fatalf("start %s container (docker %s): %s", s.Name, strings.Join(args, " "), commandError(err))
+	}
+	containerID := strings.TrimSpace(string(out))
+	t.Logf("started %s container %s from %s", s.Name, shortID(containerID), s.Image)
+	ReportFixture(t, s.Name+" container "+shortID(containerID))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if out, err := exec.CommandContext(ctx, docker, "rm", "--force", containerID).CombinedOutput(); err != nil {
+			t.Logf("remove %s container %s: %s: %s", s.Name, shortID(containerID), err, out)
+		}
+	})
+	out, err = exec.Command(docker, "port", containerID, s.Port).Output()
+	if err != nil {
+		t.Fatalf("find published port for %s container: %s", s.Name, commandError(err))
+	}
+	// docker port may list both IPv4 and IPv6 bindings
+	return strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+}
+
+var (
+	dockerInfoLock sync.Mutex
+	dockerInfo     = make(map[string]error) // docker path -> result of docker info
+)
+
+// dockerRunning reports whether the docker daemon can be reached. The
+// docker CLI may be installed when the daemon is not running, in which
+// case docker run would fail. It is only checked once for each docker.
+func dockerRunning(docker string) error {
+	dockerInfoLock.Lock()
+	defer dockerInfoLock.Unlock()
+	if err, ok := dockerInfo[docker]; ok {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	_, err := exec.CommandContext(ctx, docker, "info").Output()
+	if err != nil {
+		err = errors.New(commandError(err))
+	}
+	dockerInfo[docker] = err
+	return err
+}
+
+func commandError(err error) string {
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) != 0 {
+		return err.Error() + ": " + strings.TrimSpace(string(exitErr.Stderr))
+	}
+	return err.Error()
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
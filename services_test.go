@@ -0,0 +1,41 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestDockerServiceDaemonDown(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as docker")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"Cannot connect to the Docker daemon\" >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "docker"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir)
+	t.Setenv("NTEST_TEST_SERVICE_ADDR", "")
+	t.Setenv(ntest.DockerEnabledEnv, "")
+	service := ntest.DockerService{
+		Name:   "test-service",
+		EnvVar: "NTEST_TEST_SERVICE_ADDR",
+		Image:  "test-image",
+		Port:   "1234/tcp",
+	}
+	var skipped bool
+	t.Run("address", func(t *testing.T) {
+		defer func() {
+			skipped = t.Skipped()
+		}()
+		service.Address(t)
+		t.Error("Address returned")
+	})
+	assert.True(t, skipped, "test skipped when the docker daemon is down")
+}
@@ -0,0 +1,200 @@
+package ntest
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// PipelineSource is where a SingleStore pipeline loads data from: the
+// part of CREATE PIPELINE that follows LOAD DATA.
+type PipelineSource struct {
+	// Kind is the type of source, like "FS" or "S3".
+	Kind string
+	// Path is the path, glob, or bucket/prefix to load.
+	Path string
+	// Config and Credentials, if set, are the JSON for CONFIG and
+	// CREDENTIALS.
+	Config      string
+	Credentials string
+}
+
+// FSPipelineSource loads files matching glob from a directory. The
+// directory must be readable by the SingleStore nodes, so this is for
+// clusters that run on the same host as the test.
+func FSPipelineSource(glob string) PipelineSource {
+	return PipelineSource{Kind: "FS", Path: glob}
+}
+
+// S3PipelineSource loads objects from bucket/prefix of an S3 compatible
+// service at endpoint, like a local MinIO or LocalStack.
+func S3PipelineSource(bucketPath, endpoint, region, accessKeyID, secretAccessKey string) PipelineSource {
+	return PipelineSource{
+		Kind:        "S3",
+		Path:        bucketPath,
+		Config:      fmt.Sprintf(`{"region": %q, "endpoint_url": %q}`, region, endpoint),
+		Credentials: fmt.Sprintf(`{"aws_access_key_id": %q, "aws_secret_access_key": %q}`, accessKeyID, secretAccessKey),
+	}
+}
+
+// PipelineSpec describes a pipeline for CreatePipeline.
+type PipelineSpec struct {
+	// Name defaults to a name made from the TestID.
+	Name   string
+	Source PipelineSource
+	// Into is where the data goes, like "TABLE events" or
+	// "PROCEDURE load_events".
+	Into string
+	// Format follows Into, for example
+	// "FORMAT CSV FIELDS TERMINATED BY ','" or "FORMAT JSON (id <- id)".
+	Format string
+	// Options, like "BATCH_INTERVAL 100", go between the source and
+	// INTO.
+	Options string
+}
+
+// Pipeline is a SingleStore pipeline created by CreatePipeline.
+type Pipeline struct {
+	Name string
+	db   *sql.DB
+	t    T
+}
+
+// CreatePipeline creates a pipeline, without starting it, in the current
+// database of db. It is stopped and dropped when the test finishes.
+func CreatePipeline(t T, db *sql.DB, spec PipelineSpec) *Pipeline {
+	t.Helper()
+	name := spec.Name
+	if name == "" {
+		name = strings.ReplaceAll(string(NewTestID(t)), "-", "_")
+	}
+	query := "CREATE PIPELINE " + quoteIdentifier(name) + " AS LOAD DATA " + spec.Source.Kind + " " + quoteString(spec.Source.Path)
+	if spec.Source.Config != "" {
+		query += " CONFIG " + quoteString(spec.Source.Config)
+	}
+	if spec.Source.Credentials != "" {
+		query += " CREDENTIALS " + quoteString(spec.Source.Credentials)
+	}
+	if spec.Options != "" {
+		query += " " + spec.Options
+	}
+	query += " INTO " + spec.Into
+	if spec.Format != "" {
+		query += " " + spec.Format
+	}
+	if _, err := db.Exec(query); err != nil {
+		t.Fatalf("create pipeline %s: %s", name, err)
+	}
+	p := &Pipeline{Name: name, db: db, t: t}
+	t.Cleanup(func() {
+		_, _ = db.Exec("STOP PIPELINE " + quoteIdentifier(name))
+		if _, err := db.Exec("DROP PIPELINE " + quoteIdentifier(name)); err != nil {
+			t.Logf("drop pipeline %s: %s", name, err)
+		}
+	})
+	return p
+}
+
+// Start starts the pipeline.
+func (p *Pipeline) Start() {
+	p.t.Helper()
+	if _, err := p.db.Exec("START PIPELINE " + quoteIdentifier(p.Name)); err != nil {
+		p.t.Fatalf("start pipeline %s: %s", p.Name, err)
+	}
+}
+
+// WaitForBatches waits, with Eventually, until the pipeline has loaded
+// at least n batches successfully. If the pipeline reports errors, they
+// fail the test and WaitForBatches stops waiting. It returns true if the
+// batches were loaded.
+func (p *Pipeline) WaitForBatches(n int, opts EventuallyOptions) bool {
+	p.t.Helper()
+	if opts.Description == "" {
+		opts.Description = fmt.Sprintf("pipeline %s loads %d batches", p.Name, n)
+	}
+	var pipelineErrors []string
+	ok := Eventually(p.t, func() error {
+		var err error
+		pipelineErrors, err = p.Errors()
+		if err != nil {
+			return err
+		}
+		if len(pipelineErrors) != 0 {
+			return nil
+		}
+		var succeeded int
+		err = p.db.QueryRow("SELECT COUNT(*) FROM information_schema.PIPELINES_BATCHES_SUMMARY"+
+			" WHERE DATABASE_NAME = DATABASE() AND PIPELINE_NAME = ? AND BATCH_STATE = 'Succeeded'", p.Name).Scan(&succeeded)
+		if err != nil {
+			return err
+		}
+		if succeeded < n {
+			return fmt.Errorf("%d batches succeeded", succeeded)
+		}
+		return nil
+	}, opts)
+	if len(pipelineErrors) != 0 {
+		p.t.Errorf("pipeline %s has errors:\n\t%s", p.Name, strings.Join(pipelineErrors, "\n\t"))
+		return false
+	}
+	return ok
+}
+
+// Errors returns the errors that the pipeline has reported.
+func (p *Pipeline) Errors() ([]string, error) {
+	rows, err := p.db.Query("SELECT ERROR_MESSAGE FROM information_schema.PIPELINES_ERRORS"+
+		" WHERE DATABASE_NAME = DATABASE() AND PIPELINE_NAME = ?", p.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var messages []string
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// AssertRowCount checks that table has want rows.
+func AssertRowCount(t T, db *sql.DB, table string, want int) bool {
+	t.Helper()
+	var got int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + quoteIdentifier(table)).Scan(&got); err != nil {
+		t.Errorf("count rows of %s: %s", table, err)
+		return false
+	}
+	if got != want {
+		t.Errorf("%s has %d rows, expected %d", table, got, want)
+		return false
+	}
+	return true
+}
+
+// CreateProcedure creates (or replaces) a stored procedure, which is
+// dropped when the test finishes. The definition follows the name:
+//
+//	ntest.CreateProcedure(t, db, "load_events",
+//		"(batch QUERY(id INT)) AS BEGIN INSERT INTO events SELECT id FROM batch; END")
+func CreateProcedure(t T, db *sql.DB, name, definition string) {
+	t.Helper()
+	if _, err := db.Exec("CREATE OR REPLACE PROCEDURE " + quoteIdentifier(name) + " " + definition); err != nil {
+		t.Fatalf("create procedure %s: %s", name, err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec("DROP PROCEDURE IF EXISTS " + quoteIdentifier(name)); err != nil {
+			t.Logf("drop procedure %s: %s", name, err)
+		}
+	})
+}
+
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+func quoteString(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
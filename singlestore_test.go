@@ -0,0 +1,46 @@
+package ntest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestPipeline(t *testing.T) {
+	var executed []string
+	ft := &failingT{cleanupT: &cleanupT{T: t}}
+	db := migratedDB(t, &executed)
+	ntest.CreateProcedure(ft, db, "load_events", "(batch QUERY(id INT)) AS BEGIN INSERT INTO events SELECT id FROM batch; END")
+	p := ntest.CreatePipeline(ft, db, ntest.PipelineSpec{
+		Name:   "events",
+		Source: ntest.S3PipelineSource("bucket/events/", "http://localhost:9000", "us-east-1", "key", "it's secret"),
+		Into:   "PROCEDURE load_events",
+		Format: "FORMAT CSV",
+	})
+	p.Start()
+	// the fake database reports two errors for every query
+	assert.False(t, p.WaitForBatches(1, ntest.EventuallyOptions{Timeout: time.Second}))
+	assert.Equal(t, []string{"pipeline events has errors:\n\t2\n\t1"}, ft.errors)
+	assert.True(t, ntest.AssertRowCount(ft, db, "events", 2))
+	ft.runCleanups()
+	assert.Equal(t, []string{
+		"CREATE OR REPLACE PROCEDURE `load_events` (batch QUERY(id INT)) AS BEGIN INSERT INTO events SELECT id FROM batch; END",
+		"CREATE PIPELINE `events` AS LOAD DATA S3 'bucket/events/'" +
+			` CONFIG '{"region": "us-east-1", "endpoint_url": "http://localhost:9000"}'` +
+			` CREDENTIALS '{"aws_access_key_id": "key", "aws_secret_access_key": "it\'s secret"}'` +
+			" INTO PROCEDURE load_events FORMAT CSV",
+		"START PIPELINE `events`",
+		"SELECT ERROR_MESSAGE FROM information_schema.PIPELINES_ERRORS WHERE DATABASE_NAME = DATABASE() AND PIPELINE_NAME = ?",
+		"SELECT COUNT(*) FROM `events`",
+		"STOP PIPELINE `events`",
+		"DROP PIPELINE `events`",
+		"DROP PROCEDURE IF EXISTS `load_events`",
+	}, executed)
+
+	ft = &failingT{cleanupT: &cleanupT{T: t}}
+	assert.False(t, ntest.AssertRowCount(ft, db, "events", 3))
+	assert.Equal(t, []string{"events has 2 rows, expected 3"}, ft.errors)
+}
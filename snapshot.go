@@ -0,0 +1,147 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// SnapshotDir is the directory, relative to the package being tested,
+// where snapshots are stored.
+const SnapshotDir = "testdata/snapshots"
+
+// UpdateSnapshotsEnv is the environment variable that, when set to a
+// true value (as parsed by strconv.ParseBool), causes Snapshot to write
+// snapshots instead of comparing against them.
+const UpdateSnapshotsEnv = "NTEST_UPDATE_SNAPSHOTS"
+
+var (
+	snapshotLock   sync.Mutex
+	snapshotCounts = make(map[string]int)
+)
+
+// Snapshot compares a rendered form of value against the snapshot
+// stored for this test. Strings and byte slices are used as-is.
+// Anything else is rendered as indented JSON.
+//
+// Snapshots are stored in SnapshotDir in files named after the test.
+// If a test takes more than one snapshot, the second and later are
+// numbered.
+//
+// When the snapshot differs, the test is marked as failed with a
+// unified diff. Run with NTEST_UPDATE_SNAPSHOTS=true to create or
+// update snapshots. A missing snapshot is a failure unless updating.
+func Snapshot(t T, value interface{}) bool {
+	t.Helper()
+	got, err := renderSnapshot(value)
+	if err != nil {
+		t.Fatalf("render snapshot: %s", err)
+	}
+	path := snapshotPath(t)
+	if updateSnapshots() {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create snapshot directory: %s", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write snapshot: %s", err)
+		}
+		t.Logf("updated snapshot %s", path)
+		return true
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			t.Errorf("snapshot %s does not exist; run with %s=true to create it", path, UpdateSnapshotsEnv)
+			return false
+		}
+		t.Fatalf("read snapshot: %s", err)
+	}
+	if bytes.Equal(want, got) {
+		return true
+	}
+	t.Errorf("snapshot %s does not match (run with %s=true to update):\n%s",
+		path, UpdateSnapshotsEnv, unifiedDiff(string(want), string(got), "snapshot", "actual"))
+	return false
+}
+
+func renderSnapshot(value interface{}) ([]byte, error) {
+	var b []byte
+	switch v := value.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		var err error
+		b, err = json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(b) == 0 || b[len(b)-1] != '\n' {
+		b = append(b, '\n')
+	}
+	return b, nil
+}
+
+func snapshotPath(t T) string {
+	testName := t.Name()
+	snapshotLock.Lock()
+	snapshotCounts[testName]++
+	count := snapshotCounts[testName]
+	snapshotLock.Unlock()
+	if count == 1 {
+		// start over for the next run of the test (with -count, say)
+		t.Cleanup(func() {
+			snapshotLock.Lock()
+			defer snapshotLock.Unlock()
+			delete(snapshotCounts, testName)
+		})
+	}
+	parts := strings.Split(testName, "/")
+	for i, part := range parts {
+		parts[i] = snapshotFileName(part)
+	}
+	name := filepath.Join(parts...)
+	if count > 1 {
+		name += fmt.Sprintf("-%d", count)
+	}
+	return filepath.Join(SnapshotDir, name+".snap")
+}
+
+func snapshotFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
+
+func updateSnapshots() bool {
+	return envBool(UpdateSnapshotsEnv)
+}
+
+// unifiedDiff returns a unified diff with a little context. It is used
+// for failure messages.
+func unifiedDiff(want, got, wantName, gotName string) string {
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(want),
+		B:        difflib.SplitLines(got),
+		FromFile: wantName,
+		ToFile:   gotName,
+		Context:  2,
+	})
+	if err != nil {
+		return fmt.Sprintf("(diff failed: %s)", err)
+	}
+	return diff
+}
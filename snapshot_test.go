@@ -0,0 +1,31 @@
+package ntest_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestSnapshot(t *testing.T) {
+	ntest.Snapshot(t, map[string]interface{}{
+		"name":  "widget",
+		"sizes": []int{1, 2, 3},
+	})
+
+	capture := &errorCapturingT{T: t}
+	assert.False(t, ntest.Snapshot(capture, "line one\nline two changed\n"))
+	if assert.Equal(t, 1, len(capture.errors)) {
+		assert.Contains(t, capture.errors[0], "-line two\n+line two changed\n")
+	}
+}
+
+func TestSnapshotRepeated(t *testing.T) {
+	// each run of a test, as with -count=2, starts with the first snapshot
+	for i := 0; i < 2; i++ {
+		run := &cleanupT{T: t}
+		assert.True(t, ntest.Snapshot(run, "same every run"))
+		run.runCleanups()
+	}
+}
@@ -0,0 +1,298 @@
+package ntest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// SQLLogSlowQueries is the number of queries listed when a test that
+// used an SQLLog fails.
+var SQLLogSlowQueries = 10
+
+// SQLLog opens databases whose queries are logged to a test with LogKV:
+// the query, its arguments, rows affected (for Exec), and latency. If the
+// test fails, the slowest queries are listed.
+type SQLLog struct {
+	T T
+	// RedactArgs, if set, is called with the arguments of each query and
+	// returns what should be logged instead, for example to hide
+	// passwords.
+	RedactArgs func(query string, args []interface{}) []interface{}
+	// Chaos, if set, injects faults into queries.
+	Chaos   *ChaosPolicy
+	timings timingTable
+}
+
+// SQLLogFixture provides an *SQLLog. Use it in the injector that opens
+// the database:
+//
+//	func(log *ntest.SQLLog) (*sql.DB, error) {
+//		return log.Open("mysql", dsn)
+//	}
+var SQLLogFixture = nject.Provide("sql-log", NewSQLLog)
+
+// NewSQLLog creates an SQLLog that logs to t.
+func NewSQLLog(t T) *SQLLog {
+	l := &SQLLog{T: t}
+	OnFailure(t, func(FailureContext) {
+		slowest := l.timings.slowest(SQLLogSlowQueries)
+		if len(slowest) == 0 {
+			return
+		}
+		var b strings.Builder
+		writeTimings(&b, "Slowest queries", slowest)
+		t.Log(b.String())
+	})
+	return l
+}
+
+// Open is like sql.Open but the queries made with the returned *sql.DB
+// are logged.
+func (l *SQLLog) Open(driverName, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	_ = db.Close()
+	if dc, ok := d.(driver.DriverContext); ok {
+		connector, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return l.OpenDB(connector), nil
+	}
+	return l.OpenDB(dsnConnector{dsn: dsn, driver: d}), nil
+}
+
+// OpenDB is like sql.OpenDB but the queries made with the returned
+// *sql.DB are logged.
+func (l *SQLLog) OpenDB(c driver.Connector) *sql.DB {
+	return sql.OpenDB(loggedConnector{Connector: c, log: l})
+}
+
+func (l *SQLLog) logQuery(kind string, query string, args []driver.NamedValue, start time.Time, result driver.Result, err error) {
+	elapsed := time.Since(start)
+	l.timings.add(query, elapsed)
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	if l.RedactArgs != nil {
+		values = l.RedactArgs(query, values)
+	}
+	pairs := []interface{}{"query", query}
+	if len(values) != 0 {
+		pairs = append(pairs, "args", values)
+	}
+	if result != nil {
+		if n, err := result.RowsAffected(); err == nil {
+			pairs = append(pairs, "rows_affected", n)
+		}
+	}
+	if err != nil {
+		pairs = append(pairs, "error", err)
+	}
+	LogKV(l.T, kind, append(pairs, "elapsed", elapsed)...)
+}
+
+// chaos applies the ChaosPolicy to a query that is about to be made.
+func (l *SQLLog) chaos(ctx context.Context, kind string, query string, args []driver.NamedValue) error {
+	action, err := l.Chaos.decide(ctx)
+	switch {
+	case err != nil:
+	case action == chaosError:
+		err = ErrChaos
+	case action == chaosDrop:
+		err = driver.ErrBadConn
+	default:
+		return nil
+	}
+	l.logQuery(kind, query, args, time.Now(), nil, err)
+	return err
+}
+
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.driver.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.driver }
+
+type loggedConnector struct {
+	driver.Connector
+	log *SQLLog
+}
+
+func (c loggedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return loggedConn{Conn: conn, log: c.log}, nil
+}
+
+// loggedConn implements the optional driver interfaces, falling back to
+// what database/sql would do when the wrapped connection does not.
+type loggedConn struct {
+	driver.Conn
+	log *SQLLog
+}
+
+var (
+	_ driver.ExecerContext      = loggedConn{}
+	_ driver.QueryerContext     = loggedConn{}
+	_ driver.ConnPrepareContext = loggedConn{}
+	_ driver.ConnBeginTx        = loggedConn{}
+	_ driver.Pinger             = loggedConn{}
+	_ driver.SessionResetter    = loggedConn{}
+	_ driver.NamedValueChecker  = loggedConn{}
+)
+
+func (c loggedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return loggedStmt{Stmt: stmt, query: query, log: c.log}, nil
+}
+
+func (c loggedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	pc, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := pc.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return loggedStmt{Stmt: stmt, query: query, log: c.log}, nil
+}
+
+func (c loggedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		// database/sql will prepare a statement instead
+		return nil, driver.ErrSkip
+	}
+	if err := c.log.chaos(ctx, "sql exec", query, args); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := execer.ExecContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.log.logQuery("sql exec", query, args, start, result, err)
+	}
+	return result, err
+}
+
+func (c loggedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	if err := c.log.chaos(ctx, "sql query", query, args); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rows, err := queryer.QueryContext(ctx, query, args)
+	if err != driver.ErrSkip {
+		c.log.logQuery("sql query", query, args, start, nil, err)
+	}
+	return rows, err
+}
+
+func (c loggedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if bt, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return bt.BeginTx(ctx, opts)
+	}
+	//nolint:staticcheck // Begin is the fallback for drivers without BeginTx
+	return c.Conn.Begin()
+}
+
+func (c loggedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c loggedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c loggedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c loggedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type loggedStmt struct {
+	driver.Stmt
+	query string
+	log   *SQLLog
+}
+
+var (
+	_ driver.StmtExecContext  = loggedStmt{}
+	_ driver.StmtQueryContext = loggedStmt{}
+)
+
+func (s loggedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	if err := s.log.chaos(ctx, "sql exec", s.query, args); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if ec, ok := s.Stmt.(driver.StmtExecContext); ok {
+		result, err = ec.ExecContext(ctx, args)
+	} else {
+		//nolint:staticcheck // Exec is the fallback for drivers without ExecContext
+		result, err = s.Stmt.Exec(namedValues(args))
+	}
+	s.log.logQuery("sql exec", s.query, args, start, result, err)
+	return result, err
+}
+
+func (s loggedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	if err := s.log.chaos(ctx, "sql query", s.query, args); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		//nolint:staticcheck // Query is the fallback for drivers without QueryContext
+		rows, err = s.Stmt.Query(namedValues(args))
+	}
+	s.log.logQuery("sql query", s.query, args, start, nil, err)
+	return rows, err
+}
+
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,110 @@
+package ntest_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+// fakeSQLDriver implements only the required database/sql/driver
+// interfaces so that the fallback paths of SQLLog are exercised.
+type fakeSQLDriver struct{}
+
+type fakeSQLConn struct{}
+
+type fakeSQLStmt struct{ query string }
+
+type fakeSQLRows struct{ remaining int }
+
+func init() {
+	sql.Register("ntest-fake", fakeSQLDriver{})
+}
+
+func (fakeSQLDriver) Open(string) (driver.Conn, error) { return fakeSQLConn{}, nil }
+
+func (fakeSQLConn) Prepare(query string) (driver.Stmt, error) { return fakeSQLStmt{query: query}, nil }
+func (fakeSQLConn) Close() error                              { return nil }
+func (fakeSQLConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (s fakeSQLStmt) Close() error  { return nil }
+func (s fakeSQLStmt) NumInput() int { return strings.Count(s.query, "?") }
+func (s fakeSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(3), nil
+}
+
+func (s fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeSQLRows{remaining: 2}, nil
+}
+
+func (r *fakeSQLRows) Columns() []string { return []string{"n"} }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if r.remaining == 0 {
+		return io.EOF
+	}
+	dest[0] = int64(r.remaining)
+	r.remaining--
+	return nil
+}
+
+func TestSQLLog(t *testing.T) {
+	var caught []string
+	captureT := ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		caught = append(caught, strings.TrimSpace(s))
+	})
+	ntest.RunTest(captureT, ntest.SQLLogFixture, func(log *ntest.SQLLog) (*sql.DB, error) {
+		log.RedactArgs = func(query string, args []interface{}) []interface{} {
+			if strings.Contains(query, "password") {
+				return []interface{}{args[0], "REDACTED"}
+			}
+			return args
+		}
+		return log.Open("ntest-fake", "")
+	}, func(db *sql.DB) {
+		_, err := db.Exec("UPDATE users SET password = ? WHERE id = ?", "hunter2", 7)
+		require.NoError(t, err)
+		rows, err := db.Query("SELECT n FROM numbers")
+		require.NoError(t, err)
+		var total int64
+		for rows.Next() {
+			var n int64
+			require.NoError(t, rows.Scan(&n))
+			total += n
+		}
+		require.NoError(t, rows.Close())
+		assert.Equal(t, int64(3), total)
+		require.NoError(t, db.Close())
+	})
+
+	require.Len(t, caught, 2)
+	assert.Regexp(t, `^sql exec query="UPDATE users SET password = \? WHERE id = \?" args="\[hunter2 REDACTED\]" rows_affected=3 elapsed=\S+$`, caught[0])
+	assert.Regexp(t, `^sql query query="SELECT n FROM numbers" elapsed=\S+$`, caught[1])
+}
+
+func TestSQLLogSlowestOnFailure(t *testing.T) {
+	var caught []string
+	ct := &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		t.Log("captured:", s)
+		caught = append(caught, s)
+	})}
+	log := ntest.NewSQLLog(failedT{ct})
+	db, err := log.Open("ntest-fake", "")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = db.Exec("DELETE FROM a")
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+	ct.runCleanups()
+
+	require.Len(t, caught, 3)
+	assert.Regexp(t, `Slowest queries:\n +\S+ +2x +max +\S+  DELETE FROM a\n`, caught[2])
+}
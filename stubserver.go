@@ -0,0 +1,284 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// StubServer is an HTTP server for purely synthetic dependencies: the
+// test declares which requests it expects, with On, and what to reply.
+// Requests that no stub matches get a 404 and fail the test when it
+// finishes, as do stubs that were not called as often as expected.
+//
+//	server.On("GET", "/v1/users/7").ReplyJSON(200, user).Times(1)
+//	server.On("POST", "/v1/events").WithHeader("Authorization", "Bearer x").Reply(202, "")
+type StubServer struct {
+	URL string
+	t   T
+	mu  sync.Mutex
+	// guarded by mu
+	stubs     []*Stub
+	unmatched []string
+}
+
+// Stub matches requests to a StubServer and replies to them. Its
+// methods return the Stub so they can be chained.
+type Stub struct {
+	server   *StubServer
+	method   string
+	path     string
+	header   http.Header
+	query    map[string]string
+	body     func([]byte) bool
+	bodyDesc string
+	status   int
+	reply    []byte
+	replyHdr http.Header
+	min      int
+	max      int // -1 for no limit
+	calls    int
+}
+
+// StubServerFixture provides a *StubServer.
+var StubServerFixture = nject.Provide("stub-server", func(t T) *StubServer {
+	return NewStubServer(t)
+})
+
+// NewStubServer starts a StubServer that is closed, and checked, when
+// the test finishes.
+func NewStubServer(t T) *StubServer {
+	s := &StubServer{t: t}
+	t.Cleanup(s.check)
+	server := httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(server.Close)
+	s.URL = server.URL
+	return s
+}
+
+// On adds a stub for requests with method (any method if "") and path.
+// Unless changed, it replies 200 with an empty body and may be called
+// any number of times. When several stubs match a request, the one
+// added last is used.
+func (s *StubServer) On(method, path string) *Stub {
+	stub := &Stub{
+		server:   s,
+		method:   method,
+		path:     path,
+		header:   make(http.Header),
+		query:    make(map[string]string),
+		status:   http.StatusOK,
+		replyHdr: make(http.Header),
+		max:      -1,
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stubs = append(s.stubs, stub)
+	return stub
+}
+
+// Unmatched returns the method and URL of the requests that no stub
+// matched.
+func (s *StubServer) Unmatched() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unmatched...)
+}
+
+// WithHeader requires the request to have header key with value.
+func (stub *Stub) WithHeader(key, value string) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.header.Add(key, value)
+	return stub
+}
+
+// WithQuery requires the request to have query parameter key with value.
+func (stub *Stub) WithQuery(key, value string) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.query[key] = value
+	return stub
+}
+
+// WithBody requires the request body to contain s.
+func (stub *Stub) WithBody(s string) *Stub {
+	return stub.WithBodyFunc(fmt.Sprintf("body containing %q", s), func(body []byte) bool {
+		return bytes.Contains(body, []byte(s))
+	})
+}
+
+// WithBodyFunc requires match to accept the request body. The
+// description is used when describing the stub.
+func (stub *Stub) WithBodyFunc(description string, match func([]byte) bool) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.body = match
+	stub.bodyDesc = description
+	return stub
+}
+
+// Reply sets the status and body of the response.
+func (stub *Stub) Reply(status int, body string) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.status = status
+	stub.reply = []byte(body)
+	return stub
+}
+
+// ReplyJSON sets the status of the response and its body to v encoded
+// as JSON.
+func (stub *Stub) ReplyJSON(status int, v interface{}) *Stub {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		stub.server.t.Fatalf("stub %s: encode reply: %s", stub, err)
+	}
+	stub.ReplyHeader("Content-Type", "application/json")
+	return stub.Reply(status, string(enc))
+}
+
+// ReplyHeader adds a header to the response.
+func (stub *Stub) ReplyHeader(key, value string) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.replyHdr.Add(key, value)
+	return stub
+}
+
+// Times requires the stub to be called exactly n times by the time the
+// test finishes.
+func (stub *Stub) Times(n int) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.min, stub.max = n, n
+	return stub
+}
+
+// AtLeast requires the stub to be called at least n times by the time
+// the test finishes.
+func (stub *Stub) AtLeast(n int) *Stub {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	stub.min, stub.max = n, -1
+	return stub
+}
+
+// Calls returns how many requests the stub has replied to.
+func (stub *Stub) Calls() int {
+	stub.server.mu.Lock()
+	defer stub.server.mu.Unlock()
+	return stub.calls
+}
+
+func (stub *Stub) String() string {
+	method := stub.method
+	if method == "" {
+		method = "*"
+	}
+	desc := method + " " + stub.path
+	keys := make([]string, 0, len(stub.header))
+	for key := range stub.header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range stub.header[key] {
+			desc += fmt.Sprintf(" %s: %s", key, value)
+		}
+	}
+	keys = keys[:0]
+	for key := range stub.query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		desc += fmt.Sprintf(" ?%s=%s", key, stub.query[key])
+	}
+	if stub.body != nil {
+		desc += " with " + stub.bodyDesc
+	}
+	return desc
+}
+
+// matches is called with the server lock held
+func (stub *Stub) matches(r *http.Request, body []byte) bool {
+	if stub.method != "" && stub.method != r.Method {
+		return false
+	}
+	if stub.path != r.URL.Path {
+		return false
+	}
+	for key, values := range stub.header {
+		for _, value := range values {
+			if !containsString(r.Header.Values(key), value) {
+				return false
+			}
+		}
+	}
+	query := r.URL.Query()
+	for key, value := range stub.query {
+		if !containsString(query[key], value) {
+			return false
+		}
+	}
+	return stub.body == nil || stub.body(body)
+}
+
+func (s *StubServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.mu.Lock()
+	var stub *Stub
+	for i := len(s.stubs) - 1; i >= 0; i-- {
+		if s.stubs[i].matches(r, body) {
+			stub = s.stubs[i]
+			break
+		}
+	}
+	if stub == nil {
+		s.unmatched = append(s.unmatched, r.Method+" "+r.URL.String())
+		s.mu.Unlock()
+		http.Error(w, "ntest: no stub matches "+r.Method+" "+r.URL.String(), http.StatusNotFound)
+		return
+	}
+	stub.calls++
+	status, reply := stub.status, stub.reply
+	for key, values := range stub.replyHdr {
+		w.Header()[key] = append([]string(nil), values...)
+	}
+	s.mu.Unlock()
+	w.WriteHeader(status)
+	_, _ = w.Write(reply)
+}
+
+func (s *StubServer) check() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var problems []string
+	for _, stub := range s.stubs {
+		switch {
+		case stub.min == stub.max && stub.calls != stub.min:
+			problems = append(problems, fmt.Sprintf("%s: called %d times, expected %d", stub, stub.calls, stub.min))
+		case stub.calls < stub.min:
+			problems = append(problems, fmt.Sprintf("%s: called %d times, expected at least %d", stub, stub.calls, stub.min))
+		}
+	}
+	for _, request := range s.unmatched {
+		problems = append(problems, "no stub matched "+request)
+	}
+	if len(problems) != 0 {
+		s.t.Errorf("stub server %s:\n\t%s", s.URL, strings.Join(problems, "\n\t"))
+	}
+}
@@ -0,0 +1,59 @@
+package ntest_test
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestStubServer(t *testing.T) {
+	t.Parallel()
+	ft := &failingT{cleanupT: &cleanupT{T: t}}
+	var server *ntest.StubServer
+	ntest.RunTest(ft, ntest.StubServerFixture, func(s *ntest.StubServer) {
+		server = s
+		do := func(method, path, body string, header ...string) (int, string) {
+			req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+			require.NoError(t, err)
+			for i := 0; i+1 < len(header); i += 2 {
+				req.Header.Set(header[i], header[i+1])
+			}
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			b, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			return resp.StatusCode, string(b)
+		}
+
+		user := s.On("GET", "/users/7").ReplyJSON(http.StatusOK, map[string]string{"name": "ann"}).Times(1)
+		s.On("GET", "/users/7").WithQuery("fields", "id").Reply(http.StatusOK, "7")
+		s.On("POST", "/events").WithHeader("Authorization", "Bearer x").WithBody(`"kind":"login"`).Reply(http.StatusAccepted, "")
+		s.On("", "/never").AtLeast(1)
+		s.On("DELETE", "/users/7").Times(0)
+
+		status, body := do("GET", "/users/7", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `{"name":"ann"}`, body)
+		_, body = do("GET", "/users/7?fields=id", "")
+		assert.Equal(t, "7", body, "later stub wins")
+		status, _ = do("POST", "/events", `{"kind":"login"}`, "Authorization", "Bearer x")
+		assert.Equal(t, http.StatusAccepted, status)
+		status, _ = do("POST", "/events", `{"kind":"logout"}`, "Authorization", "Bearer x")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, 1, user.Calls())
+		assert.Empty(t, ft.errors, "checked when the test finishes")
+	})
+	ft.runCleanups()
+	assert.Equal(t, []string{"POST /events"}, server.Unmatched())
+	require.Equal(t, 1, len(ft.errors))
+	assert.Equal(t, "stub server "+server.URL+":"+
+		"\n\t* /never: called 0 times, expected at least 1"+
+		"\n\tno stub matched POST /events", ft.errors[0])
+}
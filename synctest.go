@@ -0,0 +1,35 @@
+//go:build go1.25
+
+package ntest
+
+import (
+	"context"
+	"testing"
+	"testing/synctest"
+
+	"github.com/muir/nject"
+)
+
+// SyncWait is injected by RunSynctest. It is synctest.Wait: it blocks
+// until every other goroutine in the bubble is durably blocked.
+type SyncWait func()
+
+// RunSynctest is like RunTest except that the chain runs inside a
+// testing/synctest bubble where time is fake: time only advances when
+// every goroutine in the bubble is blocked. In addition to T and
+// *testing.T, it injects a context.Context (canceled when the test
+// finishes) and a SyncWait.
+//
+// Cleanup functions registered by injectors run inside the bubble, so
+// goroutines that they stop must exit before the bubble finishes.
+//
+// RunSynctest requires Go 1.25; with earlier versions the test is skipped.
+func RunSynctest(t *testing.T, chain ...interface{}) {
+	synctest.Test(t, func(t *testing.T) {
+		RunTest(t,
+			nject.Provide("synctest-context", func() context.Context { return t.Context() }),
+			nject.Provide("synctest-wait", func() SyncWait { return synctest.Wait }),
+			nject.Sequence("synctest-chain", chain...),
+		)
+	})
+}
@@ -0,0 +1,15 @@
+//go:build !go1.25
+
+package ntest
+
+import "testing"
+
+// SyncWait is injected by RunSynctest. It is synctest.Wait: it blocks
+// until every other goroutine in the bubble is durably blocked.
+type SyncWait func()
+
+// RunSynctest requires Go 1.25 or later. With this version of Go, it
+// skips the test.
+func RunSynctest(t *testing.T, chain ...interface{}) {
+	t.Skip("RunSynctest requires Go 1.25 or later")
+}
@@ -0,0 +1,48 @@
+package ntest_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestRunSynctest(t *testing.T) {
+	t.Parallel()
+	ntest.RunSynctest(t, func(ctx context.Context, t ntest.T, wait ntest.SyncWait) {
+		start := time.Now()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			select {
+			case <-time.After(time.Hour):
+			case <-ctx.Done():
+			}
+		}()
+		wait()
+		select {
+		case <-done:
+			t.Fatal("finished too early")
+		default:
+		}
+		<-done
+		assert.Equal(t, time.Hour, time.Since(start), "fake time")
+	})
+}
+
+func TestRunSynctestEventually(t *testing.T) {
+	t.Parallel()
+	ntest.RunSynctest(t, func(t ntest.T) {
+		start := time.Now()
+		assert.True(t, ntest.Eventually(t, func() error {
+			if time.Since(start) < time.Minute {
+				return fmt.Errorf("too soon")
+			}
+			return nil
+		}, ntest.EventuallyOptions{Timeout: time.Hour, Interval: time.Minute}))
+	})
+}
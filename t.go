@@ -42,18 +42,30 @@ func ReplaceLogger(t T, logger func(string)) T {
 }
 
 func (t logWrappedT) Log(args ...interface{}) {
-	line := fmt.Sprintln(args...)
-	t.logger(line[0 : len(line)-1])
+	t.logger(sprintln(args...))
 }
 
 func (t logWrappedT) Logf(format string, args ...interface{}) {
 	t.logger(fmt.Sprintf(format, args...))
 }
 
+// ExtraDetailCaller makes ExtraDetailLogger include the short name of
+// the function that called Log or Logf (like "ntest_test.TestFoo.func1"), skipping
+// functions in the testing, testify, nject, and ntest packages.
+var ExtraDetailCaller = false
+
 // ExtraDetailLogger creates a T that wraps the logger to add both a
-// prefix and a timestamp to each line that is logged.
+// prefix and a timestamp to each line that is logged. If
+// ExtraDetailCaller is true, the name of the calling function is added
+// too.
 func ExtraDetailLogger(t T, prefix string) T {
 	return ReplaceLogger(t, func(s string) {
+		if ExtraDetailCaller {
+			if frame, ok := callerFrame(); ok {
+				t.Log(prefix, time.Now().Format("15:04:05"), shortFuncName(frame.Function), s)
+				return
+			}
+		}
 		t.Log(prefix, time.Now().Format("15:04:05"), s)
 	})
 }
@@ -0,0 +1,96 @@
+package ntest
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/muir/nject"
+)
+
+// TableDump selects what DumpTables records about a database.
+type TableDump struct {
+	Tables []string
+	// Rows is how many rows of each table are written, in full, to the
+	// ArtifactDir of the test. Zero records only the row counts and a
+	// negative number writes every row.
+	Rows int
+}
+
+// TableDumpFixture returns an injector that applies DumpTablesOnFailure
+// to the *sql.DB of the test.
+func TableDumpFixture(dump TableDump) nject.Provider {
+	return nject.Required(nject.Provide("table-dump", func(t T, db *sql.DB) {
+		DumpTablesOnFailure(t, db, dump)
+	}))
+}
+
+// DumpTablesOnFailure calls DumpTables if the test fails. Call it (or
+// use TableDumpFixture) after the database is created, so that the dump
+// happens before cleanup functions drop the schema or close the
+// database.
+func DumpTablesOnFailure(t T, db *sql.DB, dump TableDump) {
+	OnFailure(t, func(FailureContext) {
+		DumpTables(t, db, dump)
+	})
+}
+
+// DumpTables logs the number of rows in each of dump.Tables and writes
+// up to dump.Rows rows of each, ordered by the first column, to
+// db/<table>.json in the ArtifactDir of the test, so that a failure that
+// depends on the data can be investigated after the database is gone.
+// The files are JSON arrays of objects, which SQLSeedStore can load back
+// into a database.
+func DumpTables(t T, db *sql.DB, dump TableDump) {
+	t.Helper()
+	ctx := context.Background()
+	lines := make([]string, 0, len(dump.Tables))
+	for _, table := range dump.Tables {
+		var count int64
+		if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&count); err != nil {
+			lines = append(lines, fmt.Sprintf("%s: %s", table, err))
+			continue
+		}
+		line := fmt.Sprintf("%s: %d rows", table, count)
+		if dump.Rows != 0 && count != 0 {
+			path, written, err := writeTable(ctx, t, db, table, dump.Rows)
+			if err != nil {
+				line += fmt.Sprintf(", could not write them: %s", err)
+			} else {
+				line += fmt.Sprintf(", %d written to %s", written, path)
+			}
+		}
+		lines = append(lines, line)
+	}
+	t.Logf("database tables of %s:\n\t%s", t.Name(), strings.Join(lines, "\n\t"))
+}
+
+func writeTable(ctx context.Context, t T, db *sql.DB, table string, limit int) (string, int, error) {
+	// Fatalf would abort the test for the sake of a diagnostic
+	dir, err := artifactDir(t)
+	if err != nil {
+		return "", 0, err
+	}
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+table+" ORDER BY 1")
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+	result, err := scanRows(rows, limit)
+	if err != nil {
+		return "", 0, err
+	}
+	enc, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(dir, "db", artifactSegment(table)+".json")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", 0, err
+	}
+	return path, len(result), os.WriteFile(path, append(enc, '\n'), 0o644)
+}
@@ -0,0 +1,66 @@
+package ntest_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTableDumpFixture(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	var logged []string
+	ct := &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		logged = append(logged, s)
+	})}
+	ntest.RunTest(failedT{ct},
+		func() (*sql.DB, error) { return sql.Open("ntest-fake", "") },
+		ntest.TableDumpFixture(ntest.TableDump{Tables: []string{"users", "orders"}, Rows: 1}),
+		func(*sql.DB) {})
+	ct.runCleanups()
+
+	require.Len(t, logged, 1)
+	path := filepath.Join(root, "TestTableDumpFixture", "db", "users.json")
+	assert.Contains(t, logged[0], "database tables of TestTableDumpFixture:\n\tusers: 2 rows, 1 written to "+path)
+	assert.Contains(t, logged[0], "\n\torders: 2 rows, 1 written to ")
+	enc, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var rows []map[string]interface{}
+	require.NoError(t, json.Unmarshal(enc, &rows))
+	assert.Equal(t, []map[string]interface{}{{"n": 2.0}}, rows)
+
+	logged = nil
+	ct = &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+		logged = append(logged, s)
+	})}
+	ntest.RunTest(ct,
+		func() (*sql.DB, error) { return sql.Open("ntest-fake", "") },
+		ntest.TableDumpFixture(ntest.TableDump{Tables: []string{"users"}}),
+		func(*sql.DB) {})
+	ct.runCleanups()
+	for _, s := range logged {
+		assert.False(t, strings.Contains(s, "database tables"), "not dumped for tests that pass")
+	}
+}
+
+func TestDumpTablesCountsOnly(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	db, err := sql.Open("ntest-fake", "")
+	require.NoError(t, err)
+	defer db.Close()
+	var logged string
+	ntest.DumpTables(ntest.ReplaceLogger(t, func(s string) { logged += s }), db, ntest.TableDump{Tables: []string{"users"}})
+	assert.Contains(t, logged, "\tusers: 2 rows")
+	assert.NotContains(t, logged, "written")
+	_, err = os.Stat(filepath.Join(root, "TestDumpTablesCountsOnly"))
+	assert.True(t, os.IsNotExist(err), "no artifacts for counts")
+}
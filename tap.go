@@ -0,0 +1,98 @@
+package ntest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TAPEnv names the environment variable that enables a TAPReporter. Set
+// it to a file path, or to "-" for standard output.
+const TAPEnv = "NTEST_TAP"
+
+func enableTAP(path string) error {
+	if path == "-" {
+		AddReporter(NewTAPReporter(os.Stdout))
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create TAP output: %w", err)
+	}
+	AddReporter(NewTAPReporter(f))
+	return nil
+}
+
+// TAPReporter is a Reporter that writes Test Anything Protocol (version 13)
+// lines as each test finishes. The plan line is written by Close, so use
+// Main to get a complete TAP stream.
+type TAPReporter struct {
+	w     io.Writer
+	mu    sync.Mutex
+	count int
+}
+
+var _ Reporter = &TAPReporter{}
+
+// NewTAPReporter creates a TAPReporter writing to w. If w is an io.Closer
+// other than os.Stdout, it is closed by Close.
+func NewTAPReporter(w io.Writer) *TAPReporter {
+	return &TAPReporter{w: w}
+}
+
+func (r *TAPReporter) TestStarted(string, time.Time) {}
+
+func (r *TAPReporter) TestFinished(result TestResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count == 0 {
+		fmt.Fprintln(r.w, "TAP version 13")
+	}
+	r.count++
+	status := "ok"
+	if result.Failed {
+		status = "not ok"
+	}
+	line := fmt.Sprintf("%s %d - %s", status, r.count, tapEscape(result.Name))
+	if result.Skipped && !result.Failed {
+		line += " # SKIP"
+		if reason := firstLine(result.Messages); reason != "" {
+			line += " " + tapEscape(reason)
+		}
+	}
+	fmt.Fprintln(r.w, line)
+	if result.Failed {
+		fmt.Fprintln(r.w, "  ---")
+		fmt.Fprintf(r.w, "  duration_ms: %d\n", result.Duration.Milliseconds())
+		if len(result.Messages) != 0 {
+			fmt.Fprintln(r.w, "  message: |")
+			for _, msg := range result.Messages {
+				for _, l := range strings.Split(msg, "\n") {
+					fmt.Fprintln(r.w, "    "+l)
+				}
+			}
+		}
+		fmt.Fprintln(r.w, "  ...")
+	}
+}
+
+func (r *TAPReporter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.count == 0 {
+		fmt.Fprintln(r.w, "TAP version 13")
+	}
+	fmt.Fprintf(r.w, "1..%d\n", r.count)
+	if closer, ok := r.w.(io.Closer); ok && r.w != os.Stdout {
+		return closer.Close()
+	}
+	return nil
+}
+
+// tapEscape escapes characters that have meaning in a TAP description.
+func tapEscape(s string) string {
+	return strings.NewReplacer("\\", "\\\\", "#", "\\#", "\n", " ").Replace(s)
+}
@@ -0,0 +1,39 @@
+package ntest_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTAPReporter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	reporter := ntest.NewTAPReporter(&buf)
+	reporter.TestFinished(ntest.TestResult{Name: "TestA/cell#1"})
+	reporter.TestFinished(ntest.TestResult{Name: "TestB", Skipped: true, Messages: []string{"no db"}})
+	reporter.TestFinished(ntest.TestResult{
+		Name:     "TestC",
+		Failed:   true,
+		Duration: 1500 * time.Millisecond,
+		Messages: []string{"want 1\ngot 2"},
+	})
+	require.NoError(t, reporter.Close())
+	assert.Equal(t, `TAP version 13
+ok 1 - TestA/cell\#1
+ok 2 - TestB # SKIP no db
+not ok 3 - TestC
+  ---
+  duration_ms: 1500
+  message: |
+    want 1
+    got 2
+  ...
+1..3
+`, buf.String())
+}
@@ -0,0 +1,86 @@
+package ntest
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TeamCityEnv names the environment variable that, when set to a true
+// value, enables a TeamCityReporter writing to standard output. It is
+// meant to be set in the TeamCity build configuration; ntest does not
+// turn it on just because it is running under TeamCity.
+const TeamCityEnv = "NTEST_TEAMCITY"
+
+func enableTeamCity(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return err
+	}
+	if enabled {
+		AddReporter(NewTeamCityReporter(os.Stdout))
+	}
+	return nil
+}
+
+// TeamCityReporter is a Reporter that writes TeamCity service messages for
+// each test, including each matrix cell. Tests are identified by their
+// full name so that nested subtests are reported correctly. Set
+// NTEST_TEAMCITY=true in the build configuration to enable it.
+type TeamCityReporter struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+var _ Reporter = &TeamCityReporter{}
+
+// NewTeamCityReporter creates a TeamCityReporter that writes to w.
+func NewTeamCityReporter(w io.Writer) *TeamCityReporter {
+	return &TeamCityReporter{w: w}
+}
+
+func (r *TeamCityReporter) TestStarted(name string, start time.Time) {
+	r.message("testStarted", "name", name, "flowId", name, "timestamp", teamCityTime(start))
+}
+
+func (r *TeamCityReporter) TestFinished(result TestResult) {
+	switch {
+	case result.Failed:
+		r.message("testFailed", "name", result.Name, "flowId", result.Name,
+			"message", firstLine(result.Messages),
+			"details", strings.Join(combineSlices(result.Messages, result.Log), "\n"))
+	case result.Skipped:
+		r.message("testIgnored", "name", result.Name, "flowId", result.Name,
+			"message", firstLine(result.Messages))
+	}
+	r.message("testFinished", "name", result.Name, "flowId", result.Name,
+		"duration", fmt.Sprint(result.Duration.Milliseconds()))
+}
+
+func (r *TeamCityReporter) Close() error { return nil }
+
+// message writes a service message; attrs are name/value pairs.
+func (r *TeamCityReporter) message(kind string, attrs ...string) {
+	var b strings.Builder
+	b.WriteString("##teamcity[")
+	b.WriteString(kind)
+	for i := 0; i+1 < len(attrs); i += 2 {
+		fmt.Fprintf(&b, " %s='%s'", attrs[i], teamCityEscape(attrs[i+1]))
+	}
+	b.WriteString("]\n")
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, _ = io.WriteString(r.w, b.String())
+}
+
+func teamCityTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000-0700")
+}
+
+func teamCityEscape(s string) string {
+	return strings.NewReplacer("|", "||", "'", "|'", "\n", "|n", "\r", "|r", "[", "|[", "]", "|]").Replace(s)
+}
@@ -0,0 +1,44 @@
+package ntest_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTeamCityReporter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	reporter := ntest.NewTeamCityReporter(&buf)
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	reporter.TestStarted("TestA/db=[mysql]", start)
+	reporter.TestFinished(ntest.TestResult{
+		Name:     "TestA/db=[mysql]",
+		Failed:   true,
+		Duration: 2 * time.Second,
+		Messages: []string{"it's broken"},
+		Log:      []string{"a|b"},
+	})
+	reporter.TestFinished(ntest.TestResult{Name: "TestB", Skipped: true, Messages: []string{"later"}})
+	assert.Equal(t, `##teamcity[testStarted name='TestA/db=|[mysql|]' flowId='TestA/db=|[mysql|]' timestamp='2024-05-06T07:08:09.000+0000']
+##teamcity[testFailed name='TestA/db=|[mysql|]' flowId='TestA/db=|[mysql|]' message='it|'s broken' details='it|'s broken|na||b']
+##teamcity[testFinished name='TestA/db=|[mysql|]' flowId='TestA/db=|[mysql|]' duration='2000']
+##teamcity[testIgnored name='TestB' flowId='TestB' message='later']
+##teamcity[testFinished name='TestB' flowId='TestB' duration='0']
+`, buf.String())
+}
+
+func TestTeamCityReporterOptIn(t *testing.T) {
+	if failInChild(t) {
+		return
+	}
+	out := runInChild(t, "^TestTeamCityReporterOptIn$", "TEAMCITY_VERSION=2024.1", "NTEST_TEAMCITY=")
+	assert.NotContains(t, out, "##teamcity", "not enabled by TEAMCITY_VERSION alone")
+	assert.Contains(t, out, "--- FAIL: TestTeamCityReporterOptIn")
+	out = runInChild(t, "^TestTeamCityReporterOptIn$", "TEAMCITY_VERSION=2024.1", "NTEST_TEAMCITY=true")
+	assert.Contains(t, out, "##teamcity[testFailed name='TestTeamCityReporterOptIn'")
+}
@@ -1,9 +1,7 @@
 package ntest_test
 
 import (
-	"sync"
 	"testing"
-	"time"
 
 	"github.com/muir/nject"
 	"github.com/stretchr/testify/assert"
@@ -31,49 +29,39 @@ func TestRun(t *testing.T) {
 }
 
 func TestParallelMatrix(t *testing.T) {
-	var mu sync.Mutex
-	doneA := make(chan struct{})
-	doneB := make(chan struct{})
-	testsRun := make(map[string]struct{})
+	doneA := ntest.NewSignal("testA done")
+	doneB := ntest.NewSignal("testB done")
+	board := ntest.NewBlackboard()
 	ntest.RunParallelMatrix(t,
 		func() int { return 7 },
+		ntest.BlackboardFixture(board),
 		map[string]nject.Provider{
 			"testA": nject.Provide("testA",
-				func(t ntest.T) (string, chan struct{}) {
+				func(t ntest.T) (string, *ntest.Signal) {
 					return t.Name(), doneA
 				}),
 			"testB": nject.Sequence("testB",
 				func(t ntest.T, _ int) string { return t.Name() },
-				func(t ntest.T) chan struct{} {
+				func(t ntest.T) *ntest.Signal {
 					return doneB
 				},
 			),
 		},
-		func(t *testing.T, s string, c chan struct{}) {
+		func(t *testing.T, s string, done *ntest.Signal, b *ntest.Blackboard) {
 			t.Logf("final func for %s", t.Name())
 			t.Logf("s = %s", s)
-			mu.Lock()
-			defer mu.Unlock()
-			testsRun[s] = struct{}{}
-			close(c)
+			ntest.Publish(b, s, t.Name())
+			done.Fire()
 		},
 	)
 	t.Run("validate", func(t *testing.T) {
 		t.Parallel()
-		select {
-		case <-doneA:
-		case <-time.After(time.Second):
-			require.False(t, true, "timeout")
-		}
-		select {
-		case <-doneB:
-		case <-time.After(time.Second):
-			require.False(t, true, "timeout")
-		}
-		assert.Equal(t, map[string]struct{}{
-			"TestParallelMatrix/testA": {},
-			"TestParallelMatrix/testB": {},
-		}, testsRun)
+		doneA.Wait(t)
+		doneB.Wait(t)
+		assert.Equal(t, map[string]string{
+			"TestParallelMatrix/testA": "TestParallelMatrix/testA",
+			"TestParallelMatrix/testB": "TestParallelMatrix/testB",
+		}, ntest.AllPublished[string](board))
 	})
 }
 
@@ -130,3 +118,116 @@ func TestEmptyMatrix(t *testing.T) {
 		},
 	)
 }
+
+func TestMatrixCellInfo(t *testing.T) {
+	t.Parallel()
+	var cells []ntest.CellInfo
+	ntest.RunMatrix(t,
+		map[string]nject.Provider{
+			"mysql":    nject.Provide("mysql", func() string { return "mysql" }),
+			"postgres": nject.Provide("postgres", func() string { return "postgres" }),
+		},
+		map[string]nject.Provider{
+			"plain": nject.Provide("plain", func() bool { return false }),
+			"tls":   nject.Provide("tls", func() bool { return true }),
+			"zstd":  nject.Provide("zstd", func() bool { return false }),
+		},
+		func(info ntest.CellInfo, _ string, _ bool) {
+			cells = append(cells, info)
+		},
+	)
+	require.Equal(t, 6, len(cells))
+	for i, info := range cells {
+		assert.Equal(t, i, info.Index)
+		assert.Equal(t, 6, info.Total)
+	}
+	assert.Equal(t, "mysql/plain", cells[0].Path)
+	assert.Equal(t, "cell 5/6 (postgres/tls)", cells[4].String())
+}
+
+func TestMatrixResults(t *testing.T) {
+	t.Parallel()
+	results := ntest.RunMatrix(t,
+		map[string]nject.Provider{
+			"plain": nject.Provide("plain", func() bool { return false }),
+			"tls":   nject.Provide("tls", func() bool { return true }),
+		},
+		func(t ntest.T, tls bool) {
+			if tls {
+				t.Skip("no certificates")
+			}
+		},
+	)
+	cells := results.Cells()
+	require.Equal(t, 2, len(cells))
+	assert.Equal(t, "TestMatrixResults/plain", cells[0].Name)
+	assert.False(t, cells[0].Skipped)
+	assert.True(t, cells[1].Skipped)
+	assert.Equal(t, "no certificates", cells[1].SkipReason)
+	assert.Equal(t, "tls", cells[1].Path)
+	assert.Equal(t, 1, results.Ran())
+}
+
+func TestMatrixSkipsFromProviders(t *testing.T) {
+	t.Parallel()
+	// reporters capture the messages of skips on ntest.T
+	t.Cleanup(ntest.AddReporter(&recordingReporter{prefix: t.Name() + "/"}))
+	var results *ntest.MatrixResults
+	t.Run("matrix", func(t *testing.T) {
+		results = ntest.RunMatrix(t,
+			map[string]nject.Provider{
+				"ntest":   nject.Provide("ntest", func(t ntest.T) int { t.Skip("no service"); return 1 }),
+				"testing": nject.Provide("testing", func(t *testing.T) int { t.Skip("no service"); return 2 }),
+				"cell":    nject.Provide("cell", func(t *testing.T) int { ntest.SkipCell(t, "no %s", "cluster"); return 3 }),
+				"error":   nject.Provide("error", func(t ntest.T) (int, error) { t.Skipf("no %s", "disk"); return 4, nil }),
+				"runs":    nject.Provide("runs", func() int { return 5 }),
+			},
+			func(int) {},
+		)
+	})
+	assert.Equal(t, 1, results.Passed())
+	assert.Equal(t, 0, results.Failed())
+	assert.Equal(t, 4, results.Skipped())
+	assert.Equal(t, 1, results.Ran())
+	reasons := make(map[string]string)
+	for _, cell := range results.Cells() {
+		if cell.Skipped {
+			reasons[cell.Path] = cell.SkipReason
+		}
+	}
+	assert.Equal(t, map[string]string{
+		"cell":    "no cluster",
+		"error":   "no disk",
+		"ntest":   "no service",
+		"testing": "",
+	}, reasons)
+	assert.Equal(t, "5 cells: 1 passed, 0 failed, 4 skipped"+
+		"\n\tcell skipped: no cluster"+
+		"\n\terror skipped: no disk"+
+		"\n\tntest skipped: no service"+
+		"\n\ttesting skipped: no reason given", results.Summary())
+}
+
+func TestRunTestT(t *testing.T) {
+	var called int
+	ntest.RunTestT(t, func(tt *testing.T, nt ntest.T) {
+		assert.Same(t, t, tt)
+		assert.Equal(t, t.Name(), nt.Name())
+		called++
+	})
+
+	capture := &errorCapturingT{T: t}
+	ntest.RunTestT(capture, func(ct *errorCapturingT) {
+		ct.Errorf("recorded %d", 1)
+		called++
+	})
+	assert.Equal(t, []string{"recorded 1"}, capture.errors)
+
+	testing.Benchmark(func(b *testing.B) {
+		ntest.RunTestT(b, func(bb *testing.B) {
+			assert.Same(t, b, bb)
+			called++
+		})
+	})
+	assert.GreaterOrEqual(t, called, 3)
+}
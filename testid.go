@@ -0,0 +1,136 @@
+package ntest
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// TestID is a unique identifier for a single test run. It combines
+// a sanitized version of the test name with a ULID so that it sorts by
+// creation time and can be traced back to the test that created it.
+//
+// TestID is intended for naming external resources: topics, buckets,
+// schemas, etc. It only contains lowercase letters, digits, and dashes.
+type TestID string
+
+// maxTestIDNameLength limits how much of the test name is included in a
+// TestID so that the result stays usable as a resource name.
+const maxTestIDNameLength = 36
+
+// TaggedResources tracks external resources that were named with a TestID.
+// Fixtures Add resources when they create them and Release them when they are
+// torn down. Anything not released by the end of the test is listed in the
+// test log so that it can be found and removed by hand.
+type TaggedResources struct {
+	ID        TestID
+	mu        sync.Mutex
+	resources []taggedResource
+}
+
+type taggedResource struct {
+	kind     string
+	name     string
+	released bool
+}
+
+// TestIDFixture provides a TestID and *TaggedResources.
+var TestIDFixture = nject.Provide("test-id", func(t T) (TestID, *TaggedResources) {
+	id := NewTestID(t)
+	Attr(t, "ntest.test-id", string(id))
+	resources := &TaggedResources{ID: id}
+	t.Cleanup(func() {
+		resources.report(t)
+	})
+	return id, resources
+})
+
+// NewTestID creates a new TestID for a test.
+func NewTestID(t T) TestID {
+	name := sanitizeName(t.Name())
+	if len(name) > maxTestIDNameLength {
+		name = strings.TrimRight(name[:maxTestIDNameLength], "-")
+	}
+	return TestID(name + "-" + strings.ToLower(newULID(time.Now())))
+}
+
+// Add records that a resource of the given kind (eg: "topic") has been
+// created.
+func (r *TaggedResources) Add(kind, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources = append(r.resources, taggedResource{kind: kind, name: name})
+}
+
+// Release records that a resource has been successfully torn down.
+func (r *TaggedResources) Release(kind, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i := range r.resources {
+		if r.resources[i].kind == kind && r.resources[i].name == name {
+			r.resources[i].released = true
+		}
+	}
+}
+
+func (r *TaggedResources) report(t T) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var remaining []string
+	for _, resource := range r.resources {
+		if !resource.released {
+			remaining = append(remaining, resource.kind+" "+resource.name)
+		}
+	}
+	if len(remaining) == 0 {
+		return
+	}
+	t.Logf("resources tagged with %s were not released and may need manual cleanup:\n\t%s",
+		r.ID, strings.Join(remaining, "\n\t"))
+}
+
+// sanitizeName lowercases s and replaces runs of characters other than
+// letters and digits with a single dash.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(s) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+const crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID generates a ULID: 48 bits of millisecond timestamp followed
+// by 80 bits of randomness, encoded as 26 characters of Crockford base32.
+func newULID(now time.Time) string {
+	var id [16]byte
+	ms := uint64(now.UnixNano() / int64(time.Millisecond))
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], ms)
+	copy(id[:6], ts[2:])
+	_, _ = rand.Read(id[6:])
+
+	// 128 bits encode to 26 characters of 5 bits each, with the
+	// first character only carrying 3 bits.
+	hi := binary.BigEndian.Uint64(id[:8])
+	lo := binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockfordBase32[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
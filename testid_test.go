@@ -0,0 +1,35 @@
+package ntest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTestID(t *testing.T) {
+	t.Parallel()
+	var caught []string
+	t.Run("Some/Test Name", func(t *testing.T) {
+		captureT := ntest.ReplaceLogger(t, func(s string) {
+			t.Log("captured:", s)
+			caught = append(caught, s)
+		})
+		ntest.RunTest(captureT,
+			ntest.TestIDFixture,
+			func(id ntest.TestID, resources *ntest.TaggedResources) {
+				assert.Regexp(t, `^testtestid-some-test-name-[0-9a-hjkmnp-tv-z]{26}$`, string(id))
+				resources.Add("topic", string(id)+"-a")
+				resources.Add("topic", string(id)+"-b")
+				resources.Release("topic", string(id)+"-a")
+				assert.NotEqual(t, id, ntest.NewTestID(t), "unique")
+			},
+		)
+	})
+	if assert.Equal(t, 1, len(caught), "caught") {
+		assert.Contains(t, caught[0], "-b")
+		assert.False(t, strings.Contains(caught[0], "-a\n"), "released resource listed")
+	}
+}
@@ -0,0 +1,208 @@
+package ntest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// TimingEnv names the environment variable that enables a TimingReporter.
+// Set it to "-" to print the report to standard output or to a file path
+// to write the report as JSON.
+const TimingEnv = "NTEST_TIMING"
+
+// TimingReportSize is the number of tests and injectors included in
+// each section of the timing report.
+var TimingReportSize = 20
+
+func enableTiming(path string) error {
+	if path == "-" {
+		AddReporter(NewTimingReporter(os.Stdout, false))
+		return nil
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create timing report: %w", err)
+	}
+	AddReporter(NewTimingReporter(f, true))
+	return nil
+}
+
+// Timing is the accumulated duration of a test or injector.
+type Timing struct {
+	Name  string        `json:"name"`
+	Count int           `json:"count"`
+	Total time.Duration `json:"total_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+// TimingReport lists the slowest tests and injectors.
+type TimingReport struct {
+	Tests     []Timing `json:"tests"`
+	Injectors []Timing `json:"injectors"`
+}
+
+type timingTable struct {
+	mu      sync.Mutex
+	timings map[string]*Timing
+}
+
+func (tt *timingTable) add(name string, d time.Duration) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	if tt.timings == nil {
+		tt.timings = make(map[string]*Timing)
+	}
+	timing, ok := tt.timings[name]
+	if !ok {
+		timing = &Timing{Name: name}
+		tt.timings[name] = timing
+	}
+	timing.Count++
+	timing.Total += d
+	if d > timing.Max {
+		timing.Max = d
+	}
+}
+
+// slowest returns up to n timings, ordered by total duration.
+func (tt *timingTable) slowest(n int) []Timing {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	list := make([]Timing, 0, len(tt.timings))
+	for _, timing := range tt.timings {
+		list = append(list, *timing)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Total != list[j].Total {
+			return list[i].Total > list[j].Total
+		}
+		return list[i].Name < list[j].Name
+	})
+	if len(list) > n {
+		list = list[:n]
+	}
+	return list
+}
+
+var injectorTimings timingTable
+
+// TimeInjector wraps an injector function so that the time spent in it is
+// included in the injector section of timing reports. If the function takes
+// a T, an injector event is also emitted for the test. The result is named
+// so it can be used with nject.ReplaceNamed and friends. For wrapper
+// functions (whose first argument is the inner function) the time spent
+// in the inner function is not counted.
+//
+//	var Database = ntest.TimeInjector("database", func(t ntest.T) *sql.DB { ... })
+func TimeInjector(name string, fn interface{}) nject.Provider {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		panic(fmt.Sprintf("TimeInjector %s: %T is not a function", name, fn))
+	}
+	fnType := v.Type()
+	isWrapper := fnType.NumIn() > 0 && fnType.In(0).Kind() == reflect.Func
+	timed := reflect.MakeFunc(fnType, func(args []reflect.Value) []reflect.Value {
+		var innerTime time.Duration
+		if isWrapper {
+			inner := args[0]
+			args[0] = reflect.MakeFunc(inner.Type(), func(innerArgs []reflect.Value) []reflect.Value {
+				start := time.Now()
+				defer func() { innerTime += time.Since(start) }()
+				return inner.Call(innerArgs)
+			})
+		}
+		start := time.Now()
+		defer func() {
+			elapsed := time.Since(start) - innerTime
+			injectorTimings.add(name, elapsed)
+			if testName := argsTestName(args); testName != "" {
+				emitEvent(Event{Time: time.Now(), Type: EventInjector, Test: testName, Name: name, Elapsed: elapsed})
+			}
+		}()
+		return v.Call(args)
+	})
+	return nject.Provide(name, timed.Interface())
+}
+
+// argsTestName returns the name of the test if one of args is a T.
+func argsTestName(args []reflect.Value) string {
+	for _, arg := range args {
+		if !arg.CanInterface() {
+			continue
+		}
+		if t, ok := arg.Interface().(T); ok && t != nil {
+			return t.Name()
+		}
+	}
+	return ""
+}
+
+// TimingReporter is a Reporter that accumulates test durations and, when
+// closed, reports the slowest tests along with the slowest injectors
+// wrapped with TimeInjector.
+type TimingReporter struct {
+	w      io.Writer
+	asJSON bool
+	tests  timingTable
+}
+
+var _ Reporter = &TimingReporter{}
+
+// NewTimingReporter creates a TimingReporter that writes its report to w
+// as text or as JSON (a TimingReport). If w is an io.Closer other than
+// os.Stdout, it is closed by Close.
+func NewTimingReporter(w io.Writer, asJSON bool) *TimingReporter {
+	return &TimingReporter{w: w, asJSON: asJSON}
+}
+
+func (r *TimingReporter) TestStarted(string, time.Time) {}
+
+func (r *TimingReporter) TestFinished(result TestResult) {
+	r.tests.add(result.Name, result.Duration)
+}
+
+// Report returns the slowest tests and injectors so far.
+func (r *TimingReporter) Report() TimingReport {
+	return TimingReport{
+		Tests:     r.tests.slowest(TimingReportSize),
+		Injectors: injectorTimings.slowest(TimingReportSize),
+	}
+}
+
+func (r *TimingReporter) Close() error {
+	report := r.Report()
+	var err error
+	if r.asJSON {
+		enc := json.NewEncoder(r.w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	} else {
+		writeTimings(r.w, "Slowest tests", report.Tests)
+		writeTimings(r.w, "Slowest injectors", report.Injectors)
+	}
+	if closer, ok := r.w.(io.Closer); ok && r.w != os.Stdout {
+		if closeErr := closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func writeTimings(w io.Writer, title string, timings []Timing) {
+	if len(timings) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, timing := range timings {
+		fmt.Fprintf(w, "  %10s  %4dx  max %10s  %s\n",
+			timing.Total.Round(time.Millisecond), timing.Count, timing.Max.Round(time.Millisecond), timing.Name)
+	}
+}
@@ -0,0 +1,50 @@
+package ntest_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestTimingReporter(t *testing.T) {
+	t.Parallel()
+	type slowThing string
+	var buf bytes.Buffer
+	reporter := ntest.NewTimingReporter(&buf, false)
+	ntest.RunTest(t,
+		ntest.TimeInjector("timing-test-wrapper", func(inner func()) {
+			inner()
+		}),
+		ntest.TimeInjector("timing-test-slow", func() slowThing {
+			time.Sleep(20 * time.Millisecond)
+			return "slow"
+		}),
+		func(s slowThing) {
+			assert.Equal(t, slowThing("slow"), s)
+			time.Sleep(50 * time.Millisecond)
+		},
+	)
+	reporter.TestFinished(ntest.TestResult{Name: "TestFast", Duration: time.Millisecond})
+	reporter.TestFinished(ntest.TestResult{Name: "TestSlow", Duration: time.Second})
+
+	report := reporter.Report()
+	require.Equal(t, 2, len(report.Tests))
+	assert.Equal(t, "TestSlow", report.Tests[0].Name)
+	injectors := make(map[string]ntest.Timing)
+	for _, timing := range report.Injectors {
+		injectors[timing.Name] = timing
+	}
+	assert.GreaterOrEqual(t, injectors["timing-test-slow"].Total, 20*time.Millisecond)
+	assert.Less(t, injectors["timing-test-wrapper"].Total, 20*time.Millisecond, "inner time excluded")
+
+	require.NoError(t, reporter.Close())
+	out := buf.String()
+	assert.True(t, strings.Index(out, "TestSlow") < strings.Index(out, "TestFast"), out)
+	assert.Contains(t, out, "Slowest injectors:")
+}
@@ -0,0 +1,103 @@
+package ntest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/trace"
+	"sync"
+	"time"
+
+	"github.com/muir/nject"
+)
+
+// Environment variables for execution traces: TraceEnv selects, with a
+// regular expression that is matched against the full test (or matrix
+// cell) name, tests that RunTest traces with RecordTrace and
+// TraceSlowEnv sets TraceSlow, for example NTEST_TRACE=^TestQueue
+// NTEST_TRACE_SLOW=10s.
+const (
+	TraceEnv     = "NTEST_TRACE"
+	TraceSlowEnv = "NTEST_TRACE_SLOW"
+)
+
+// TraceSlow is the duration after which the tests selected with
+// $NTEST_TRACE are considered slow, see RecordTrace.
+var TraceSlow time.Duration
+
+var traceTests struct {
+	mu      sync.Mutex
+	pattern *regexp.Regexp
+}
+
+func enableTrace(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+	traceTests.mu.Lock()
+	defer traceTests.mu.Unlock()
+	traceTests.pattern = re
+	return nil
+}
+
+func setTraceSlow(value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	TraceSlow = d
+	return nil
+}
+
+func traceSelected(name string) bool {
+	traceTests.mu.Lock()
+	defer traceTests.mu.Unlock()
+	return traceTests.pattern != nil && traceTests.pattern.MatchString(name)
+}
+
+// TraceFixture returns an injector that applies RecordTrace.
+func TraceFixture(slow time.Duration) nject.Provider {
+	return nject.Required(nject.Provide("trace", func(t T) {
+		RecordTrace(t, slow)
+	}))
+}
+
+// RecordTrace records a runtime execution trace (see runtime/trace) from
+// now until the test finishes, including its cleanup functions registered
+// after RecordTrace. If the test fails, or takes longer than slow (when
+// slow is not zero), the trace is written to trace.out in the ArtifactDir
+// of the test; view it with go tool trace. Otherwise it is discarded.
+//
+// Only one trace can be recorded at a time, so if another test (or go
+// test -trace) is already tracing, that is logged and the test is not
+// traced. The trace covers the whole process, which is what is needed to
+// see how parallel tests affect each other.
+func RecordTrace(t T, slow time.Duration) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Logf("could not start execution trace: %s", err)
+		return
+	}
+	start := time.Now()
+	t.Cleanup(func() {
+		trace.Stop()
+		elapsed := time.Since(start)
+		if !t.Failed() && (slow == 0 || elapsed <= slow) {
+			return
+		}
+		// Fatalf would abort the test for the sake of a diagnostic
+		dir, err := artifactDir(t)
+		path := filepath.Join(dir, "trace.out")
+		if err == nil {
+			err = os.WriteFile(path, buf.Bytes(), 0o644)
+		}
+		if err != nil {
+			t.Logf("could not write execution trace: %s", err)
+			return
+		}
+		t.Logf("execution trace written to %s (view with go tool trace)", path)
+	})
+}
@@ -0,0 +1,56 @@
+package ntest_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestRecordTrace(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv(ntest.ArtifactRootEnv, root)
+	traceFile := func(name string) string {
+		return filepath.Join(root, "TestRecordTrace", name, "trace.out")
+	}
+
+	t.Run("failed", func(t *testing.T) {
+		ct := &cleanupT{T: t}
+		ntest.RecordTrace(failedT{ct}, 0)
+		ct.runCleanups()
+	})
+	t.Run("slow", func(t *testing.T) {
+		ct := &cleanupT{T: t}
+		ntest.RunTest(ct, ntest.TraceFixture(time.Millisecond), func() {
+			time.Sleep(5 * time.Millisecond)
+		})
+		ct.runCleanups()
+	})
+	t.Run("fast", func(t *testing.T) {
+		ct := &cleanupT{T: t}
+		ntest.RecordTrace(ct, time.Minute)
+		ct.runCleanups()
+	})
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	ntest.RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-ntest.trace", "^TestRecordTrace/selected$", "-ntest.trace-slow", "1ns"}))
+	defer func() { _ = fs.Parse([]string{"-ntest.trace", "$^", "-ntest.trace-slow", "0s"}) }()
+	t.Run("selected", func(t *testing.T) {
+		ntest.RunTest(t, func() {})
+	})
+
+	for _, name := range []string{"failed", "slow", "selected"} {
+		info, err := os.Stat(traceFile(name))
+		if assert.NoError(t, err, name) {
+			assert.NotZero(t, info.Size(), name)
+		}
+	}
+	_, err := os.Stat(traceFile("fast"))
+	assert.True(t, os.IsNotExist(err), "fast tests that pass are not kept")
+}
@@ -0,0 +1,139 @@
+package ntest
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// ResourceTracker lets injectors register the resources they create
+// (ports, files, schemas, containers) so that, after all cleanup functions
+// have run, it can verify that each one was released.
+type ResourceTracker struct {
+	mu        sync.Mutex
+	resources []*TrackedResource
+}
+
+// TrackedResource is a resource registered with a ResourceTracker.
+type TrackedResource struct {
+	Kind    string
+	Name    string
+	Creator string
+	verify  func() error
+	mu      sync.Mutex
+	release bool
+}
+
+// ResourceTrackerFixture provides a *ResourceTracker. It should be early in
+// the injection chain so that its verification runs after the cleanup
+// functions of all the injectors that use it. Leaks are reported with
+// t.Errorf.
+var ResourceTrackerFixture = nject.Provide("resource-tracker", func(t T) *ResourceTracker {
+	tracker := &ResourceTracker{}
+	t.Cleanup(func() {
+		tracker.verifyAll(t)
+	})
+	return tracker
+})
+
+// Track registers a resource. The creator is recorded as the name of the
+// calling function. If verify is not nil, it is called after cleanup to
+// confirm that the resource is really gone (return an error if not); in
+// that case calling Release is optional.
+func (r *ResourceTracker) Track(kind, name string, verify func() error) *TrackedResource {
+	resource := &TrackedResource{
+		Kind:    kind,
+		Name:    name,
+		Creator: callerName(2),
+		verify:  verify,
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources = append(r.resources, resource)
+	return resource
+}
+
+// Release marks the resource as released.
+func (res *TrackedResource) Release() {
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	res.release = true
+}
+
+func (res *TrackedResource) check() error {
+	if res.verify != nil {
+		return res.verify()
+	}
+	res.mu.Lock()
+	defer res.mu.Unlock()
+	if !res.release {
+		return fmt.Errorf("not released")
+	}
+	return nil
+}
+
+func (r *ResourceTracker) verifyAll(t T) {
+	r.mu.Lock()
+	resources := append([]*TrackedResource(nil), r.resources...)
+	r.mu.Unlock()
+	var leaks []string
+	for _, res := range resources {
+		if err := res.check(); err != nil {
+			leaks = append(leaks, fmt.Sprintf("%s %s (created by %s): %s", res.Kind, res.Name, res.Creator, err))
+		}
+	}
+	if len(leaks) != 0 {
+		t.Errorf("%d resources leaked:\n\t%s", len(leaks), strings.Join(leaks, "\n\t"))
+	}
+}
+
+// FileRemoved is a verify function for Track that checks that a file or
+// directory no longer exists.
+func FileRemoved(path string) func() error {
+	return func() error {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return fmt.Errorf("still exists")
+		}
+		return nil
+	}
+}
+
+// PortReleased is a verify function for Track that checks that nothing is
+// listening on a local TCP address.
+func PortReleased(addr string) func() error {
+	return func() error {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return fmt.Errorf("still in use: %w", err)
+		}
+		return listener.Close()
+	}
+}
+
+// callerName returns the short name of the function skip frames up the
+// stack from callerName's caller.
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	return shortFuncName(fn.Name())
+}
+
+// shortFuncName trims the package path from a fully qualified function
+// name: "github.com/memsql/ntest.RunTest.func1" becomes "ntest.RunTest.func1".
+func shortFuncName(name string) string {
+	if i := strings.LastIndexByte(name, '/'); i != -1 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,46 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+type leakyFile string
+
+func createLeakyFile(t ntest.T, tracker *ntest.ResourceTracker) leakyFile {
+	dir, err := os.MkdirTemp("", "ntest-leak-")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "leak")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	// never removed: this is the leak being detected
+	tracker.Track("file", path, ntest.FileRemoved(path))
+	return leakyFile(path)
+}
+
+func TestResourceTracker(t *testing.T) {
+	t.Parallel()
+	var leaked leakyFile
+	capture := &errorCapturingT{}
+	t.Run("leak", func(t *testing.T) {
+		capture.T = t
+		ntest.RunTest(capture,
+			ntest.ResourceTrackerFixture,
+			createLeakyFile,
+			func(tracker *ntest.ResourceTracker, f leakyFile) {
+				leaked = f
+				tracker.Track("port", "released", nil).Release()
+			},
+		)
+	})
+	defer os.RemoveAll(filepath.Dir(string(leaked)))
+	if assert.Equal(t, 1, len(capture.errors)) {
+		assert.Contains(t, capture.errors[0], "1 resources leaked")
+		assert.Contains(t, capture.errors[0], "(created by ntest_test.createLeakyFile): still exists")
+	}
+}
@@ -0,0 +1,129 @@
+package ntest
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HangWatchdog controls whether RunTest watches for tests that are about
+// to hit the go test -timeout deadline. When a test is still running
+// DefaultDeadlineMargin (scaled with ScaledTimeout) before the deadline,
+// the watchdog logs the fixtures that are still open (see ReportFixture)
+// and a dump of all goroutines, which is also written to goroutines.txt
+// in the test's ArtifactDir (only the start and end of the dump are
+// logged if it is larger than DumpThreshold). That way a "test timed
+// out" failure comes with evidence of what was stuck.
+var HangWatchdog = true
+
+var (
+	openFixturesLock sync.Mutex
+	openFixtures     = make(map[string]map[string]time.Time) // test -> fixture -> created
+)
+
+func fixtureOpened(test, name string, start time.Time) {
+	openFixturesLock.Lock()
+	defer openFixturesLock.Unlock()
+	if openFixtures[test] == nil {
+		openFixtures[test] = make(map[string]time.Time)
+	}
+	openFixtures[test][name] = start
+}
+
+func fixtureClosed(test, name string) {
+	openFixturesLock.Lock()
+	defer openFixturesLock.Unlock()
+	delete(openFixtures[test], name)
+	if len(openFixtures[test]) == 0 {
+		delete(openFixtures, test)
+	}
+}
+
+// fixturesOpen describes the open fixtures of test and its subtests.
+func fixturesOpen(test string) []string {
+	openFixturesLock.Lock()
+	defer openFixturesLock.Unlock()
+	var open []string
+	for name, fixtures := range openFixtures {
+		if name != test && !strings.HasPrefix(name, test+"/") {
+			continue
+		}
+		for fixture, start := range fixtures {
+			open = append(open, name+": "+fixture+" (open for "+time.Since(start).Round(time.Second).String()+")")
+		}
+	}
+	sort.Strings(open)
+	return open
+}
+
+// startWatchdog arranges for watchdogFired to be called shortly before
+// the test's deadline. It does nothing if the test has no deadline.
+func startWatchdog(t T) {
+	if !HangWatchdog {
+		return
+	}
+	d, ok := unwrapAll(t).(deadliner)
+	if !ok {
+		return
+	}
+	deadline, ok := safeDeadline(d)
+	if !ok {
+		return
+	}
+	var mu sync.Mutex
+	done := false
+	timer := time.AfterFunc(time.Until(deadline.Add(-ScaledTimeout(DefaultDeadlineMargin))), func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			watchdogFired(t)
+		}
+	})
+	t.Cleanup(func() {
+		timer.Stop()
+		mu.Lock()
+		defer mu.Unlock()
+		done = true
+	})
+}
+
+func watchdogFired(t T) {
+	dump := goroutineDump()
+	t.Logf("watchdog: %s is about to reach the test deadline", t.Name())
+	if open := fixturesOpen(t.Name()); len(open) != 0 {
+		t.Logf("watchdog: open fixtures:\n\t%s", strings.Join(open, "\n\t"))
+	}
+	// ArtifactDir would call Fatalf, which must not be called from
+	// this goroutine
+	dir, err := artifactDir(t)
+	path := filepath.Join(dir, "goroutines.txt")
+	if err == nil {
+		err = os.WriteFile(path, dump, 0o644)
+	}
+	if err != nil {
+		t.Logf("watchdog: write %s: %s", path, err)
+		path = ""
+	} else {
+		t.Logf("watchdog: goroutine dump written to %s", path)
+	}
+	if len(dump) > DumpThreshold {
+		logTruncated(t, "watchdog: goroutines", dump, path)
+	} else {
+		t.Logf("watchdog: goroutines:\n%s", dump)
+	}
+}
+
+func goroutineDump() []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
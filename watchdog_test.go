@@ -0,0 +1,32 @@
+package ntest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestHangWatchdog(t *testing.T) {
+	t.Setenv(ntest.ArtifactRootEnv, t.TempDir())
+	t.Setenv(ntest.TimeoutScaleEnv, "1")
+	dt := &deadlineT{T: t, deadline: time.Now().Add(ntest.DefaultDeadlineMargin + 20*time.Millisecond)}
+	ntest.RunTest(dt, func(t ntest.T) {
+		ntest.ReportFixture(t, "stuck-db")
+		time.Sleep(100 * time.Millisecond)
+	})
+	dt.mu.Lock()
+	defer dt.mu.Unlock()
+	joined := strings.Join(dt.logs, "\n")
+	assert.Contains(t, joined, "about to reach the test deadline")
+	assert.Contains(t, joined, "open fixtures")
+	dump, err := os.ReadFile(filepath.Join(ntest.ArtifactDir(t), "goroutines.txt"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dump), "TestHangWatchdog")
+}
@@ -0,0 +1,124 @@
+package ntest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookEnv names the environment variable that, when set to a URL,
+// enables a WebhookNotifier posting to that URL.
+const WebhookEnv = "NTEST_WEBHOOK_URL"
+
+func enableWebhook(url string) error {
+	AddFailureHook(NewWebhookNotifier(url))
+	return nil
+}
+
+// FailureHook is called when a test run with RunTest fails. If a
+// FailureHook is also an io.Closer, it is closed by Main.
+type FailureHook interface {
+	OnTestFailed(result TestResult)
+}
+
+// AddFailureHook registers a FailureHook for all subsequent tests.
+func AddFailureHook(hook FailureHook) {
+	AddReporter(failureHookReporter{hook: hook})
+}
+
+type failureHookReporter struct {
+	hook FailureHook
+}
+
+func (r failureHookReporter) TestStarted(string, time.Time) {}
+
+func (r failureHookReporter) TestFinished(result TestResult) {
+	if result.Failed {
+		r.hook.OnTestFailed(result)
+	}
+}
+
+func (r failureHookReporter) Close() error {
+	if closer, ok := r.hook.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// WebhookNotifier is a FailureHook that posts a Slack-style JSON message
+// ({"text": "..."}) for each failed test: its name, matrix cells, failure
+// messages, and the end of its log. Messages are posted in the background;
+// Close waits for them to be sent.
+type WebhookNotifier struct {
+	URL string
+	// LogLines is the number of log lines to include.
+	LogLines int
+	Client   *http.Client
+	wg       sync.WaitGroup
+}
+
+var _ FailureHook = &WebhookNotifier{}
+
+// NewWebhookNotifier creates a WebhookNotifier that posts to url.
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:      url,
+		LogLines: 20,
+		Client:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) OnTestFailed(result TestResult) {
+	body, err := json.Marshal(map[string]string{"text": n.message(result)})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ntest: webhook: %s\n", err)
+		return
+	}
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+		resp, err := n.Client.Post(n.URL, "application/json", bytes.NewReader(body))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ntest: webhook: %s\n", err)
+			return
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			fmt.Fprintf(os.Stderr, "ntest: webhook: status %d\n", resp.StatusCode)
+		}
+	}()
+}
+
+func (n *WebhookNotifier) message(result TestResult) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* failed after %s", result.Name, result.Duration.Round(time.Millisecond))
+	if parts := strings.Split(result.Name, "/"); len(parts) > 1 {
+		fmt.Fprintf(&b, "\nmatrix: %s", strings.Join(parts[1:], ", "))
+	}
+	for _, msg := range result.Messages {
+		b.WriteString("\n> ")
+		b.WriteString(strings.ReplaceAll(msg, "\n", "\n> "))
+	}
+	log := result.Log
+	if len(log) > n.LogLines {
+		log = log[len(log)-n.LogLines:]
+	}
+	if len(log) != 0 {
+		b.WriteString("\n```\n")
+		b.WriteString(strings.Join(log, "\n"))
+		b.WriteString("\n```")
+	}
+	return b.String()
+}
+
+// Close waits for messages that are being posted.
+func (n *WebhookNotifier) Close() error {
+	n.wg.Wait()
+	return nil
+}
@@ -0,0 +1,50 @@
+package ntest_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	t.Parallel()
+	var mu sync.Mutex
+	var texts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Text string `json:"text"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		defer mu.Unlock()
+		texts = append(texts, body.Text)
+	}))
+	t.Cleanup(server.Close)
+
+	notifier := ntest.NewWebhookNotifier(server.URL)
+	notifier.LogLines = 2
+	notifier.OnTestFailed(ntest.TestResult{
+		Name:     "TestNightly/db=mysql/tls",
+		Duration: 3 * time.Second,
+		Messages: []string{"boom\nbang"},
+		Log:      []string{"one", "two", "three"},
+	})
+	var closer io.Closer = notifier
+	require.NoError(t, closer.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"*TestNightly/db=mysql/tls* failed after 3s\n" +
+		"matrix: db=mysql, tls\n" +
+		"> boom\n> bang\n" +
+		"```\ntwo\nthree\n```"}, texts)
+}
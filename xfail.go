@@ -0,0 +1,105 @@
+package ntest
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/muir/nject"
+)
+
+// XFailFixture returns an injector that applies XFail to the T of the
+// rest of the chain. Put it in the cell of a matrix that is expected to
+// fail, or at the start of the chain of a test.
+func XFailFixture(reason string) nject.Provider {
+	return nject.Provide("xfail", func(t T) T {
+		return XFail(t, reason)
+	})
+}
+
+// XFail marks a test (or matrix cell) as expected to fail, for example
+// because of a known bug that a test was written for first. It returns
+// a T that records failures instead of reporting them: if the test
+// fails, as expected, it passes with a note that lists the failures.
+// If it passes, it fails because it was expected to fail, so that the
+// XFail is removed once the bug is fixed.
+//
+// Only failures reported through the returned T, or T wrappers
+// derived from it, count. A failure reported directly on the
+// *testing.T is a real failure. Fatal and FailNow stop the test, like
+// they do on *testing.T, which requires the test to run with RunTest.
+func XFail(t T, reason string) T {
+	xfailTests.Store(t.Name(), true)
+	x := &xfailT{T: t, reason: reason}
+	t.Cleanup(func() {
+		xfailTests.Delete(t.Name())
+		x.mu.Lock()
+		failures := x.failures
+		x.mu.Unlock()
+		if len(failures) == 0 {
+			t.Errorf("%s passed but was expected to fail: %s", t.Name(), reason)
+			return
+		}
+		t.Logf("%s failed as expected (%s):\n\t%s", t.Name(), reason, strings.Join(failures, "\n\t"))
+	})
+	return x
+}
+
+// xfailTests has the names of the tests that use XFail, so that RunTest
+// knows to recover expectedFailureStop.
+var xfailTests sync.Map
+
+type xfailT struct {
+	T
+	reason   string
+	mu       sync.Mutex
+	failures []string
+}
+
+// expectedFailureStop is panicked by FailNow on an xfailT and recovered
+// by RunTest.
+type expectedFailureStop struct{}
+
+func (t *xfailT) unwrap() T { return t.T }
+
+func (t *xfailT) fail(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if msg == "" {
+		msg = "failed"
+	}
+	t.failures = append(t.failures, msg)
+}
+
+func (t *xfailT) Error(args ...interface{}) {
+	t.fail(sprintln(args...))
+}
+
+func (t *xfailT) Errorf(format string, args ...interface{}) {
+	t.fail(fmt.Sprintf(format, args...))
+}
+
+func (t *xfailT) Fatal(args ...interface{}) {
+	t.Error(args...)
+	t.FailNow()
+}
+
+func (t *xfailT) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	t.FailNow()
+}
+
+func (t *xfailT) FailNow() {
+	t.mu.Lock()
+	if len(t.failures) == 0 {
+		t.failures = append(t.failures, "failed")
+	}
+	t.mu.Unlock()
+	panic(expectedFailureStop{})
+}
+
+func (t *xfailT) Failed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.failures) != 0 || t.T.Failed()
+}
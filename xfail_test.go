@@ -0,0 +1,61 @@
+package ntest_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/muir/nject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/memsql/ntest"
+)
+
+func TestXFail(t *testing.T) {
+	var logged []string
+	run := func(final interface{}) *failingT {
+		logged = nil
+		ft := &failingT{cleanupT: &cleanupT{T: ntest.ReplaceLogger(t, func(s string) {
+			logged = append(logged, s)
+		})}}
+		ntest.RunTest(ft, ntest.XFailFixture("bug 42"), final)
+		ft.runCleanups()
+		return ft
+	}
+
+	ft := run(func(t ntest.T) {
+		t.Errorf("got %d", 1)
+		t.Error("got", 2)
+	})
+	assert.Empty(t, ft.errors)
+	assert.Contains(t, logged, "TestXFail failed as expected (bug 42):\n\tgot 1\n\tgot 2")
+
+	var after bool
+	ft = run(func(t ntest.T) {
+		require.Equal(t, 1, 2)
+		after = true
+	})
+	assert.Empty(t, ft.errors)
+	assert.False(t, after, "Fatal stops the test")
+	require.Len(t, logged, 1)
+	assert.True(t, strings.HasPrefix(logged[0], "TestXFail failed as expected (bug 42):\n\t"), logged[0])
+
+	ft = run(func(t ntest.T) {})
+	assert.Equal(t, []string{"TestXFail passed but was expected to fail: bug 42"}, ft.errors)
+}
+
+func TestXFailMatrixCell(t *testing.T) {
+	var results *ntest.MatrixResults
+	t.Run("matrix", func(t *testing.T) {
+		results = ntest.RunMatrix(t,
+			map[string]nject.Provider{
+				"fixed":  nject.Provide("fixed", func() bool { return true }),
+				"broken": nject.Sequence("broken", ntest.XFailFixture("not fixed yet"), func() bool { return false }),
+			},
+			func(t ntest.T, fixed bool) {
+				assert.True(t, fixed)
+			},
+		)
+	})
+	assert.Equal(t, 2, results.Passed())
+}